@@ -3,53 +3,74 @@ package main
 import (
 	"fmt"
 	"log"
+	"os"
 
 	"github.com/donnpebe/shoppo/pkg/domain"
+	"github.com/donnpebe/shoppo/pkg/lib/paymentprovider"
 	"github.com/donnpebe/shoppo/pkg/lib/promotioncondition"
+	"github.com/donnpebe/shoppo/pkg/lib/shippingcalculator"
 	"github.com/donnpebe/shoppo/pkg/services"
+	"github.com/donnpebe/shoppo/pkg/storage/memory"
 )
 
 var inventories = map[string]*domain.Product{
 	"googlehome": {
-		ID:        "googlehome",
-		SKU:       "120P90",
-		Name:      "Google Home",
-		UnitPrice: 49.99,
-		Quantity:  10,
+		ID:         "googlehome",
+		SKU:        "120P90",
+		Name:       "Google Home",
+		UnitPrice:  domain.MustParseMoney("49.99", "USD"),
+		Quantity:   10,
+		Weight:     480,
+		Dimensions: domain.Dimensions{Length: 96, Width: 96, Height: 143},
 	},
 	"macbookpro": {
-		ID:        "macbookpro",
-		SKU:       "43N23P",
-		Name:      "MacBook Pro",
-		UnitPrice: 5399.99,
-		Quantity:  5,
+		ID:         "macbookpro",
+		SKU:        "43N23P",
+		Name:       "MacBook Pro",
+		UnitPrice:  domain.MustParseMoney("5399.99", "USD"),
+		Quantity:   5,
+		Weight:     2000,
+		Dimensions: domain.Dimensions{Length: 356, Width: 249, Height: 17},
 	},
 	"alexaspeaker": {
-		ID:        "alexaspeaker",
-		SKU:       "A304SD",
-		Name:      "Alexa Speaker",
-		UnitPrice: 109.50,
-		Quantity:  10,
+		ID:         "alexaspeaker",
+		SKU:        "A304SD",
+		Name:       "Alexa Speaker",
+		UnitPrice:  domain.MustParseMoney("109.50", "USD"),
+		Quantity:   10,
+		Weight:     780,
+		Dimensions: domain.Dimensions{Length: 100, Width: 100, Height: 148},
 	},
 	"raspberrypi": {
-		ID:        "raspberrypi",
-		SKU:       "234234",
-		Name:      "Raspberry Pi B",
-		UnitPrice: 30,
-		Quantity:  2,
+		ID:         "raspberrypi",
+		SKU:        "234234",
+		Name:       "Raspberry Pi B",
+		UnitPrice:  domain.MustParseMoney("30", "USD"),
+		Quantity:   2,
+		Weight:     46,
+		Dimensions: domain.Dimensions{Length: 85, Width: 56, Height: 17},
 	},
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		serve(os.Args[2:])
+		return
+	}
+
 	promotions := setupPromotion()
-	shopService := services.NewShopService(inventories, promotions, make(map[string]*domain.Order))
+	shopService := services.NewShopService(memory.NewStore(inventories, nil), promotions)
 
 	fmt.Println("Welcome to shoppo")
 	fmt.Println("=================")
 
 	// Scenario 1: Each sale of a Macbook Pro comes with a free Raspberry Pi B
-	order := shopService.CreateCart()
-	order, err := shopService.AddItemToCart(order.ID, "macbookpro", 1)
+	order, err := shopService.CreateCart()
+	if err != nil {
+		log.Fatalf("cannot create cart: %v", err)
+	}
+
+	order, err = shopService.AddItemToCart(order.ID, "macbookpro", 1)
 	if err != nil {
 		log.Fatalf("cannot add item to cart: %v", err)
 	}
@@ -59,15 +80,23 @@ func main() {
 		log.Fatalf("cannot add item to cart: %v", err)
 	}
 
-	totalAmount, err := shopService.Checkout(order.ID)
+	order, err = shopService.SetShippingAddress(order.ID, shippingAddress)
+	if err != nil {
+		log.Fatalf("cannot set shipping address: %v", err)
+	}
+
+	pricing, err := shopService.Checkout(order.ID)
 	if err != nil {
 		log.Fatalf("cannot add item to cart: %v", err)
 	}
 
-	fmt.Printf("For scenario 1 you need to pay: %.2f\n", totalAmount)
+	printPricing("scenario 1", pricing)
 
 	// Scenario 2: Buy 3 Google Homes for the price of 2
-	order2 := shopService.CreateCart()
+	order2, err := shopService.CreateCart()
+	if err != nil {
+		log.Fatalf("cannot create cart: %v", err)
+	}
 	order2, err = shopService.AddItemToCart(order2.ID, "googlehome", 1)
 	if err != nil {
 		log.Fatalf("cannot add item to cart: %v", err)
@@ -81,15 +110,23 @@ func main() {
 		log.Fatalf("cannot add item to cart: %v", err)
 	}
 
-	totalAmount, err = shopService.Checkout(order2.ID)
+	order2, err = shopService.SetShippingAddress(order2.ID, shippingAddress)
+	if err != nil {
+		log.Fatalf("cannot set shipping address: %v", err)
+	}
+
+	pricing, err = shopService.Checkout(order2.ID)
 	if err != nil {
 		log.Fatalf("cannot add item to cart: %v", err)
 	}
 
-	fmt.Printf("For scenario 2 you need to pay: %.2f\n", totalAmount)
+	printPricing("scenario 2", pricing)
 
 	// Scenario 3: Buy more than 3 Alexa Speakers will have 10% discount on all alexa speakers
-	order3 := shopService.CreateCart()
+	order3, err := shopService.CreateCart()
+	if err != nil {
+		log.Fatalf("cannot create cart: %v", err)
+	}
 	order3, err = shopService.AddItemToCart(order3.ID, "alexaspeaker", 1)
 	if err != nil {
 		log.Fatalf("cannot add item to cart: %v", err)
@@ -103,23 +140,50 @@ func main() {
 		log.Fatalf("cannot add item to cart: %v", err)
 	}
 
-	totalAmount, err = shopService.Checkout(order3.ID)
+	order3, err = shopService.SetShippingAddress(order3.ID, shippingAddress)
+	if err != nil {
+		log.Fatalf("cannot set shipping address: %v", err)
+	}
+
+	pricing, err = shopService.Checkout(order3.ID)
 	if err != nil {
 		log.Fatalf("cannot add item to cart: %v", err)
 	}
 
-	fmt.Printf("For scenario 3 you need to pay: %.2f\n", totalAmount)
+	printPricing("scenario 3", pricing)
+}
+
+// printPricing prints the promotions applied to the order and its total.
+func printPricing(scenario string, pricing *domain.Pricing) {
+	for _, discount := range pricing.Discounts {
+		fmt.Printf("For %s %q saves you %s\n", scenario, discount.Name, discount.Amount.Neg())
+	}
+
+	fmt.Printf("For %s you need to pay: %s\n", scenario, pricing.Total)
+}
+
+var shippingAddress = domain.Address{
+	FullName:   "Jane Doe",
+	StreetLine: "Jl. Jend. Sudirman Kav. 52-53",
+	City:       "Jakarta Selatan",
+	Province:   "DKI Jakarta",
+	PostalCode: "12190",
+	Country:    "ID",
 }
 
 func setupPromotion() []domain.Promotion {
 	return []domain.Promotion{
 		{
+			ID:   "macbookpro-free-raspberrypi",
+			Name: "Free Raspberry Pi with every MacBook Pro",
 			Condition: promotioncondition.BuyXProductGetFreeProductCondition{
 				XProductID:    "macbookpro",
 				FreeProductID: "raspberrypi",
 			},
 		},
 		{
+			ID:   "googlehome-3-for-2",
+			Name: "3 Google Homes for the price of 2",
 			Condition: promotioncondition.ProductQuantityDiscount{
 				ProductID:          "googlehome",
 				RequiredQuantity:   3,
@@ -127,6 +191,8 @@ func setupPromotion() []domain.Promotion {
 			},
 		},
 		{
+			ID:   "alexaspeaker-10-percent",
+			Name: "10% off Alexa Speakers when buying 3 or more",
 			Condition: promotioncondition.ProductPercentageDiscount{
 				ProductID:         "alexaspeaker",
 				MinQuantity:       3,
@@ -135,3 +201,70 @@ func setupPromotion() []domain.Promotion {
 		},
 	}
 }
+
+func setupShippingMethods() []domain.ShippingMethod {
+	freeOver100 := func(calc domain.ShippingCalculator) domain.ShippingCalculator {
+		return shippingcalculator.FreeOverThreshold{
+			Threshold:  domain.MustParseMoney("100", "USD"),
+			Calculator: calc,
+		}
+	}
+
+	return []domain.ShippingMethod{
+		{
+			ID:          "standard",
+			Code:        "standard",
+			Name:        "Standard",
+			Description: "Delivered in 2 to 5 working days, free for orders of $100 or more",
+			Calculator: shippingcalculator.PerRegion{
+				Regions: []shippingcalculator.Region{
+					{
+						Country:   "ID",
+						Provinces: []string{"DKI Jakarta", "Banten", "Jawa Barat"},
+						Calculator: freeOver100(shippingcalculator.WeightBased{
+							BaseCost:          domain.MustParseMoney("2", "USD"),
+							CostPerKg:         domain.MustParseMoney("0.50", "USD"),
+							VolumetricDivisor: 6000,
+							MaxWeight:         30000,
+						}),
+					},
+					{
+						Country: "ID",
+						Calculator: freeOver100(shippingcalculator.WeightBased{
+							BaseCost:          domain.MustParseMoney("4", "USD"),
+							CostPerKg:         domain.MustParseMoney("1.50", "USD"),
+							VolumetricDivisor: 6000,
+							MaxWeight:         30000,
+						}),
+					},
+				},
+			},
+		},
+		{
+			ID:          "express",
+			Code:        "express",
+			Name:        "Express",
+			Description: "Delivered the next working day",
+			Calculator: shippingcalculator.PerRegion{
+				Regions: []shippingcalculator.Region{
+					{
+						Country:    "ID",
+						Calculator: shippingcalculator.FlatRate{Cost: domain.MustParseMoney("15", "USD")},
+					},
+				},
+			},
+		},
+	}
+}
+
+// setupPaymentMethods offers the fake payment provider, for demos and
+// tests, until a real gateway is integrated.
+func setupPaymentMethods() []domain.PaymentMethod {
+	return []domain.PaymentMethod{
+		{
+			Code:     "fake-card",
+			Name:     "Test Card",
+			Provider: paymentprovider.NewFake(),
+		},
+	}
+}
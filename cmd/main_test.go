@@ -4,7 +4,9 @@ import (
 	"testing"
 
 	"github.com/donnpebe/shoppo/pkg/domain"
+	"github.com/donnpebe/shoppo/pkg/lib/promotionconfig"
 	"github.com/donnpebe/shoppo/pkg/services"
+	"github.com/donnpebe/shoppo/pkg/storage/memory"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
@@ -12,8 +14,7 @@ import (
 type MainTestSuite struct {
 	suite.Suite
 
-	inventories map[string]*domain.Product
-	orderStore  map[string]*domain.Order
+	store domain.Store
 }
 
 func TestMain(t *testing.T) {
@@ -22,82 +23,112 @@ func TestMain(t *testing.T) {
 }
 
 func (ms *MainTestSuite) SetupTest() {
-	ms.inventories = map[string]*domain.Product{
+	ms.store = memory.NewStore(map[string]*domain.Product{
 		"googlehome": {
 			ID:        "googlehome",
 			SKU:       "120P90",
 			Name:      "Google Home",
-			UnitPrice: 49.99,
+			UnitPrice: domain.MustParseMoney("49.99", "USD"),
 			Quantity:  10,
 		},
 		"macbookpro": {
 			ID:        "macbookpro",
 			SKU:       "43N23P",
 			Name:      "MacBook Pro",
-			UnitPrice: 5399.99,
+			UnitPrice: domain.MustParseMoney("5399.99", "USD"),
 			Quantity:  5,
 		},
 		"alexaspeaker": {
 			ID:        "alexaspeaker",
 			SKU:       "A304SD",
 			Name:      "Alexa Speaker",
-			UnitPrice: 109.50,
+			UnitPrice: domain.MustParseMoney("109.50", "USD"),
 			Quantity:  10,
 		},
 		"raspberrypi": {
 			ID:        "raspberrypi",
 			SKU:       "234234",
 			Name:      "Raspberry Pi B",
-			UnitPrice: 30,
+			UnitPrice: domain.MustParseMoney("30", "USD"),
 			Quantity:  2,
 		},
-	}
-
-	ms.orderStore = make(map[string]*domain.Order)
+	}, nil)
 }
 
 func (ms *MainTestSuite) TestBuyXProductGetFreeProductCondition() {
-	sut := services.NewShopService(ms.inventories, setupPromotion(), ms.orderStore)
+	sut := services.NewShopService(ms.store, setupPromotion())
 
-	order := sut.CreateCart()
-	order, err := sut.AddItemToCart(order.ID, "macbookpro", 1)
+	order, err := sut.CreateCart()
+	assert.NoError(ms.T(), err)
+	order, err = sut.AddItemToCart(order.ID, "macbookpro", 1)
 	assert.NoError(ms.T(), err)
 	order, err = sut.AddItemToCart(order.ID, "raspberrypi", 1)
 	assert.NoError(ms.T(), err)
 
-	totalAmount, err := sut.Checkout(order.ID)
+	order, err = sut.SetShippingAddress(order.ID, shippingAddress)
+	assert.NoError(ms.T(), err)
+
+	pricing, err := sut.Checkout(order.ID)
 	assert.NoError(ms.T(), err)
-	assert.Equal(ms.T(), 5399.99, totalAmount)
+	assert.Equal(ms.T(), domain.MustParseMoney("5399.99", "USD"), pricing.Total)
+	assert.Equal(ms.T(), []domain.AppliedPromotion{
+		{
+			PromotionID: "macbookpro-free-raspberrypi",
+			Name:        "Free Raspberry Pi with every MacBook Pro",
+			Amount:      domain.MustParseMoney("-30", "USD"),
+			Lines: []domain.LineDiscount{
+				{OrderLineID: pricing.Lines[1].OrderLineID, Quantity: 1, Amount: domain.MustParseMoney("-30", "USD")},
+			},
+			Promotion: &domain.PromotionSnapshot{
+				ConditionType: "buy_x_product_get_free_product",
+				Parameters:    map[string]interface{}{"xProductId": "macbookpro", "freeProductId": "raspberrypi"},
+			},
+		},
+	}, pricing.Discounts)
 }
 
 func (ms *MainTestSuite) TestProductPercentageDiscountCondition() {
-	sut := services.NewShopService(ms.inventories, setupPromotion(), ms.orderStore)
+	sut := services.NewShopService(ms.store, setupPromotion())
 
-	order := sut.CreateCart()
-	order, err := sut.AddItemToCart(order.ID, "alexaspeaker", 1)
+	order, err := sut.CreateCart()
+	assert.NoError(ms.T(), err)
+	order, err = sut.AddItemToCart(order.ID, "alexaspeaker", 1)
 	assert.NoError(ms.T(), err)
 	order, err = sut.AddItemToCart(order.ID, "alexaspeaker", 1)
 	assert.NoError(ms.T(), err)
 	order, err = sut.AddItemToCart(order.ID, "alexaspeaker", 1)
 	assert.NoError(ms.T(), err)
 
-	totalAmount, err := sut.Checkout(order.ID)
+	order, err = sut.SetShippingAddress(order.ID, shippingAddress)
+	assert.NoError(ms.T(), err)
+
+	pricing, err := sut.Checkout(order.ID)
 	assert.NoError(ms.T(), err)
-	assert.Equal(ms.T(), 295.65, totalAmount)
+	assert.Equal(ms.T(), domain.MustParseMoney("295.65", "USD"), pricing.Total)
 }
 
 func (ms *MainTestSuite) TestProductQuantityDiscountCondition() {
-	sut := services.NewShopService(ms.inventories, setupPromotion(), ms.orderStore)
+	sut := services.NewShopService(ms.store, setupPromotion())
 
-	order := sut.CreateCart()
-	order, err := sut.AddItemToCart(order.ID, "googlehome", 1)
+	order, err := sut.CreateCart()
+	assert.NoError(ms.T(), err)
+	order, err = sut.AddItemToCart(order.ID, "googlehome", 1)
 	assert.NoError(ms.T(), err)
 	order, err = sut.AddItemToCart(order.ID, "googlehome", 1)
 	assert.NoError(ms.T(), err)
 	order, err = sut.AddItemToCart(order.ID, "googlehome", 1)
 	assert.NoError(ms.T(), err)
 
-	totalAmount, err := sut.Checkout(order.ID)
+	order, err = sut.SetShippingAddress(order.ID, shippingAddress)
+	assert.NoError(ms.T(), err)
+
+	pricing, err := sut.Checkout(order.ID)
+	assert.NoError(ms.T(), err)
+	assert.Equal(ms.T(), domain.MustParseMoney("99.98", "USD"), pricing.Total)
+}
+
+func (ms *MainTestSuite) TestPromotionsFileMatchesBuiltInPromotions() {
+	promotions, err := promotionconfig.Load("../promotions.yaml")
 	assert.NoError(ms.T(), err)
-	assert.Equal(ms.T(), 49.99*2, totalAmount)
+	assert.Equal(ms.T(), setupPromotion(), promotions)
 }
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/donnpebe/shoppo/pkg/api"
+	"github.com/donnpebe/shoppo/pkg/domain"
+	"github.com/donnpebe/shoppo/pkg/lib/promotionconfig"
+	"github.com/donnpebe/shoppo/pkg/services"
+	"github.com/donnpebe/shoppo/pkg/storage/memory"
+	"github.com/donnpebe/shoppo/pkg/storage/sqlite"
+)
+
+// serve exposes the shop through the GraphQL API until the process is killed.
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", ":8080", "address to listen on")
+	dbPath := flags.String("db", "", "path of the SQLite database, everything is kept in memory when empty")
+	reservationTTL := flags.Duration("reservation-ttl", 0, "how long adding an item to a cart holds its stock, stock is not held when zero")
+	promotionsPath := flags.String("promotions", "", "path of a JSON or YAML file describing the promotions, the built-in promotions are used when empty")
+	bestDeal := flags.Bool("best-deal", false, "choose the promotions that can't apply together to give the lowest total instead of by priority")
+	fakePayments := flags.Bool("fake-payments", false, "offer the fake-card payment method, which accepts payments without charging anyone")
+	_ = flags.Parse(args)
+
+	promotions := setupPromotion()
+	if *promotionsPath != "" {
+		var err error
+		if promotions, err = promotionconfig.Load(*promotionsPath); err != nil {
+			log.Fatalf("cannot load promotions: %v", err)
+		}
+	}
+
+	var store domain.Store = memory.NewStore(inventories, nil)
+	if *dbPath != "" {
+		sqliteStore, err := sqlite.Open(*dbPath)
+		if err != nil {
+			log.Fatalf("cannot open database: %v", err)
+		}
+		defer sqliteStore.Close()
+
+		if err := seedProducts(sqliteStore.Products(), inventories); err != nil {
+			log.Fatalf("cannot seed products: %v", err)
+		}
+
+		store = sqliteStore
+	}
+
+	opts := []services.Option{
+		services.WithReservationTTL(*reservationTTL),
+		services.WithShippingMethods(setupShippingMethods()...),
+	}
+	if *bestDeal {
+		opts = append(opts, services.WithBestDeal(services.DefaultBestDealCombinations))
+	}
+	if *fakePayments {
+		opts = append(opts, services.WithPaymentMethods(setupPaymentMethods()...))
+	}
+
+	shopService := services.NewShopService(store, promotions, opts...)
+	if *reservationTTL > 0 {
+		go releaseExpiredReservations(shopService, time.Minute)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/graphql", api.NewHandler(shopService))
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	log.Printf("shoppo is listening on %s", *addr)
+	log.Fatal(server.ListenAndServe())
+}
+
+// releaseExpiredReservations cleans up the reservations of abandoned
+// carts every interval.
+func releaseExpiredReservations(shop domain.ShopService, interval time.Duration) {
+	for range time.Tick(interval) {
+		released, err := shop.ReleaseExpiredReservations()
+		if err != nil {
+			log.Printf("cannot release expired reservations: %v", err)
+			continue
+		}
+
+		if released > 0 {
+			log.Printf("released %d expired reservations", released)
+		}
+	}
+}
+
+// seedProducts saves the products that are not stored yet, leaving the
+// stock of the existing ones untouched.
+func seedProducts(repo domain.ProductRepository, products map[string]*domain.Product) error {
+	for _, product := range products {
+		_, err := repo.FindByID(product.ID)
+		if err == nil {
+			continue
+		}
+
+		if !errors.Is(err, domain.ErrProductNotFound) {
+			return err
+		}
+
+		if err := repo.Save(product); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
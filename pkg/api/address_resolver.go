@@ -0,0 +1,25 @@
+package api
+
+import "github.com/donnpebe/shoppo/pkg/domain"
+
+type orderAddressResolver struct {
+	address *domain.Address
+}
+
+func (r *orderAddressResolver) FullName() *string    { return optionalString(r.address.FullName) }
+func (r *orderAddressResolver) Company() *string     { return optionalString(r.address.Company) }
+func (r *orderAddressResolver) StreetLine() *string  { return optionalString(r.address.StreetLine) }
+func (r *orderAddressResolver) City() *string        { return optionalString(r.address.City) }
+func (r *orderAddressResolver) Province() *string    { return optionalString(r.address.Province) }
+func (r *orderAddressResolver) PostalCode() *string  { return optionalString(r.address.PostalCode) }
+func (r *orderAddressResolver) Country() *string     { return optionalString(r.address.Country) }
+func (r *orderAddressResolver) PhoneNumber() *string { return optionalString(r.address.PhoneNumber) }
+
+// optionalString returns nil for an empty string.
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
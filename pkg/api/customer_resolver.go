@@ -0,0 +1,19 @@
+package api
+
+import (
+	"github.com/graph-gophers/graphql-go"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+type customerResolver struct {
+	customer *domain.Customer
+}
+
+func (r *customerResolver) ID() graphql.ID {
+	return graphql.ID(r.customer.ID)
+}
+
+func (r *customerResolver) EmailAddress() string {
+	return r.customer.EmailAddress
+}
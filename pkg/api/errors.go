@@ -0,0 +1,119 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+const (
+	CodeBadUserInput  = "BAD_USER_INPUT"
+	CodeNoActiveOrder = "NO_ACTIVE_ORDER"
+	CodeInternal      = "INTERNAL_SERVER_ERROR"
+)
+
+var errNoActiveOrder = errors.New("there is no active order")
+
+// errorCodes maps domain errors to the stable codes exposed in
+// the "extensions.code" field of a GraphQL error.
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{domain.ErrCartNotFound, "CART_NOT_FOUND"},
+	{domain.ErrProductNotFound, "PRODUCT_NOT_FOUND"},
+	{domain.ErrNotEnoughStock, "NOT_ENOUGH_STOCK"},
+	{domain.ErrInvalidQuantity, CodeBadUserInput},
+	{domain.ErrVariantRequired, "VARIANT_REQUIRED"},
+	{domain.ErrCurrencyMismatch, "CURRENCY_MISMATCH"},
+	{domain.ErrItemNotFoundInCart, "ITEM_NOT_FOUND_IN_CART"},
+	{domain.ErrSomeProductInCartNotFound, "SOME_PRODUCT_IN_CART_NOT_FOUND"},
+	{domain.ErrSomeProductInCartNotEnoughInStock, "SOME_PRODUCT_IN_CART_NOT_ENOUGH_IN_STOCK"},
+	{domain.ErrOrderNotModifiable, "ORDER_NOT_MODIFIABLE"},
+	{domain.ErrOrderAlreadyCheckedOut, "ORDER_ALREADY_CHECKED_OUT"},
+	{domain.ErrOrderCancelled, "ORDER_CANCELLED"},
+	{domain.ErrInvalidOrderStatusTransition, "INVALID_ORDER_STATUS_TRANSITION"},
+	{domain.ErrCustomerNotFound, "CUSTOMER_NOT_FOUND"},
+	{domain.ErrCustomerAlreadyExists, "CUSTOMER_ALREADY_EXISTS"},
+	{domain.ErrInvalidEmailAddress, CodeBadUserInput},
+	{domain.ErrInvalidPassword, CodeBadUserInput},
+	{domain.ErrInvalidCredentials, "INVALID_CREDENTIALS"},
+	{domain.ErrInvalidAddress, CodeBadUserInput},
+	{domain.ErrShippingAddressRequired, "SHIPPING_ADDRESS_REQUIRED"},
+	{domain.ErrShippingMethodNotFound, "SHIPPING_METHOD_NOT_FOUND"},
+	{domain.ErrShippingMethodNotEligible, "SHIPPING_METHOD_NOT_ELIGIBLE"},
+	{domain.ErrShippingMethodRequired, "SHIPPING_METHOD_REQUIRED"},
+	{domain.ErrOrderNotAwaitingPayment, "ORDER_NOT_AWAITING_PAYMENT"},
+	{domain.ErrPaymentMethodNotFound, "PAYMENT_METHOD_NOT_FOUND"},
+	{domain.ErrPaymentDeclined, "PAYMENT_DECLINED"},
+	{domain.ErrPaymentFailed, "PAYMENT_FAILED"},
+	{domain.ErrPaymentPending, "PAYMENT_PENDING"},
+	{domain.ErrOrderNotRefundable, "ORDER_NOT_REFUNDABLE"},
+	{domain.ErrInvalidRefundQuantity, CodeBadUserInput},
+	{domain.ErrRefundFailed, "REFUND_FAILED"},
+	{domain.ErrCouponCodeNotFound, "COUPON_CODE_NOT_FOUND"},
+	{domain.ErrCouponCodeExpired, "COUPON_CODE_EXPIRED"},
+	{domain.ErrCouponCodeNotEligible, "COUPON_CODE_NOT_ELIGIBLE"},
+	{domain.ErrCouponCodeUsageLimitReached, "COUPON_CODE_USAGE_LIMIT_REACHED"},
+	{domain.ErrCouponCodeNotApplied, "COUPON_CODE_NOT_APPLIED"},
+	{errNoActiveOrder, CodeNoActiveOrder},
+}
+
+// Error is a GraphQL error carrying a stable code in its extensions.
+type Error struct {
+	Code    string
+	Message string
+	// Fields lists the invalid fields of the input, if any.
+	Fields []domain.FieldError
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{
+		"code": e.Code,
+	}
+
+	if len(e.Fields) > 0 {
+		fields := make([]map[string]string, 0, len(e.Fields))
+		for _, field := range e.Fields {
+			fields = append(fields, map[string]string{"field": field.Field, "message": field.Message})
+		}
+		extensions["fields"] = fields
+	}
+
+	return extensions
+}
+
+func badUserInput(message string) *Error {
+	return &Error{Code: CodeBadUserInput, Message: message}
+}
+
+// toGraphQLError converts err into an *Error, hiding the message of
+// errors we don't know about.
+func toGraphQLError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var gqlErr *Error
+	if errors.As(err, &gqlErr) {
+		return gqlErr
+	}
+
+	var fields []domain.FieldError
+	var validationErr *domain.ValidationError
+	if errors.As(err, &validationErr) {
+		fields = validationErr.Fields
+	}
+
+	for _, ec := range errorCodes {
+		if errors.Is(err, ec.err) {
+			return &Error{Code: ec.code, Message: err.Error(), Fields: fields}
+		}
+	}
+
+	return &Error{Code: CodeInternal, Message: "internal server error"}
+}
@@ -0,0 +1,25 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+
+	"github.com/donnpebe/shoppo"
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+// NewHandler returns an http.Handler serving the shoppo GraphQL API
+// backed by shop.
+func NewHandler(shop domain.ShopService) http.Handler {
+	sessions := newSessionStore()
+	resolver := &Resolver{
+		shop:     shop,
+		sessions: sessions,
+	}
+
+	schema := graphql.MustParseSchema(shoppo.Schema, resolver, graphql.UseStringDescriptions())
+
+	return withSession(sessions, &relay.Handler{Schema: schema})
+}
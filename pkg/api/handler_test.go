@@ -0,0 +1,661 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+	"github.com/donnpebe/shoppo/pkg/lib/paymentprovider"
+	"github.com/donnpebe/shoppo/pkg/lib/promotioncondition"
+	"github.com/donnpebe/shoppo/pkg/lib/shippingcalculator"
+	"github.com/donnpebe/shoppo/pkg/services"
+	"github.com/donnpebe/shoppo/pkg/storage/memory"
+)
+
+type graphqlResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string `json:"message"`
+		Extensions struct {
+			Code   string `json:"code"`
+			Fields []struct {
+				Field   string `json:"field"`
+				Message string `json:"message"`
+			} `json:"fields"`
+		} `json:"extensions"`
+	} `json:"errors"`
+}
+
+type testClient struct {
+	t       *testing.T
+	handler http.Handler
+	cookies []*http.Cookie
+}
+
+func newTestClient(t *testing.T) *testClient {
+	inventories := map[string]*domain.Product{
+		"p01": {
+			ID:         "p01",
+			SKU:        "120P90",
+			Name:       "Google Home",
+			UnitPrice:  domain.MustParseMoney("49.99", "USD"),
+			Quantity:   5,
+			Categories: []string{"smart-home", "speakers"},
+			Tags:       []string{"sale"},
+		},
+		"p02": {
+			ID:        "p02",
+			SKU:       "43N23P",
+			Name:      "MacBook Pro",
+			UnitPrice: domain.MustParseMoney("5399.99", "USD"),
+			Quantity:  4,
+		},
+	}
+
+	shop := services.NewShopService(memory.NewStore(inventories, nil), nil, services.WithPasswordCost(bcrypt.MinCost))
+	return &testClient{t: t, handler: NewHandler(shop)}
+}
+
+func (c *testClient) do(query string) graphqlResponse {
+	body, err := json.Marshal(map[string]string{"query": query})
+	require.NoError(c.t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	for _, cookie := range c.cookies {
+		req.AddCookie(cookie)
+	}
+
+	rec := httptest.NewRecorder()
+	c.handler.ServeHTTP(rec, req)
+	require.Equal(c.t, http.StatusOK, rec.Code)
+
+	if cookies := rec.Result().Cookies(); len(cookies) > 0 {
+		c.cookies = cookies
+	}
+
+	var resp graphqlResponse
+	require.NoError(c.t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp
+}
+
+func TestHandler_Products(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "should return all products ordered by id",
+			query: `{ products { totalItems items { id name sku unitPrice quantity availableQuantity } } }`,
+			want:  `{"totalItems":2,"items":[{"id":"p01","name":"Google Home","sku":"120P90","unitPrice":49.99,"quantity":5,"availableQuantity":5},{"id":"p02","name":"MacBook Pro","sku":"43N23P","unitPrice":5399.99,"quantity":4,"availableQuantity":4}]}`,
+		},
+		{
+			name:  "should apply skip and limit after counting total items",
+			query: `{ products(options: {skip: 1, limit: 1}) { totalItems items { id } } }`,
+			want:  `{"totalItems":2,"items":[{"id":"p02"}]}`,
+		},
+		{
+			name:  "should return categories, tags and collections",
+			query: `{ products { items { id categories tags collections } } }`,
+			want:  `{"items":[{"id":"p01","categories":["smart-home","speakers"],"tags":["sale"],"collections":[]},{"id":"p02","categories":[],"tags":[],"collections":[]}]}`,
+		},
+		{
+			name:  "should filter products by name",
+			query: `{ products(options: {filter: {name: {regex: "^Mac"}}}) { totalItems items { id } } }`,
+			want:  `{"totalItems":1,"items":[{"id":"p02"}]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := newTestClient(t).do(test.query)
+			assert.Empty(t, resp.Errors)
+			assert.JSONEq(t, test.want, string(resp.Data["products"]))
+		})
+	}
+}
+
+func TestHandler_ProductVariants(t *testing.T) {
+	inventories := map[string]*domain.Product{
+		"p02": {
+			ID:        "p02",
+			Name:      "MacBook Pro",
+			UnitPrice: domain.MustParseMoney("5399.99", "USD"),
+		},
+		"p02-16gb": {
+			ID:        "p02-16gb",
+			SKU:       "43N23P-16",
+			Name:      "MacBook Pro 16GB",
+			UnitPrice: domain.MustParseMoney("5399.99", "USD"),
+			Quantity:  4,
+			ParentID:  "p02",
+			Options:   []domain.ProductOption{{Name: "Memory", Value: "16GB"}},
+		},
+		"p02-32gb": {
+			ID:        "p02-32gb",
+			SKU:       "43N23P-32",
+			Name:      "MacBook Pro 32GB",
+			UnitPrice: domain.MustParseMoney("5999.99", "USD"),
+			Quantity:  2,
+			ParentID:  "p02",
+			Options:   []domain.ProductOption{{Name: "Memory", Value: "32GB"}},
+		},
+	}
+	client := &testClient{t: t, handler: NewHandler(services.NewShopService(memory.NewStore(inventories, nil), nil))}
+
+	resp := client.do(`{ products { totalItems items { id parentId availableQuantity variants { id sku unitPrice parentId options { name value } } } } }`)
+	assert.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"totalItems":1,"items":[{"id":"p02","parentId":null,"availableQuantity":6,"variants":[`+
+		`{"id":"p02-16gb","sku":"43N23P-16","unitPrice":5399.99,"parentId":"p02","options":[{"name":"Memory","value":"16GB"}]},`+
+		`{"id":"p02-32gb","sku":"43N23P-32","unitPrice":5999.99,"parentId":"p02","options":[{"name":"Memory","value":"32GB"}]}]}]}`,
+		string(resp.Data["products"]))
+
+	resp = client.do(`mutation { addItemToOrder(productId: "p02", quantity: 1) { id } }`)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "VARIANT_REQUIRED", resp.Errors[0].Extensions.Code)
+
+	resp = client.do(`mutation { addItemToOrder(productId: "p02-32gb", quantity: 1) { orderLines { unitPrice product { id parentId } } } }`)
+	assert.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"orderLines":[{"unitPrice":5999.99,"product":{"id":"p02-32gb","parentId":"p02"}}]}`, string(resp.Data["addItemToOrder"]))
+}
+
+func TestHandler_ActiveOrder(t *testing.T) {
+	client := newTestClient(t)
+
+	resp := client.do(`{ activeOrder { id } }`)
+	assert.Empty(t, resp.Errors)
+	assert.JSONEq(t, `null`, string(resp.Data["activeOrder"]))
+
+	resp = client.do(`mutation { addItemToOrder(productId: "p01", quantity: 2) { id } }`)
+	assert.Empty(t, resp.Errors)
+	added := resp.Data["addItemToOrder"]
+
+	resp = client.do(`mutation { addItemToOrder(productId: "p01", quantity: 1) { id orderLines { quantity product { id } } } }`)
+	assert.Empty(t, resp.Errors)
+
+	var order struct {
+		ID         string `json:"id"`
+		OrderLines []struct {
+			Quantity int `json:"quantity"`
+		} `json:"orderLines"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Data["addItemToOrder"], &order))
+	assert.JSONEq(t, string(added), `{"id":"`+order.ID+`"}`)
+	require.Len(t, order.OrderLines, 1)
+	assert.Equal(t, 3, order.OrderLines[0].Quantity)
+
+	resp = client.do(`{ activeOrder { id total } }`)
+	assert.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"id":"`+order.ID+`","total":149.97}`, string(resp.Data["activeOrder"]))
+
+	resp = client.do(`mutation { checkout { id } }`)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "SHIPPING_ADDRESS_REQUIRED", resp.Errors[0].Extensions.Code)
+
+	resp = client.do(`mutation { setShippingAddress(input: {streetLine: "Jl. Jend. Sudirman Kav. 52-53", city: "Jakarta Selatan", province: "DKI Jakarta", country: "ID"}) { shippingAddress { city country fullName } } }`)
+	assert.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"shippingAddress":{"city":"Jakarta Selatan","country":"ID","fullName":null}}`, string(resp.Data["setShippingAddress"]))
+
+	resp = client.do(`mutation { checkout { id total } }`)
+	assert.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"id":"`+order.ID+`","total":149.97}`, string(resp.Data["checkout"]))
+
+	resp = client.do(`{ activeOrder { id } order(id: "` + order.ID + `") { id orderStatus pricing { subtotal discounts { promotionId } total currencyCode } } }`)
+	assert.Empty(t, resp.Errors)
+	assert.JSONEq(t, `null`, string(resp.Data["activeOrder"]))
+	assert.JSONEq(t, `{"id":"`+order.ID+`","orderStatus":"ArrangingPayment","pricing":{"subtotal":149.97,"discounts":[],"total":149.97,"currencyCode":"USD"}}`, string(resp.Data["order"]))
+
+	other := &testClient{t: t, handler: client.handler}
+	resp = other.do(`{ order(id: "` + order.ID + `") { id shippingAddress { city } } }`)
+	assert.Empty(t, resp.Errors)
+	assert.JSONEq(t, `null`, string(resp.Data["order"]))
+}
+
+func TestHandler_Customer(t *testing.T) {
+	client := newTestClient(t)
+
+	resp := client.do(`mutation { addItemToOrder(productId: "p01", quantity: 1) { id } }`)
+	require.Empty(t, resp.Errors)
+
+	resp = client.do(`mutation { registerCustomerAccount(emailAddress: "Jane@Example.com", password: "correct horse") { id emailAddress } }`)
+	require.Empty(t, resp.Errors)
+
+	var customer struct {
+		ID           string `json:"id"`
+		EmailAddress string `json:"emailAddress"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Data["registerCustomerAccount"], &customer))
+	assert.Equal(t, "jane@example.com", customer.EmailAddress)
+
+	resp = client.do(`{ activeCustomer { id } activeOrder { customer { emailAddress } orderLines { quantity } } }`)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"id":"`+customer.ID+`"}`, string(resp.Data["activeCustomer"]))
+	assert.JSONEq(t, `{"customer":{"emailAddress":"jane@example.com"},"orderLines":[{"quantity":1}]}`, string(resp.Data["activeOrder"]))
+
+	// The same customer fills another cart anonymously on another device
+	// before logging in.
+	other := &testClient{t: t, handler: client.handler}
+	resp = other.do(`mutation { addItemToOrder(productId: "p02", quantity: 1) { id } }`)
+	require.Empty(t, resp.Errors)
+
+	resp = other.do(`mutation { logIn(emailAddress: "jane@example.com", password: "battery staple") { id } }`)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "INVALID_CREDENTIALS", resp.Errors[0].Extensions.Code)
+
+	resp = other.do(`mutation { logIn(emailAddress: "jane@example.com", password: "correct horse") { id } }`)
+	require.Empty(t, resp.Errors)
+
+	resp = other.do(`{ activeOrder { orderLines { quantity product { id } } } }`)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"orderLines":[{"quantity":1,"product":{"id":"p01"}},{"quantity":1,"product":{"id":"p02"}}]}`, string(resp.Data["activeOrder"]))
+
+	resp = other.do(`mutation { logOut }`)
+	require.Empty(t, resp.Errors)
+
+	resp = other.do(`{ activeCustomer { id } activeOrder { id } }`)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `null`, string(resp.Data["activeCustomer"]))
+	assert.JSONEq(t, `null`, string(resp.Data["activeOrder"]))
+}
+
+func TestHandler_Sessions(t *testing.T) {
+	client := newTestClient(t)
+
+	// A session id the server never issued is replaced.
+	client.cookies = []*http.Cookie{{Name: sessionCookieName, Value: "planted"}}
+	resp := client.do(`{ activeCustomer { id } }`)
+	require.Empty(t, resp.Errors)
+	require.Len(t, client.cookies, 1)
+	assert.NotEqual(t, "planted", client.cookies[0].Value)
+
+	// An attacker who knows the session id before the customer logs in
+	// can't use it afterwards.
+	attacker := &testClient{t: t, handler: client.handler, cookies: client.cookies}
+	anonymous := client.cookies[0].Value
+
+	resp = client.do(`mutation { registerCustomerAccount(emailAddress: "jane@example.com", password: "correct horse") { id } }`)
+	require.Empty(t, resp.Errors)
+	require.Len(t, client.cookies, 1)
+	loggedIn := client.cookies[0].Value
+	assert.NotEqual(t, anonymous, loggedIn)
+
+	resp = attacker.do(`{ activeCustomer { id } }`)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `null`, string(resp.Data["activeCustomer"]))
+	assert.NotEqual(t, loggedIn, attacker.cookies[0].Value)
+
+	resp = client.do(`{ activeCustomer { emailAddress } }`)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"emailAddress":"jane@example.com"}`, string(resp.Data["activeCustomer"]))
+
+	// Logging out gives a new session and revokes the old one.
+	leaked := &testClient{t: t, handler: client.handler, cookies: client.cookies}
+	resp = client.do(`mutation { logOut }`)
+	require.Empty(t, resp.Errors)
+	assert.NotEqual(t, loggedIn, client.cookies[0].Value)
+
+	resp = leaked.do(`{ activeCustomer { id } }`)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `null`, string(resp.Data["activeCustomer"]))
+
+	// Logging in on a first request without any session works too.
+	other := &testClient{t: t, handler: client.handler}
+	resp = other.do(`mutation { logIn(emailAddress: "jane@example.com", password: "correct horse") { id } }`)
+	require.Empty(t, resp.Errors)
+	require.Len(t, other.cookies, 1)
+
+	resp = other.do(`{ activeCustomer { emailAddress } }`)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"emailAddress":"jane@example.com"}`, string(resp.Data["activeCustomer"]))
+}
+
+func TestHandler_InvalidAddress(t *testing.T) {
+	client := newTestClient(t)
+
+	resp := client.do(`mutation { addItemToOrder(productId: "p01", quantity: 1) { id } }`)
+	require.Empty(t, resp.Errors)
+
+	resp = client.do(`mutation { setBillingAddress(input: {streetLine: "", city: "Jakarta Selatan", province: "", country: "ID"}) { id } }`)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, CodeBadUserInput, resp.Errors[0].Extensions.Code)
+
+	var fields []string
+	for _, field := range resp.Errors[0].Extensions.Fields {
+		fields = append(fields, field.Field)
+	}
+	assert.Equal(t, []string{"streetLine", "province"}, fields)
+}
+
+func TestHandler_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    []string
+		query    string
+		wantCode string
+	}{
+		{
+			name:     "should return PRODUCT_NOT_FOUND when adding unknown product",
+			query:    `mutation { addItemToOrder(productId: "p99", quantity: 1) { id } }`,
+			wantCode: "PRODUCT_NOT_FOUND",
+		},
+		{
+			name:     "should return NOT_ENOUGH_STOCK when adding more than what's in stock",
+			query:    `mutation { addItemToOrder(productId: "p02", quantity: 5) { id } }`,
+			wantCode: "NOT_ENOUGH_STOCK",
+		},
+		{
+			name:     "should return BAD_USER_INPUT when quantity is not positive",
+			query:    `mutation { addItemToOrder(productId: "p01", quantity: 0) { id } }`,
+			wantCode: CodeBadUserInput,
+		},
+		{
+			name:     "should return NO_ACTIVE_ORDER when checking out without active order",
+			query:    `mutation { checkout { id } }`,
+			wantCode: CodeNoActiveOrder,
+		},
+		{
+			name:     "should return INVALID_CREDENTIALS when logging in with unknown email address",
+			query:    `mutation { logIn(emailAddress: "jane@example.com", password: "correct horse") { id } }`,
+			wantCode: "INVALID_CREDENTIALS",
+		},
+		{
+			name:     "should return CUSTOMER_ALREADY_EXISTS when registering the same email address twice",
+			setup:    []string{`mutation { registerCustomerAccount(emailAddress: "jane@example.com", password: "correct horse") { id } }`},
+			query:    `mutation { registerCustomerAccount(emailAddress: "jane@example.com", password: "correct horse") { id } }`,
+			wantCode: "CUSTOMER_ALREADY_EXISTS",
+		},
+		{
+			name:     "should return BAD_USER_INPUT when registering an invalid email address",
+			query:    `mutation { registerCustomerAccount(emailAddress: "jane", password: "correct horse") { id } }`,
+			wantCode: CodeBadUserInput,
+		},
+		{
+			name:     "should return BAD_USER_INPUT when registering a too short password",
+			query:    `mutation { registerCustomerAccount(emailAddress: "jane@example.com", password: "short") { id } }`,
+			wantCode: CodeBadUserInput,
+		},
+		{
+			name:     "should return ITEM_NOT_FOUND_IN_CART when removing unknown order line",
+			setup:    []string{`mutation { addItemToOrder(productId: "p01", quantity: 1) { id } }`},
+			query:    `mutation { removeOrderLine(orderLineId: "unknown") { id } }`,
+			wantCode: "ITEM_NOT_FOUND_IN_CART",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := newTestClient(t)
+			for _, query := range test.setup {
+				resp := client.do(query)
+				require.Empty(t, resp.Errors)
+			}
+
+			resp := client.do(test.query)
+			require.Len(t, resp.Errors, 1)
+			assert.Equal(t, test.wantCode, resp.Errors[0].Extensions.Code)
+		})
+	}
+}
+
+func TestHandler_ShippingMethods(t *testing.T) {
+	shop := services.NewShopService(memory.NewStore(map[string]*domain.Product{
+		"p01": {
+			ID:        "p01",
+			SKU:       "120P90",
+			Name:      "Google Home",
+			UnitPrice: domain.MustParseMoney("49.99", "USD"),
+			Quantity:  5,
+			Weight:    480,
+		},
+	}, nil), nil, services.WithShippingMethods(
+		domain.ShippingMethod{
+			ID:         "express",
+			Code:       "express",
+			Name:       "Express",
+			Calculator: shippingcalculator.FlatRate{Cost: domain.MustParseMoney("15", "USD")},
+		},
+	))
+	client := &testClient{t: t, handler: NewHandler(shop)}
+
+	resp := client.do(`{ eligibleShippingMethods { price } }`)
+	assert.Empty(t, resp.Errors)
+	assert.JSONEq(t, `[]`, string(resp.Data["eligibleShippingMethods"]))
+
+	resp = client.do(`mutation { addItemToOrder(productId: "p01", quantity: 1) { id } }`)
+	require.Empty(t, resp.Errors)
+
+	resp = client.do(`{ eligibleShippingMethods { shippingMethod { id code name } price } }`)
+	assert.Empty(t, resp.Errors)
+	assert.JSONEq(t, `[{"shippingMethod":{"id":"express","code":"express","name":"Express"},"price":15}]`, string(resp.Data["eligibleShippingMethods"]))
+
+	resp = client.do(`mutation { setOrderShippingMethod(shippingMethodId: "pigeon") { id } }`)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "SHIPPING_METHOD_NOT_FOUND", resp.Errors[0].Extensions.Code)
+
+	resp = client.do(`mutation { setOrderShippingMethod(shippingMethodId: "express") { shippingMethod { id } pricing { shipping total } } }`)
+	assert.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"shippingMethod":{"id":"express"},"pricing":{"shipping":15,"total":64.99}}`, string(resp.Data["setOrderShippingMethod"]))
+}
+
+func TestHandler_AddPayment(t *testing.T) {
+	shop := services.NewShopService(memory.NewStore(map[string]*domain.Product{
+		"p01": {
+			ID:        "p01",
+			SKU:       "120P90",
+			Name:      "Google Home",
+			UnitPrice: domain.MustParseMoney("49.99", "USD"),
+			Quantity:  5,
+			Digital:   true,
+		},
+	}, nil), nil, services.WithPaymentMethods(
+		domain.PaymentMethod{Code: "card", Name: "Credit Card", Provider: paymentprovider.NewFake()},
+	))
+	client := &testClient{t: t, handler: NewHandler(shop)}
+
+	resp := client.do(`{ eligiblePaymentMethods { code name } }`)
+	assert.Empty(t, resp.Errors)
+	assert.JSONEq(t, `[{"code":"card","name":"Credit Card"}]`, string(resp.Data["eligiblePaymentMethods"]))
+
+	resp = client.do(`mutation { addItemToOrder(productId: "p01", quantity: 2) { id } }`)
+	require.Empty(t, resp.Errors)
+
+	var order struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Data["addItemToOrder"], &order))
+
+	resp = client.do(`mutation { addPayment(input: {orderId: "` + order.ID + `", method: "card"}) { id } }`)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "ORDER_NOT_AWAITING_PAYMENT", resp.Errors[0].Extensions.Code)
+
+	resp = client.do(`mutation { checkout { id } }`)
+	require.Empty(t, resp.Errors)
+
+	stranger := &testClient{t: t, handler: client.handler}
+	resp = stranger.do(`mutation { addPayment(input: {orderId: "` + order.ID + `", method: "card"}) { id } }`)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "CART_NOT_FOUND", resp.Errors[0].Extensions.Code)
+
+	resp = client.do(`mutation { addPayment(input: {orderId: "` + order.ID + `", method: "card", token: "decline"}) { id } }`)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "PAYMENT_DECLINED", resp.Errors[0].Extensions.Code)
+
+	resp = client.do(`mutation { addPayment(input: {orderId: "` + order.ID + `", method: "card", token: "tok_visa"}) { orderStatus paymentType payments { method amount state errorMessage } refunds { amount } } }`)
+	assert.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{
+		"orderStatus": "Paid",
+		"paymentType": "card",
+		"payments": [
+			{"method": "card", "amount": 99.98, "state": "Declined", "errorMessage": "payment declined: insufficient funds"},
+			{"method": "card", "amount": 99.98, "state": "Settled", "errorMessage": null}
+		],
+		"refunds": []
+	}`, string(resp.Data["addPayment"]))
+}
+
+// unserializableCondition is a condition that can't be configured.
+type unserializableCondition struct{}
+
+func (unserializableCondition) CalculateDiscount(order *domain.Order) domain.Money {
+	return domain.Money{}
+}
+
+func TestHandler_ActivePromotions(t *testing.T) {
+	promotions := []domain.Promotion{
+		{
+			ID:        "googlehome-3-for-2",
+			Name:      "3 Google Homes for the price of 2",
+			StartDate: time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC),
+			Condition: promotioncondition.ProductQuantityDiscount{
+				ProductID:          "p01",
+				RequiredQuantity:   3,
+				DiscountedQuantity: 1,
+			},
+			Priority:   10,
+			StackGroup: "googlehome",
+		},
+		{
+			ID:        "custom",
+			Name:      "Custom",
+			Condition: unserializableCondition{},
+			Exclusive: true,
+		},
+		{
+			ID:      "expired",
+			Name:    "Expired",
+			EndDate: time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC),
+			Condition: promotioncondition.ProductQuantityDiscount{
+				ProductID:          "p01",
+				RequiredQuantity:   2,
+				DiscountedQuantity: 1,
+			},
+		},
+	}
+	shop := services.NewShopService(memory.NewStore(map[string]*domain.Product{}, nil), promotions)
+	client := &testClient{t: t, handler: NewHandler(shop)}
+
+	resp := client.do(`{ activePromotions { id name type parameters startDate endDate priority exclusive stackGroup maxOnePerLine } }`)
+	assert.Empty(t, resp.Errors)
+	assert.JSONEq(t, `[
+		{
+			"id": "googlehome-3-for-2",
+			"name": "3 Google Homes for the price of 2",
+			"type": "product_quantity_discount",
+			"parameters": {"productId": "p01", "requiredQuantity": 3, "discountedQuantity": 1},
+			"startDate": "2022-03-01T00:00:00Z",
+			"endDate": null,
+			"priority": 10,
+			"exclusive": false,
+			"stackGroup": "googlehome",
+			"maxOnePerLine": false
+		},
+		{
+			"id": "custom",
+			"name": "Custom",
+			"type": null,
+			"parameters": null,
+			"startDate": null,
+			"endDate": null,
+			"priority": 0,
+			"exclusive": true,
+			"stackGroup": null,
+			"maxOnePerLine": false
+		}
+	]`, string(resp.Data["activePromotions"]))
+}
+
+func TestHandler_LineDiscounts(t *testing.T) {
+	inventories := map[string]*domain.Product{
+		"p01": {
+			ID:        "p01",
+			SKU:       "120P90",
+			Name:      "Google Home",
+			UnitPrice: domain.MustParseMoney("49.99", "USD"),
+			Quantity:  5,
+		},
+	}
+	promotions := []domain.Promotion{
+		{
+			ID:   "googlehome-3-for-2",
+			Name: "3 Google Homes for the price of 2",
+			Condition: promotioncondition.ProductQuantityDiscount{
+				ProductID:          "p01",
+				RequiredQuantity:   3,
+				DiscountedQuantity: 1,
+			},
+		},
+	}
+	shop := services.NewShopService(memory.NewStore(inventories, nil), promotions)
+	client := &testClient{t: t, handler: NewHandler(shop)}
+
+	resp := client.do(`mutation { addItemToOrder(productId: "p01", quantity: 3) { id } }`)
+	require.Empty(t, resp.Errors)
+
+	resp = client.do(`{ activeOrder { pricing {
+		lines { quantity subtotal discount total }
+		discounts { promotionId amount lines { quantity amount } }
+	} } }`)
+	assert.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"pricing": {
+		"lines": [{"quantity": 3, "subtotal": 149.97, "discount": -49.99, "total": 99.98}],
+		"discounts": [{"promotionId": "googlehome-3-for-2", "amount": -49.99, "lines": [{"quantity": 1, "amount": -49.99}]}]
+	}}`, string(resp.Data["activeOrder"]))
+}
+
+func TestHandler_Coupons(t *testing.T) {
+	inventories := map[string]*domain.Product{
+		"p01": {
+			ID:        "p01",
+			SKU:       "120P90",
+			Name:      "Google Home",
+			UnitPrice: domain.MustParseMoney("49.99", "USD"),
+			Quantity:  5,
+		},
+	}
+	promotions := []domain.Promotion{
+		{
+			ID:   "googlehome-3-for-2",
+			Name: "3 Google Homes for the price of 2",
+			Condition: promotioncondition.ProductQuantityDiscount{
+				ProductID:          "p01",
+				RequiredQuantity:   3,
+				DiscountedQuantity: 1,
+			},
+			CouponCode: "THREE",
+		},
+	}
+	shop := services.NewShopService(memory.NewStore(inventories, nil), promotions)
+	client := &testClient{t: t, handler: NewHandler(shop)}
+
+	resp := client.do(`{ activePromotions { id } }`)
+	assert.Empty(t, resp.Errors)
+	assert.JSONEq(t, `[]`, string(resp.Data["activePromotions"]))
+
+	resp = client.do(`mutation { addItemToOrder(productId: "p01", quantity: 3) { pricing { discountTotal } } }`)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"pricing": {"discountTotal": 0}}`, string(resp.Data["addItemToOrder"]))
+
+	resp = client.do(`mutation { applyCouponCode(couponCode: "unknown") { id } }`)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "COUPON_CODE_NOT_FOUND", resp.Errors[0].Extensions.Code)
+
+	resp = client.do(`mutation { applyCouponCode(couponCode: " three ") { couponCodes pricing { discountTotal } } }`)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"couponCodes": ["THREE"], "pricing": {"discountTotal": -49.99}}`, string(resp.Data["applyCouponCode"]))
+
+	resp = client.do(`mutation { removeCouponCode(couponCode: "THREE") { couponCodes pricing { discountTotal } } }`)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"couponCodes": [], "pricing": {"discountTotal": 0}}`, string(resp.Data["removeCouponCode"]))
+
+	resp = client.do(`mutation { removeCouponCode(couponCode: "THREE") { id } }`)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "COUPON_CODE_NOT_APPLIED", resp.Errors[0].Extensions.Code)
+}
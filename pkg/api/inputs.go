@@ -0,0 +1,117 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+type productListOptions struct {
+	Skip   *int32
+	Limit  *int32
+	Filter *productFilterParameter
+}
+
+type productFilterParameter struct {
+	CreatedAt *dateOperators
+	UpdatedAt *dateOperators
+	Name      *stringOperators
+}
+
+type dateOperators struct {
+	Eq  *Date
+	Lt  *Date
+	Lte *Date
+	Gt  *Date
+	Gte *Date
+}
+
+type stringOperators struct {
+	Eq    *string
+	Ne    *string
+	Regex *string
+}
+
+type createAddressInput struct {
+	FullName    *string
+	Company     *string
+	StreetLine  string
+	City        string
+	Province    string
+	PostalCode  *string
+	Country     string
+	PhoneNumber *string
+}
+
+func (input createAddressInput) toAddress() domain.Address {
+	return domain.Address{
+		FullName:    stringValue(input.FullName),
+		Company:     stringValue(input.Company),
+		StreetLine:  input.StreetLine,
+		City:        input.City,
+		Province:    input.Province,
+		PostalCode:  stringValue(input.PostalCode),
+		Country:     input.Country,
+		PhoneNumber: stringValue(input.PhoneNumber),
+	}
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
+
+type paymentInput struct {
+	OrderID graphql.ID
+	Method  string
+	Token   *string
+}
+
+// Date is the GraphQL Date scalar, encoded as an RFC 3339 string.
+type Date struct {
+	time.Time
+}
+
+func (Date) ImplementsGraphQLType(name string) bool {
+	return name == "Date"
+}
+
+func (d *Date) UnmarshalGraphQL(input interface{}) error {
+	s, ok := input.(string)
+	if !ok {
+		return fmt.Errorf("wrong type for Date: %T", input)
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return err
+	}
+
+	d.Time = t
+	return nil
+}
+
+// JSON is the GraphQL JSON scalar, holding any JSON value.
+type JSON struct {
+	Value interface{}
+}
+
+func (JSON) ImplementsGraphQLType(name string) bool {
+	return name == "JSON"
+}
+
+func (j *JSON) UnmarshalGraphQL(input interface{}) error {
+	j.Value = input
+	return nil
+}
+
+func (j JSON) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.Value)
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"github.com/graph-gophers/graphql-go"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+type orderResolver struct {
+	shop  domain.ShopService
+	order *domain.Order
+}
+
+func (r *orderResolver) ID() graphql.ID {
+	return graphql.ID(r.order.ID)
+}
+
+func (r *orderResolver) Customer() (*customerResolver, error) {
+	if r.order.CustomerID == "" {
+		return nil, nil
+	}
+
+	customer, err := r.shop.GetCustomer(r.order.CustomerID)
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	return &customerResolver{customer: customer}, nil
+}
+
+// PaymentType returns the method of the last settled payment.
+func (r *orderResolver) PaymentType() *string {
+	for i := len(r.order.Payments) - 1; i >= 0; i-- {
+		if payment := r.order.Payments[i]; payment.State == domain.PaymentStateSettled {
+			return &payment.Method
+		}
+	}
+
+	return nil
+}
+
+func (r *orderResolver) Payments() []*paymentResolver {
+	payments := make([]*paymentResolver, 0, len(r.order.Payments))
+	for _, payment := range r.order.Payments {
+		payments = append(payments, &paymentResolver{payment: payment})
+	}
+
+	return payments
+}
+
+func (r *orderResolver) OrderStatus() *string {
+	if r.order.Status == "" {
+		return nil
+	}
+
+	status := string(r.order.Status)
+	return &status
+}
+
+func (r *orderResolver) Address() *string {
+	return nil
+}
+
+func (r *orderResolver) OrderLines() *[]*orderLineResolver {
+	lines := make([]*orderLineResolver, 0, len(r.order.Lines))
+	for _, line := range r.order.Lines {
+		lines = append(lines, &orderLineResolver{shop: r.shop, line: line})
+	}
+
+	return &lines
+}
+
+func (r *orderResolver) ShippingAddress() *orderAddressResolver {
+	if r.order.ShippingAddress == nil {
+		return nil
+	}
+
+	return &orderAddressResolver{address: r.order.ShippingAddress}
+}
+
+func (r *orderResolver) BillingAddress() *orderAddressResolver {
+	if r.order.BillingAddress == nil {
+		return nil
+	}
+
+	return &orderAddressResolver{address: r.order.BillingAddress}
+}
+
+func (r *orderResolver) ShippingMethod() (*shippingMethodResolver, error) {
+	if r.order.ShippingMethodID == "" {
+		return nil, nil
+	}
+
+	method, err := r.shop.GetShippingMethod(r.order.ShippingMethodID)
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	return &shippingMethodResolver{method: method}, nil
+}
+
+func (r *orderResolver) CouponCodes() []string {
+	return append([]string{}, r.order.CouponCodes...)
+}
+
+func (r *orderResolver) Refunds() []*refundResolver {
+	refunds := make([]*refundResolver, 0, len(r.order.Refunds))
+	for _, refund := range r.order.Refunds {
+		refunds = append(refunds, &refundResolver{refund: refund})
+	}
+
+	return refunds
+}
+
+func (r *orderResolver) Total() (*float64, error) {
+	pricing, err := r.pricing()
+	if err != nil || pricing == nil {
+		return nil, err
+	}
+
+	total := pricing.Total.Float64()
+	return &total, nil
+}
+
+func (r *orderResolver) Pricing() (*pricingResolver, error) {
+	pricing, err := r.pricing()
+	if err != nil || pricing == nil {
+		return nil, err
+	}
+
+	return &pricingResolver{pricing: pricing}, nil
+}
+
+// pricing returns the pricing stored at checkout, or a quote while the
+// order is still a cart.
+func (r *orderResolver) pricing() (*domain.Pricing, error) {
+	if r.order.Pricing != nil {
+		return r.order.Pricing, nil
+	}
+
+	if !r.order.IsModifiable() {
+		return nil, nil
+	}
+
+	pricing, err := r.shop.QuoteOrder(r.order.ID)
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	return pricing, nil
+}
+
+type orderLineResolver struct {
+	shop domain.ShopService
+	line *domain.OrderLine
+}
+
+func (r *orderLineResolver) ID() graphql.ID {
+	return graphql.ID(r.line.ID)
+}
+
+func (r *orderLineResolver) Product() (*productResolver, error) {
+	product, err := r.shop.GetProduct(r.line.ProductID)
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	return &productResolver{shop: r.shop, product: product}, nil
+}
+
+func (r *orderLineResolver) UnitPrice() float64 {
+	return r.line.UnitPrice.Float64()
+}
+
+func (r *orderLineResolver) Quantity() int32 {
+	return int32(r.line.Quantity)
+}
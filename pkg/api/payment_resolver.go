@@ -0,0 +1,96 @@
+package api
+
+import (
+	"github.com/graph-gophers/graphql-go"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+type paymentMethodResolver struct {
+	method *domain.PaymentMethod
+}
+
+func (r *paymentMethodResolver) Code() string {
+	return r.method.Code
+}
+
+func (r *paymentMethodResolver) Name() string {
+	return r.method.Name
+}
+
+type paymentResolver struct {
+	payment *domain.Payment
+}
+
+func (r *paymentResolver) ID() graphql.ID {
+	return graphql.ID(r.payment.ID)
+}
+
+func (r *paymentResolver) Method() string {
+	return r.payment.Method
+}
+
+func (r *paymentResolver) Amount() float64 {
+	return r.payment.Amount.Float64()
+}
+
+func (r *paymentResolver) State() string {
+	return string(r.payment.State)
+}
+
+func (r *paymentResolver) TransactionID() *string {
+	return optionalString(r.payment.TransactionID)
+}
+
+func (r *paymentResolver) ErrorMessage() *string {
+	return optionalString(r.payment.ErrorMessage)
+}
+
+type refundResolver struct {
+	refund *domain.Refund
+}
+
+func (r *refundResolver) ID() graphql.ID {
+	return graphql.ID(r.refund.ID)
+}
+
+func (r *refundResolver) Amount() float64 {
+	return r.refund.Amount.Float64()
+}
+
+func (r *refundResolver) Reason() *string {
+	return optionalString(r.refund.Reason)
+}
+
+func (r *refundResolver) Lines() []*refundLineResolver {
+	lines := make([]*refundLineResolver, 0, len(r.refund.Lines))
+	for i := range r.refund.Lines {
+		lines = append(lines, &refundLineResolver{line: &r.refund.Lines[i]})
+	}
+
+	return lines
+}
+
+func (r *refundResolver) State() string {
+	return string(r.refund.State)
+}
+
+func (r *refundResolver) TransactionID() *string {
+	return optionalString(r.refund.TransactionID)
+}
+
+func (r *refundResolver) ErrorMessage() *string {
+	return optionalString(r.refund.ErrorMessage)
+}
+
+type refundLineResolver struct {
+	line *domain.RefundLine
+}
+
+func (r *refundLineResolver) OrderLineID() graphql.ID {
+	return graphql.ID(r.line.OrderLineID)
+}
+
+func (r *refundLineResolver) Quantity() int32 {
+	return int32(r.line.Quantity)
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"github.com/graph-gophers/graphql-go"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+type pricingResolver struct {
+	pricing *domain.Pricing
+}
+
+func (r *pricingResolver) Lines() []*linePricingResolver {
+	lines := make([]*linePricingResolver, 0, len(r.pricing.Lines))
+	for i := range r.pricing.Lines {
+		lines = append(lines, &linePricingResolver{line: &r.pricing.Lines[i]})
+	}
+
+	return lines
+}
+
+func (r *pricingResolver) Subtotal() float64 {
+	return r.pricing.Subtotal.Float64()
+}
+
+func (r *pricingResolver) Discounts() []*appliedPromotionResolver {
+	discounts := make([]*appliedPromotionResolver, 0, len(r.pricing.Discounts))
+	for i := range r.pricing.Discounts {
+		discounts = append(discounts, &appliedPromotionResolver{discount: &r.pricing.Discounts[i]})
+	}
+
+	return discounts
+}
+
+func (r *pricingResolver) DiscountTotal() float64 {
+	return r.pricing.DiscountTotal.Float64()
+}
+
+func (r *pricingResolver) Shipping() float64 {
+	return r.pricing.Shipping.Float64()
+}
+
+func (r *pricingResolver) Total() float64 {
+	return r.pricing.Total.Float64()
+}
+
+func (r *pricingResolver) CurrencyCode() string {
+	return r.pricing.Total.Currency
+}
+
+type linePricingResolver struct {
+	line *domain.LinePricing
+}
+
+func (r *linePricingResolver) OrderLineID() graphql.ID {
+	return graphql.ID(r.line.OrderLineID)
+}
+
+func (r *linePricingResolver) ProductID() graphql.ID {
+	return graphql.ID(r.line.ProductID)
+}
+
+func (r *linePricingResolver) Quantity() int32 {
+	return int32(r.line.Quantity)
+}
+
+func (r *linePricingResolver) UnitPrice() float64 {
+	return r.line.UnitPrice.Float64()
+}
+
+func (r *linePricingResolver) Subtotal() float64 {
+	return r.line.Subtotal.Float64()
+}
+
+func (r *linePricingResolver) Discount() float64 {
+	return r.line.Discount.Float64()
+}
+
+func (r *linePricingResolver) Total() float64 {
+	return r.line.Total().Float64()
+}
+
+type appliedPromotionResolver struct {
+	discount *domain.AppliedPromotion
+}
+
+func (r *appliedPromotionResolver) PromotionID() graphql.ID {
+	return graphql.ID(r.discount.PromotionID)
+}
+
+func (r *appliedPromotionResolver) Name() string {
+	return r.discount.Name
+}
+
+func (r *appliedPromotionResolver) Amount() float64 {
+	return r.discount.Amount.Float64()
+}
+
+func (r *appliedPromotionResolver) Lines() []*lineDiscountResolver {
+	lines := make([]*lineDiscountResolver, 0, len(r.discount.Lines))
+	for i := range r.discount.Lines {
+		lines = append(lines, &lineDiscountResolver{discount: &r.discount.Lines[i]})
+	}
+
+	return lines
+}
+
+type lineDiscountResolver struct {
+	discount *domain.LineDiscount
+}
+
+func (r *lineDiscountResolver) OrderLineID() graphql.ID {
+	return graphql.ID(r.discount.OrderLineID)
+}
+
+func (r *lineDiscountResolver) Quantity() int32 {
+	return int32(r.discount.Quantity)
+}
+
+func (r *lineDiscountResolver) Amount() float64 {
+	return r.discount.Amount.Float64()
+}
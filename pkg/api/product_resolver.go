@@ -0,0 +1,124 @@
+package api
+
+import (
+	"github.com/graph-gophers/graphql-go"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+type productResolver struct {
+	shop    domain.ShopService
+	product *domain.Product
+}
+
+func (r *productResolver) ID() graphql.ID {
+	return graphql.ID(r.product.ID)
+}
+
+func (r *productResolver) Name() string {
+	return r.product.Name
+}
+
+func (r *productResolver) SKU() *string {
+	if r.product.SKU == "" {
+		return nil
+	}
+
+	return &r.product.SKU
+}
+
+func (r *productResolver) UnitPrice() float64 {
+	return r.product.UnitPrice.Float64()
+}
+
+func (r *productResolver) CurrencyCode() string {
+	return r.product.UnitPrice.Currency
+}
+
+func (r *productResolver) Quantity() int32 {
+	return int32(r.product.Quantity)
+}
+
+func (r *productResolver) Digital() bool {
+	return r.product.Digital
+}
+
+func (r *productResolver) Categories() []string {
+	return append([]string{}, r.product.Categories...)
+}
+
+func (r *productResolver) Tags() []string {
+	return append([]string{}, r.product.Tags...)
+}
+
+func (r *productResolver) Collections() []string {
+	return append([]string{}, r.product.Collections...)
+}
+
+func (r *productResolver) ParentID() *graphql.ID {
+	if r.product.ParentID == "" {
+		return nil
+	}
+
+	id := graphql.ID(r.product.ParentID)
+	return &id
+}
+
+func (r *productResolver) Options() []*productOptionResolver {
+	options := make([]*productOptionResolver, 0, len(r.product.Options))
+	for _, option := range r.product.Options {
+		options = append(options, &productOptionResolver{option: option})
+	}
+
+	return options
+}
+
+func (r *productResolver) Variants() []*productResolver {
+	variants := make([]*productResolver, 0, len(r.product.Variants))
+	for _, variant := range r.product.Variants {
+		variants = append(variants, &productResolver{shop: r.shop, product: variant})
+	}
+
+	return variants
+}
+
+func (r *productResolver) AvailableQuantity() (int32, error) {
+	stock, err := r.shop.GetStockLevel(r.product.ID)
+	if err != nil {
+		return 0, toGraphQLError(err)
+	}
+
+	return int32(stock.Available), nil
+}
+
+type productOptionResolver struct {
+	option domain.ProductOption
+}
+
+func (r *productOptionResolver) Name() string {
+	return r.option.Name
+}
+
+func (r *productOptionResolver) Value() string {
+	return r.option.Value
+}
+
+type productListResolver struct {
+	shop       domain.ShopService
+	products   []*domain.Product
+	totalItems int
+}
+
+func (r *productListResolver) Items() *[]*productResolver {
+	items := make([]*productResolver, 0, len(r.products))
+	for _, product := range r.products {
+		items = append(items, &productResolver{shop: r.shop, product: product})
+	}
+
+	return &items
+}
+
+func (r *productListResolver) TotalItems() *int32 {
+	totalItems := int32(r.totalItems)
+	return &totalItems
+}
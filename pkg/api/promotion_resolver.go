@@ -0,0 +1,76 @@
+package api
+
+import (
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+	"github.com/donnpebe/shoppo/pkg/lib/promotioncondition"
+)
+
+type promotionResolver struct {
+	promotion *domain.Promotion
+}
+
+func (r *promotionResolver) ID() graphql.ID {
+	return graphql.ID(r.promotion.ID)
+}
+
+func (r *promotionResolver) Name() string {
+	return r.promotion.Name
+}
+
+func (r *promotionResolver) Type() *string {
+	typeName, _, err := promotioncondition.DefaultRegistry.Encode(r.promotion.Condition)
+	if err != nil {
+		return nil
+	}
+
+	return &typeName
+}
+
+func (r *promotionResolver) Parameters() *JSON {
+	_, params, err := promotioncondition.DefaultRegistry.Encode(r.promotion.Condition)
+	if err != nil {
+		return nil
+	}
+
+	return &JSON{Value: params}
+}
+
+func (r *promotionResolver) StartDate() *Date {
+	return optionalDate(r.promotion.StartDate)
+}
+
+func (r *promotionResolver) EndDate() *Date {
+	return optionalDate(r.promotion.EndDate)
+}
+
+func (r *promotionResolver) Priority() int32 {
+	return int32(r.promotion.Priority)
+}
+
+func (r *promotionResolver) Exclusive() bool {
+	return r.promotion.Exclusive
+}
+
+func (r *promotionResolver) StackGroup() *string {
+	if r.promotion.StackGroup == "" {
+		return nil
+	}
+
+	return &r.promotion.StackGroup
+}
+
+func (r *promotionResolver) MaxOnePerLine() bool {
+	return r.promotion.MaxOnePerLine
+}
+
+func optionalDate(t time.Time) *Date {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &Date{Time: t}
+}
@@ -0,0 +1,419 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+
+	"github.com/graph-gophers/graphql-go"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+// Resolver is the root resolver for both Query and Mutation.
+type Resolver struct {
+	shop     domain.ShopService
+	sessions *sessionStore
+}
+
+func (r *Resolver) ActiveOrder(ctx context.Context) (*orderResolver, error) {
+	order, err := r.activeOrder(ctx)
+	if errors.Is(err, errNoActiveOrder) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	return &orderResolver{shop: r.shop, order: order}, nil
+}
+
+func (r *Resolver) ActiveCustomer(ctx context.Context) (*customerResolver, error) {
+	customerID, ok := r.sessions.customerID(sessionIDFromContext(ctx))
+	if !ok {
+		return nil, nil
+	}
+
+	customer, err := r.shop.GetCustomer(customerID)
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	return &customerResolver{customer: customer}, nil
+}
+
+func (r *Resolver) Products(args struct{ Options *productListOptions }) (*productListResolver, error) {
+	products, err := r.shop.ListProducts()
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	sort.Slice(products, func(i, j int) bool {
+		return products[i].ID < products[j].ID
+	})
+
+	if args.Options == nil {
+		return &productListResolver{shop: r.shop, products: products, totalItems: len(products)}, nil
+	}
+
+	if args.Options.Filter != nil {
+		filtered, err := filterProducts(products, args.Options.Filter)
+		if err != nil {
+			return nil, err
+		}
+		products = filtered
+	}
+
+	totalItems := len(products)
+
+	if args.Options.Skip != nil {
+		skip := int(*args.Options.Skip)
+		if skip < 0 {
+			return nil, badUserInput("skip must not be negative")
+		}
+		if skip > len(products) {
+			skip = len(products)
+		}
+		products = products[skip:]
+	}
+
+	if args.Options.Limit != nil {
+		limit := int(*args.Options.Limit)
+		if limit < 0 {
+			return nil, badUserInput("limit must not be negative")
+		}
+		if limit < len(products) {
+			products = products[:limit]
+		}
+	}
+
+	return &productListResolver{shop: r.shop, products: products, totalItems: totalItems}, nil
+}
+
+// Order returns the order only to its owner, the order of somebody else
+// is reported as not found.
+func (r *Resolver) Order(ctx context.Context, args struct{ ID graphql.ID }) (*orderResolver, error) {
+	order, err := r.shop.GetOrder(string(args.ID))
+	if errors.Is(err, domain.ErrCartNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	if !order.IsOwnedBy(r.owner(ctx)) {
+		return nil, nil
+	}
+
+	return &orderResolver{shop: r.shop, order: order}, nil
+}
+
+func (r *Resolver) RegisterCustomerAccount(ctx context.Context, args struct {
+	EmailAddress string
+	Password     string
+}) (*customerResolver, error) {
+	customer, err := r.shop.RegisterCustomer(args.EmailAddress, args.Password)
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	if err := r.logIn(ctx, customer); err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	return &customerResolver{customer: customer}, nil
+}
+
+func (r *Resolver) LogIn(ctx context.Context, args struct {
+	EmailAddress string
+	Password     string
+}) (*customerResolver, error) {
+	customer, err := r.shop.Authenticate(args.EmailAddress, args.Password)
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	if err := r.logIn(ctx, customer); err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	return &customerResolver{customer: customer}, nil
+}
+
+func (r *Resolver) LogOut(ctx context.Context) bool {
+	sessionFromContext(ctx).renew()
+	return true
+}
+
+// logIn logs the customer in on a new session replacing the one in ctx,
+// merging the cart filled anonymously into theirs.
+func (r *Resolver) logIn(ctx context.Context, customer *domain.Customer) error {
+	session := sessionFromContext(ctx)
+
+	_, err := r.shop.MergeSessionCart(session.id, customer.ID)
+	if err != nil && !errors.Is(err, domain.ErrCartNotFound) {
+		return err
+	}
+
+	session.renew()
+	r.sessions.logIn(session.id, customer.ID)
+
+	return nil
+}
+
+func (r *Resolver) AddItemToOrder(ctx context.Context, args struct {
+	ProductID graphql.ID
+	Quantity  int32
+}) (*orderResolver, error) {
+	if args.Quantity <= 0 {
+		return nil, badUserInput("quantity must be greater than zero")
+	}
+
+	order, err := r.shop.GetOrCreateActiveOrder(r.owner(ctx))
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	order, err = r.shop.AddItemToCart(order.ID, string(args.ProductID), int(args.Quantity))
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	return &orderResolver{shop: r.shop, order: order}, nil
+}
+
+func (r *Resolver) RemoveOrderLine(ctx context.Context, args struct{ OrderLineID graphql.ID }) (*orderResolver, error) {
+	order, err := r.activeOrder(ctx)
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	var productID string
+	for _, line := range order.Lines {
+		if line.ID == string(args.OrderLineID) {
+			productID = line.ProductID
+			break
+		}
+	}
+
+	if productID == "" {
+		return nil, toGraphQLError(domain.ErrItemNotFoundInCart)
+	}
+
+	order, err = r.shop.RemoveItemFromCart(order.ID, productID)
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	return &orderResolver{shop: r.shop, order: order}, nil
+}
+
+func (r *Resolver) SetShippingAddress(ctx context.Context, args struct{ Input createAddressInput }) (*orderResolver, error) {
+	order, err := r.activeOrder(ctx)
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	order, err = r.shop.SetShippingAddress(order.ID, args.Input.toAddress())
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	return &orderResolver{shop: r.shop, order: order}, nil
+}
+
+func (r *Resolver) SetBillingAddress(ctx context.Context, args struct{ Input createAddressInput }) (*orderResolver, error) {
+	order, err := r.activeOrder(ctx)
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	order, err = r.shop.SetBillingAddress(order.ID, args.Input.toAddress())
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	return &orderResolver{shop: r.shop, order: order}, nil
+}
+
+func (r *Resolver) EligibleShippingMethods(ctx context.Context) ([]*shippingMethodQuoteResolver, error) {
+	quoteResolvers := []*shippingMethodQuoteResolver{}
+
+	order, err := r.activeOrder(ctx)
+	if errors.Is(err, errNoActiveOrder) {
+		return quoteResolvers, nil
+	}
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	quotes, err := r.shop.GetEligibleShippingMethods(order.ID)
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	for i := range quotes {
+		quoteResolvers = append(quoteResolvers, &shippingMethodQuoteResolver{quote: &quotes[i]})
+	}
+
+	return quoteResolvers, nil
+}
+
+func (r *Resolver) SetOrderShippingMethod(ctx context.Context, args struct{ ShippingMethodID graphql.ID }) (*orderResolver, error) {
+	order, err := r.activeOrder(ctx)
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	order, err = r.shop.SetShippingMethod(order.ID, string(args.ShippingMethodID))
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	return &orderResolver{shop: r.shop, order: order}, nil
+}
+
+func (r *Resolver) ApplyCouponCode(ctx context.Context, args struct{ CouponCode string }) (*orderResolver, error) {
+	order, err := r.activeOrder(ctx)
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	order, err = r.shop.ApplyCoupon(order.ID, args.CouponCode)
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	return &orderResolver{shop: r.shop, order: order}, nil
+}
+
+func (r *Resolver) RemoveCouponCode(ctx context.Context, args struct{ CouponCode string }) (*orderResolver, error) {
+	order, err := r.activeOrder(ctx)
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	order, err = r.shop.RemoveCoupon(order.ID, args.CouponCode)
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	return &orderResolver{shop: r.shop, order: order}, nil
+}
+
+func (r *Resolver) ActivePromotions() []*promotionResolver {
+	promotions := r.shop.GetActivePromotions()
+	promotionResolvers := make([]*promotionResolver, 0, len(promotions))
+	for i := range promotions {
+		promotionResolvers = append(promotionResolvers, &promotionResolver{promotion: &promotions[i]})
+	}
+
+	return promotionResolvers
+}
+
+func (r *Resolver) EligiblePaymentMethods() []*paymentMethodResolver {
+	methods := r.shop.GetPaymentMethods()
+	methodResolvers := make([]*paymentMethodResolver, 0, len(methods))
+	for i := range methods {
+		methodResolvers = append(methodResolvers, &paymentMethodResolver{method: &methods[i]})
+	}
+
+	return methodResolvers
+}
+
+func (r *Resolver) AddPayment(ctx context.Context, args struct{ Input paymentInput }) (*orderResolver, error) {
+	// The order is no longer active once checked out, it must still
+	// belong to whoever pays for it.
+	order, err := r.shop.GetOrder(string(args.Input.OrderID))
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	if !order.IsOwnedBy(r.owner(ctx)) {
+		return nil, toGraphQLError(domain.ErrCartNotFound)
+	}
+
+	order, err = r.shop.AddPayment(order.ID, args.Input.Method, stringValue(args.Input.Token))
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	return &orderResolver{shop: r.shop, order: order}, nil
+}
+
+func (r *Resolver) Checkout(ctx context.Context) (*orderResolver, error) {
+	order, err := r.activeOrder(ctx)
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	if _, err := r.shop.Checkout(order.ID); err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	order, err = r.shop.GetOrder(order.ID)
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+
+	return &orderResolver{shop: r.shop, order: order}, nil
+}
+
+// activeOrder returns the active order of the customer or session in
+// ctx, or errNoActiveOrder if they don't have one yet.
+func (r *Resolver) activeOrder(ctx context.Context) (*domain.Order, error) {
+	order, err := r.shop.GetActiveOrder(r.owner(ctx))
+	if errors.Is(err, domain.ErrCartNotFound) {
+		return nil, errNoActiveOrder
+	}
+
+	return order, err
+}
+
+// owner returns the customer logged in on the session in ctx, or the
+// session itself when nobody is logged in.
+func (r *Resolver) owner(ctx context.Context) domain.OrderOwner {
+	sessionID := sessionIDFromContext(ctx)
+	customerID, _ := r.sessions.customerID(sessionID)
+
+	return domain.OrderOwner{CustomerID: customerID, SessionID: sessionID}
+}
+
+func filterProducts(products []*domain.Product, filter *productFilterParameter) ([]*domain.Product, error) {
+	if filter.CreatedAt != nil || filter.UpdatedAt != nil {
+		return nil, badUserInput("filtering products by createdAt or updatedAt is not supported")
+	}
+
+	if filter.Name == nil {
+		return products, nil
+	}
+
+	var nameRegex *regexp.Regexp
+	if filter.Name.Regex != nil {
+		var err error
+		nameRegex, err = regexp.Compile(*filter.Name.Regex)
+		if err != nil {
+			return nil, badUserInput("invalid name regex: " + err.Error())
+		}
+	}
+
+	var filtered []*domain.Product
+	for _, product := range products {
+		if filter.Name.Eq != nil && product.Name != *filter.Name.Eq {
+			continue
+		}
+
+		if filter.Name.Ne != nil && product.Name == *filter.Name.Ne {
+			continue
+		}
+
+		if nameRegex != nil && !nameRegex.MatchString(product.Name) {
+			continue
+		}
+
+		filtered = append(filtered, product)
+	}
+
+	return filtered, nil
+}
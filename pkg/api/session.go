@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"sync"
+)
+
+const sessionCookieName = "shoppo_session"
+
+type sessionKey struct{}
+
+// sessionStore keeps track of the sessions issued by the server and of the
+// customer logged in on each of them.
+type sessionStore struct {
+	mu sync.RWMutex
+	// customers maps every issued session id to the customer logged in on
+	// it, or to "" when nobody is.
+	customers map[string]string
+}
+
+func newSessionStore() *sessionStore {
+	return &sessionStore{
+		customers: make(map[string]string),
+	}
+}
+
+// issue returns a new unguessable session id nobody is logged in on.
+func (s *sessionStore) issue() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	sessionID := base64.RawURLEncoding.EncodeToString(b)
+
+	s.mu.Lock()
+	s.customers[sessionID] = ""
+	s.mu.Unlock()
+
+	return sessionID
+}
+
+func (s *sessionStore) issued(sessionID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.customers[sessionID]
+	return ok
+}
+
+func (s *sessionStore) customerID(sessionID string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	customerID := s.customers[sessionID]
+	return customerID, customerID != ""
+}
+
+func (s *sessionStore) logIn(sessionID string, customerID string) {
+	s.mu.Lock()
+	s.customers[sessionID] = customerID
+	s.mu.Unlock()
+}
+
+// revoke forgets sessionID, so that its cookie isn't accepted anymore.
+func (s *sessionStore) revoke(sessionID string) {
+	s.mu.Lock()
+	delete(s.customers, sessionID)
+	s.mu.Unlock()
+}
+
+// session is the session of a single request. Resolvers renew it when the
+// customer logs in or out, so that a session id known before can't be used
+// to act on their behalf.
+type session struct {
+	id    string
+	w     http.ResponseWriter
+	store *sessionStore
+}
+
+// renew replaces the session id with a new one, revoking the previous one
+// and sending the new one to the client.
+func (s *session) renew() {
+	s.store.revoke(s.id)
+	s.id = s.store.issue()
+	// The cookie of the session issued for this very request, if any, is
+	// superseded by the new one.
+	s.w.Header().Del("Set-Cookie")
+	setSessionCookie(s.w, s.id)
+}
+
+// withSession makes sure every request has a session id issued by store,
+// issuing a new session cookie when the client didn't send one or sent
+// one the server doesn't know about.
+func withSession(store *sessionStore, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var sessionID string
+		if cookie, err := r.Cookie(sessionCookieName); err == nil && store.issued(cookie.Value) {
+			sessionID = cookie.Value
+		} else {
+			sessionID = store.issue()
+			setSessionCookie(w, sessionID)
+		}
+
+		ctx := context.WithValue(r.Context(), sessionKey{}, &session{id: sessionID, w: w, store: store})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func setSessionCookie(w http.ResponseWriter, sessionID string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    sessionID,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func sessionFromContext(ctx context.Context) *session {
+	s, _ := ctx.Value(sessionKey{}).(*session)
+	return s
+}
+
+func sessionIDFromContext(ctx context.Context) string {
+	if s := sessionFromContext(ctx); s != nil {
+		return s.id
+	}
+	return ""
+}
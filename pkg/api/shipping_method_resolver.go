@@ -0,0 +1,39 @@
+package api
+
+import (
+	"github.com/graph-gophers/graphql-go"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+type shippingMethodResolver struct {
+	method *domain.ShippingMethod
+}
+
+func (r *shippingMethodResolver) ID() graphql.ID {
+	return graphql.ID(r.method.ID)
+}
+
+func (r *shippingMethodResolver) Code() string {
+	return r.method.Code
+}
+
+func (r *shippingMethodResolver) Name() string {
+	return r.method.Name
+}
+
+func (r *shippingMethodResolver) Description() string {
+	return r.method.Description
+}
+
+type shippingMethodQuoteResolver struct {
+	quote *domain.ShippingQuote
+}
+
+func (r *shippingMethodQuoteResolver) ShippingMethod() *shippingMethodResolver {
+	return &shippingMethodResolver{method: &r.quote.Method}
+}
+
+func (r *shippingMethodQuoteResolver) Price() float64 {
+	return r.quote.Cost.Float64()
+}
@@ -0,0 +1,56 @@
+package domain
+
+import "strings"
+
+type Address struct {
+	FullName    string `json:"full_name,omitempty"`
+	Company     string `json:"company,omitempty"`
+	StreetLine  string `json:"street_line"`
+	City        string `json:"city"`
+	Province    string `json:"province"`
+	PostalCode  string `json:"postal_code,omitempty"`
+	Country     string `json:"country"`
+	PhoneNumber string `json:"phone_number,omitempty"`
+}
+
+// Normalize returns the address with the surrounding spaces of every
+// field removed.
+func (address Address) Normalize() Address {
+	return Address{
+		FullName:    strings.TrimSpace(address.FullName),
+		Company:     strings.TrimSpace(address.Company),
+		StreetLine:  strings.TrimSpace(address.StreetLine),
+		City:        strings.TrimSpace(address.City),
+		Province:    strings.TrimSpace(address.Province),
+		PostalCode:  strings.TrimSpace(address.PostalCode),
+		Country:     strings.TrimSpace(address.Country),
+		PhoneNumber: strings.TrimSpace(address.PhoneNumber),
+	}
+}
+
+// Validate returns a *ValidationError wrapping ErrInvalidAddress that
+// lists every required field left empty.
+func (address Address) Validate() error {
+	required := []struct {
+		field string
+		value string
+	}{
+		{"streetLine", address.StreetLine},
+		{"city", address.City},
+		{"province", address.Province},
+		{"country", address.Country},
+	}
+
+	var fields []FieldError
+	for _, r := range required {
+		if strings.TrimSpace(r.value) == "" {
+			fields = append(fields, FieldError{Field: r.field, Message: "is required"})
+		}
+	}
+
+	if len(fields) > 0 {
+		return &ValidationError{Err: ErrInvalidAddress, Fields: fields}
+	}
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+type Customer struct {
+	ID           string
+	EmailAddress string
+	// PasswordHash is the bcrypt hash of the password of the customer, a
+	// customer without one can't log in.
+	PasswordHash string
+}
+
+// NormalizeEmailAddress validates the email address and returns it in
+// lower case, so the same customer is found whatever the case they type.
+func NormalizeEmailAddress(emailAddress string) (string, error) {
+	address, err := mail.ParseAddress(strings.TrimSpace(emailAddress))
+	if err != nil || address.Name != "" {
+		return "", fmt.Errorf("%w: %q", ErrInvalidEmailAddress, emailAddress)
+	}
+
+	return strings.ToLower(address.Address), nil
+}
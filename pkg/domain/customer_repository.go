@@ -0,0 +1,14 @@
+package domain
+
+// CustomerRepository stores customers. Implementations must be safe for
+// concurrent use.
+type CustomerRepository interface {
+	// FindByID returns ErrCustomerNotFound if there is no customer with the given id.
+	FindByID(customerID string) (*Customer, error)
+	// FindByEmailAddress returns ErrCustomerNotFound if there is no
+	// customer with the given normalized email address.
+	FindByEmailAddress(emailAddress string) (*Customer, error)
+	// Save creates or replaces the customer, it returns
+	// ErrCustomerAlreadyExists if another customer has the same email address.
+	Save(customer *Customer) error
+}
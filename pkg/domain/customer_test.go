@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeEmailAddress(t *testing.T) {
+	tests := []struct {
+		name         string
+		emailAddress string
+		want         string
+		wantErr      error
+	}{
+		{
+			name:         "should lower the case of the address",
+			emailAddress: " Jane.Doe@Example.com ",
+			want:         "jane.doe@example.com",
+		},
+		{
+			name:         "should reject address without domain",
+			emailAddress: "jane.doe",
+			wantErr:      ErrInvalidEmailAddress,
+		},
+		{
+			name:         "should reject address with display name",
+			emailAddress: "Jane <jane.doe@example.com>",
+			wantErr:      ErrInvalidEmailAddress,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := NormalizeEmailAddress(test.emailAddress)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
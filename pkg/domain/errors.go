@@ -6,7 +6,38 @@ var (
 	ErrCartNotFound                      = errors.New("cart not found")
 	ErrProductNotFound                   = errors.New("product not found")
 	ErrNotEnoughStock                    = errors.New("not enough stock")
+	ErrInvalidQuantity                   = errors.New("quantity must be greater than zero")
+	ErrVariantRequired                   = errors.New("product has variants, one of them must be chosen")
 	ErrItemNotFoundInCart                = errors.New("item not found in cart")
 	ErrSomeProductInCartNotFound         = errors.New("some product in cart are not found")
 	ErrSomeProductInCartNotEnoughInStock = errors.New("some product in cart are not enough in stock")
+	ErrOrderNotModifiable                = errors.New("order can no longer be modified")
+	ErrOrderAlreadyCheckedOut            = errors.New("order already checked out")
+	ErrOrderCancelled                    = errors.New("order is cancelled")
+	ErrInvalidOrderStatusTransition      = errors.New("invalid order status transition")
+	ErrCustomerNotFound                  = errors.New("customer not found")
+	ErrCustomerAlreadyExists             = errors.New("customer already exists")
+	ErrInvalidEmailAddress               = errors.New("invalid email address")
+	ErrInvalidPassword                   = errors.New("invalid password")
+	ErrInvalidCredentials                = errors.New("invalid email address or password")
+	ErrInvalidAddress                    = errors.New("invalid address")
+	ErrShippingAddressRequired           = errors.New("shipping address is required to ship physical products")
+	ErrShippingMethodNotFound            = errors.New("shipping method not found")
+	ErrShippingMethodNotEligible         = errors.New("shipping method is not eligible for the order")
+	ErrShippingMethodRequired            = errors.New("shipping method is required to ship physical products")
+	ErrOrderNotAwaitingPayment           = errors.New("order is not awaiting payment")
+	ErrPaymentMethodNotFound             = errors.New("payment method not found")
+	ErrPaymentDeclined                   = errors.New("payment declined")
+	ErrPaymentFailed                     = errors.New("payment failed")
+	ErrPaymentTimeout                    = errors.New("payment provider timed out")
+	ErrPaymentPending                    = errors.New("payment is pending, the payment provider didn't answer")
+	ErrOrderNotRefundable                = errors.New("order can only be refunded once paid")
+	ErrInvalidRefundQuantity             = errors.New("invalid refund quantity")
+	ErrRefundFailed                      = errors.New("refund failed")
+	ErrInvalidPromotion                  = errors.New("invalid promotion")
+	ErrCouponCodeNotFound                = errors.New("coupon code not found")
+	ErrCouponCodeExpired                 = errors.New("coupon code has expired")
+	ErrCouponCodeNotEligible             = errors.New("coupon code is not eligible for the order")
+	ErrCouponCodeUsageLimitReached       = errors.New("coupon code usage limit reached")
+	ErrCouponCodeNotApplied              = errors.New("coupon code is not applied to the order")
 )
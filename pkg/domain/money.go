@@ -0,0 +1,266 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnknownCurrency  = errors.New("unknown currency")
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+)
+
+// RoundingMode tells how an amount that falls between two minor units
+// is rounded.
+type RoundingMode int
+
+const (
+	// RoundHalfUp rounds half away from zero, 0.125 becomes 0.13.
+	RoundHalfUp RoundingMode = iota
+	// RoundHalfEven rounds half to the nearest even minor unit, 0.125 becomes 0.12.
+	RoundHalfEven
+	// RoundDown truncates towards zero, 0.129 becomes 0.12.
+	RoundDown
+)
+
+// Currency describes how amounts of a currency are stored and rounded.
+type Currency struct {
+	Code string
+	// MinorUnits is the number of digits after the decimal point,
+	// e.g. 2 for USD, the amount of a Money is expressed in this unit.
+	MinorUnits int
+	Rounding   RoundingMode
+}
+
+var currencies = map[string]Currency{
+	"USD": {Code: "USD", MinorUnits: 2, Rounding: RoundHalfUp},
+	"EUR": {Code: "EUR", MinorUnits: 2, Rounding: RoundHalfEven},
+	"SGD": {Code: "SGD", MinorUnits: 2, Rounding: RoundHalfUp},
+	"IDR": {Code: "IDR", MinorUnits: 0, Rounding: RoundHalfUp},
+	"JPY": {Code: "JPY", MinorUnits: 0, Rounding: RoundHalfUp},
+}
+
+// RegisterCurrency adds or replaces a currency, it is not safe to call
+// concurrently with Money operations and is meant to be called on startup.
+func RegisterCurrency(currency Currency) {
+	currencies[currency.Code] = currency
+}
+
+// LookupCurrency returns the registered currency with the given code.
+func LookupCurrency(code string) (Currency, error) {
+	currency, ok := currencies[code]
+	if !ok {
+		return Currency{}, fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+	}
+
+	return currency, nil
+}
+
+// Money is an exact amount of money expressed in the minor unit of its
+// currency, e.g. Money{Amount: 4999, Currency: "USD"} is 49.99 USD.
+//
+// The zero value is zero money without currency, it can be added to or
+// subtracted from money of any currency.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// NewMoney returns money of amount minor units of currency.
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// ParseMoney parses a decimal string such as "49.99" into money of the
+// given currency. It refuses amounts that have more decimal digits than
+// the currency allows instead of rounding them.
+func ParseMoney(s string, currency string) (Money, error) {
+	cur, err := LookupCurrency(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	r.Mul(r, new(big.Rat).SetInt(pow10(cur.MinorUnits)))
+	if !r.IsInt() {
+		return Money{}, fmt.Errorf("%w: %q has more than %d decimal digits", ErrInvalidAmount, s, cur.MinorUnits)
+	}
+
+	if !r.Num().IsInt64() {
+		return Money{}, fmt.Errorf("%w: %q is out of range", ErrInvalidAmount, s)
+	}
+
+	return Money{Amount: r.Num().Int64(), Currency: cur.Code}, nil
+}
+
+// MustParseMoney is like ParseMoney but panics if s cannot be parsed.
+// It is meant for literals in code and tests.
+func MustParseMoney(s string, currency string) Money {
+	m, err := ParseMoney(s, currency)
+	if err != nil {
+		panic(err)
+	}
+
+	return m
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Add returns m + o, it panics if both have a different currency.
+func (m Money) Add(o Money) Money {
+	currency := m.mustMatch(o)
+	return Money{Amount: m.Amount + o.Amount, Currency: currency}
+}
+
+// Sub returns m - o, it panics if both have a different currency.
+func (m Money) Sub(o Money) Money {
+	currency := m.mustMatch(o)
+	return Money{Amount: m.Amount - o.Amount, Currency: currency}
+}
+
+// Mul returns m multiplied by n.
+func (m Money) Mul(n int) Money {
+	return Money{Amount: m.Amount * int64(n), Currency: m.Currency}
+}
+
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// Cmp compares m and o and returns -1, 0 or +1, it panics if both have
+// a different currency.
+func (m Money) Cmp(o Money) int {
+	m.mustMatch(o)
+
+	switch {
+	case m.Amount < o.Amount:
+		return -1
+	case m.Amount > o.Amount:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Percent returns percent % of m, rounded to the minor unit according
+// to the rounding mode of the currency.
+func (m Money) Percent(percent float64) Money {
+	// Going through the shortest decimal representation keeps percentages
+	// such as 33.3 exact instead of using their binary approximation.
+	p, _ := new(big.Rat).SetString(strconv.FormatFloat(percent, 'f', -1, 64))
+
+	r := new(big.Rat).SetInt64(m.Amount)
+	r.Mul(r, p)
+	r.Quo(r, big.NewRat(100, 1))
+
+	return Money{Amount: roundRat(r, m.currency().Rounding), Currency: m.Currency}
+}
+
+// Float64 returns m in major units, e.g. 49.99 for 4999 cents. It is
+// lossy and must only be used for presentation.
+func (m Money) Float64() float64 {
+	f, _ := new(big.Rat).SetFrac(big.NewInt(m.Amount), pow10(m.currency().MinorUnits)).Float64()
+	return f
+}
+
+// String formats m as a decimal followed by its currency code, e.g. "49.99 USD".
+func (m Money) String() string {
+	amount := m.Decimal()
+	if m.Currency == "" {
+		return amount
+	}
+
+	return amount + " " + m.Currency
+}
+
+// Decimal formats m as a decimal without its currency code, e.g. "49.99",
+// which ParseMoney parses back.
+func (m Money) Decimal() string {
+	cur := m.currency()
+	return new(big.Rat).SetFrac(big.NewInt(m.Amount), pow10(cur.MinorUnits)).FloatString(cur.MinorUnits)
+}
+
+func (m Money) currency() Currency {
+	cur, ok := currencies[m.Currency]
+	if !ok {
+		return Currency{Code: m.Currency, MinorUnits: 2, Rounding: RoundHalfUp}
+	}
+
+	return cur
+}
+
+// MatchCurrency returns ErrCurrencyMismatch if m and o have a different
+// currency, in which case Add, Sub and Cmp would panic.
+func (m Money) MatchCurrency(o Money) error {
+	_, err := m.matchCurrency(o)
+	return err
+}
+
+// mustMatch returns the currency of the result of an operation between
+// m and o. Zero money without currency takes the currency of the other side.
+func (m Money) mustMatch(o Money) string {
+	currency, err := m.matchCurrency(o)
+	if err != nil {
+		panic(err)
+	}
+
+	return currency
+}
+
+func (m Money) matchCurrency(o Money) (string, error) {
+	switch {
+	case m.Currency == o.Currency:
+		return m.Currency, nil
+	case m.Currency == "" && m.Amount == 0:
+		return o.Currency, nil
+	case o.Currency == "" && o.Amount == 0:
+		return m.Currency, nil
+	}
+
+	return "", fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+}
+
+func roundRat(r *big.Rat, mode RoundingMode) int64 {
+	quo, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if rem.Sign() == 0 {
+		return quo.Int64()
+	}
+
+	if mode == RoundDown {
+		return quo.Int64()
+	}
+
+	// Compare the remainder with half of the denominator to find out
+	// whether r is below, exactly at or above the half way point.
+	twiceRem := new(big.Int).Abs(rem)
+	twiceRem.Mul(twiceRem, big.NewInt(2))
+	half := twiceRem.Cmp(r.Denom())
+
+	awayFromZero := half > 0 ||
+		(half == 0 && mode == RoundHalfUp) ||
+		(half == 0 && mode == RoundHalfEven && quo.Bit(0) == 1)
+
+	if awayFromZero {
+		quo.Add(quo, big.NewInt(int64(r.Sign())))
+	}
+
+	return quo.Int64()
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
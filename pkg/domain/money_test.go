@@ -0,0 +1,142 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		currency string
+		want     Money
+		wantErr  error
+	}{
+		{
+			name:     "should parse amount into minor units",
+			input:    "49.99",
+			currency: "USD",
+			want:     Money{Amount: 4999, Currency: "USD"},
+		},
+		{
+			name:     "should parse amount without decimal point",
+			input:    "30",
+			currency: "USD",
+			want:     Money{Amount: 3000, Currency: "USD"},
+		},
+		{
+			name:     "should parse negative amount",
+			input:    "-0.5",
+			currency: "USD",
+			want:     Money{Amount: -50, Currency: "USD"},
+		},
+		{
+			name:     "should parse currency without minor units",
+			input:    "15000",
+			currency: "IDR",
+			want:     Money{Amount: 15000, Currency: "IDR"},
+		},
+		{
+			name:     "should return error when amount has more decimals than the currency allows",
+			input:    "49.999",
+			currency: "USD",
+			wantErr:  ErrInvalidAmount,
+		},
+		{
+			name:     "should return error when amount is not a number",
+			input:    "abc",
+			currency: "USD",
+			wantErr:  ErrInvalidAmount,
+		},
+		{
+			name:     "should return error when currency is unknown",
+			input:    "10",
+			currency: "XYZ",
+			wantErr:  ErrUnknownCurrency,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseMoney(test.input, test.currency)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.want, got)
+			}
+		})
+	}
+}
+
+func TestMoney_Percent(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   Money
+		percent float64
+		want    Money
+	}{
+		{
+			name:    "should return exact percentage",
+			input:   Money{Amount: 32850, Currency: "USD"},
+			percent: 10,
+			want:    Money{Amount: 3285, Currency: "USD"},
+		},
+		{
+			name:    "should round half up for USD",
+			input:   Money{Amount: 14997, Currency: "USD"},
+			percent: 15,
+			want:    Money{Amount: 2250, Currency: "USD"},
+		},
+		{
+			name:    "should round half away from zero for negative amount",
+			input:   Money{Amount: -25, Currency: "USD"},
+			percent: 50,
+			want:    Money{Amount: -13, Currency: "USD"},
+		},
+		{
+			name:    "should round half to even for EUR",
+			input:   Money{Amount: 25, Currency: "EUR"},
+			percent: 50,
+			want:    Money{Amount: 12, Currency: "EUR"},
+		},
+		{
+			name:    "should use decimal value of percent",
+			input:   Money{Amount: 1000, Currency: "USD"},
+			percent: 33.3,
+			want:    Money{Amount: 333, Currency: "USD"},
+		},
+		{
+			name:    "should round to whole units for IDR",
+			input:   Money{Amount: 15999, Currency: "IDR"},
+			percent: 10,
+			want:    Money{Amount: 1600, Currency: "IDR"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, test.input.Percent(test.percent))
+		})
+	}
+}
+
+func TestMoney_Add(t *testing.T) {
+	var total Money
+	total = total.Add(MustParseMoney("0.10", "USD"))
+	total = total.Add(MustParseMoney("0.20", "USD"))
+	assert.Equal(t, MustParseMoney("0.30", "USD"), total)
+
+	assert.Panics(t, func() {
+		total.Add(MustParseMoney("1", "EUR"))
+	})
+}
+
+func TestMoney_String(t *testing.T) {
+	assert.Equal(t, "49.99 USD", MustParseMoney("49.99", "USD").String())
+	assert.Equal(t, "-0.05 USD", MustParseMoney("-0.05", "USD").String())
+	assert.Equal(t, "15000 IDR", MustParseMoney("15000", "IDR").String())
+	assert.Equal(t, "0.00", Money{}.String())
+}
@@ -1,6 +1,121 @@
 package domain
 
+import (
+	"fmt"
+	"time"
+)
+
 type Order struct {
-	ID    string
-	Lines []*OrderLine
+	ID     string
+	Status OrderStatus
+	// CustomerID is empty until a customer claims the order, the order
+	// then belongs to the anonymous session with SessionID.
+	CustomerID string
+	SessionID  string
+	Lines      []*OrderLine
+	// ShippingAddress is required to check out physical products.
+	ShippingAddress *Address
+	BillingAddress  *Address
+	// ShippingMethodID is the shipping method chosen by the customer.
+	ShippingMethodID string
+	// CouponCodes are the normalized coupon codes applied to the order.
+	CouponCodes []string
+	CreatedAt   time.Time
+	// PlacedAt is the time the order was checked out, zero for a cart.
+	PlacedAt time.Time
+	// Pricing is the breakdown computed at checkout, nil for a cart.
+	Pricing *Pricing
+	// Payments lists every attempt to pay for the order, in order.
+	Payments []*Payment
+	Refunds  []*Refund
+}
+
+// OrderOwner identifies whose cart an order is: a customer, or an
+// anonymous session when CustomerID is empty.
+type OrderOwner struct {
+	CustomerID string
+	SessionID  string
+}
+
+// Owner returns the owner of the order.
+func (order *Order) Owner() OrderOwner {
+	return OrderOwner{CustomerID: order.CustomerID, SessionID: order.SessionID}
+}
+
+// IsOwnedBy tells whether the order belongs to the owner, an order of a
+// customer doesn't belong to the session that created it anymore.
+func (order *Order) IsOwnedBy(owner OrderOwner) bool {
+	if owner.CustomerID != "" {
+		return order.CustomerID == owner.CustomerID
+	}
+
+	return order.CustomerID == "" && owner.SessionID != "" && order.SessionID == owner.SessionID
+}
+
+// HasCouponCode tells whether the normalized coupon code is applied to
+// the order.
+func (order *Order) HasCouponCode(code string) bool {
+	for _, applied := range order.CouponCodes {
+		if applied == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Currency returns the currency the lines of the order are priced in,
+// empty for an order without lines. An order is priced in a single
+// currency, it returns ErrCurrencyMismatch if its lines are not.
+func (order *Order) Currency() (string, error) {
+	currency := ""
+	for _, line := range order.Lines {
+		switch {
+		case currency == "":
+			currency = line.UnitPrice.Currency
+		case line.UnitPrice.Currency != currency:
+			return "", fmt.Errorf("%w: order %s has lines in %s and %s", ErrCurrencyMismatch, order.ID, currency, line.UnitPrice.Currency)
+		}
+	}
+
+	return currency, nil
+}
+
+// Clone returns a deep copy of the order.
+func (order *Order) Clone() *Order {
+	clone := *order
+	clone.CouponCodes = append([]string(nil), order.CouponCodes...)
+	clone.Lines = nil
+	for _, line := range order.Lines {
+		clone.Lines = append(clone.Lines, line.Clone())
+	}
+
+	if order.ShippingAddress != nil {
+		address := *order.ShippingAddress
+		clone.ShippingAddress = &address
+	}
+
+	if order.BillingAddress != nil {
+		address := *order.BillingAddress
+		clone.BillingAddress = &address
+	}
+
+	if order.Pricing != nil {
+		clone.Pricing = order.Pricing.Clone()
+	}
+
+	clone.Payments = nil
+	for _, payment := range order.Payments {
+		p := *payment
+		clone.Payments = append(clone.Payments, &p)
+	}
+
+	clone.Refunds = nil
+	for _, refund := range order.Refunds {
+		r := *refund
+		r.Lines = append([]RefundLine(nil), refund.Lines...)
+		clone.Refunds = append(clone.Refunds, &r)
+	}
+
+	return &clone
 }
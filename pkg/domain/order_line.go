@@ -4,5 +4,45 @@ type OrderLine struct {
 	ID        string
 	ProductID string
 	Quantity  int
-	UnitPrice float64
+	UnitPrice Money
+	// ParentProductID is the product the line is a variant of, if any.
+	ParentProductID string
+
+	// SKU, Categories, Tags and Collections are those of the product when
+	// it was added, like its unit price, so promotions keep targeting the
+	// line the same way once the order is checked out.
+	SKU         string
+	Categories  []string
+	Tags        []string
+	Collections []string
+}
+
+// NewOrderLine returns a line of product priced at its current price.
+func NewOrderLine(id string, product *Product, quantity int) *OrderLine {
+	return &OrderLine{
+		ID:              id,
+		ProductID:       product.ID,
+		Quantity:        quantity,
+		UnitPrice:       product.UnitPrice,
+		ParentProductID: product.ParentID,
+		SKU:             product.SKU,
+		Categories:      cloneStrings(product.Categories),
+		Tags:            cloneStrings(product.Tags),
+		Collections:     cloneStrings(product.Collections),
+	}
+}
+
+// Subtotal is the price of the line before any discount.
+func (line *OrderLine) Subtotal() Money {
+	return line.UnitPrice.Mul(line.Quantity)
+}
+
+// Clone returns a deep copy of the line.
+func (line *OrderLine) Clone() *OrderLine {
+	clone := *line
+	clone.Categories = cloneStrings(line.Categories)
+	clone.Tags = cloneStrings(line.Tags)
+	clone.Collections = cloneStrings(line.Collections)
+
+	return &clone
 }
@@ -0,0 +1,18 @@
+package domain
+
+// OrderRepository stores orders. Implementations must be safe for
+// concurrent use and must return copies so callers can't modify stored
+// orders by accident.
+type OrderRepository interface {
+	// FindByID returns ErrCartNotFound if there is no order with the given id.
+	FindByID(orderID string) (*Order, error)
+	// FindActive returns the most recently created order of the owner that
+	// is still a cart, or ErrCartNotFound if there is none.
+	FindActive(owner OrderOwner) (*Order, error)
+	// CountCouponCodeUses returns how many placed orders use the
+	// normalized coupon code, only counting the orders of the customer
+	// when customerID is not empty.
+	CountCouponCodeUses(code string, customerID string) (int, error)
+	// Save creates or replaces the order.
+	Save(order *Order) error
+}
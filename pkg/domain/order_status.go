@@ -0,0 +1,69 @@
+package domain
+
+import "fmt"
+
+type OrderStatus string
+
+const (
+	OrderStatusCreated          OrderStatus = "Created"
+	OrderStatusArrangingPayment OrderStatus = "ArrangingPayment"
+	OrderStatusPaid             OrderStatus = "Paid"
+	OrderStatusShipped          OrderStatus = "Shipped"
+	OrderStatusDelivered        OrderStatus = "Delivered"
+	OrderStatusCancelled        OrderStatus = "Cancelled"
+)
+
+// orderStatusTransitions lists the statuses an order can move to from
+// each status. An order is a cart while Created, checking it out moves it
+// to ArrangingPayment.
+var orderStatusTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusCreated:          {OrderStatusArrangingPayment, OrderStatusCancelled},
+	OrderStatusArrangingPayment: {OrderStatusPaid, OrderStatusCancelled},
+	OrderStatusPaid:             {OrderStatusShipped, OrderStatusCancelled},
+	OrderStatusShipped:          {OrderStatusDelivered},
+	OrderStatusDelivered:        {},
+	OrderStatusCancelled:        {},
+}
+
+func (status OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderStatusTransitions[status] {
+		if allowed == next {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TransitionTo moves the order to next, it returns
+// ErrInvalidOrderStatusTransition if the transition is not allowed.
+func (order *Order) TransitionTo(next OrderStatus) error {
+	if !order.Status.CanTransitionTo(next) {
+		return fmt.Errorf("%w: from %s to %s", ErrInvalidOrderStatusTransition, order.Status, next)
+	}
+
+	order.Status = next
+	return nil
+}
+
+// IsModifiable tells whether items can still be added to or removed from
+// the order.
+func (order *Order) IsModifiable() bool {
+	return order.Status == OrderStatusCreated
+}
+
+// IsPaid tells whether the order was paid and not cancelled since.
+func (order *Order) IsPaid() bool {
+	switch order.Status {
+	case OrderStatusPaid, OrderStatusShipped, OrderStatusDelivered:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsPlaced tells whether the order was checked out and not cancelled
+// since.
+func (order *Order) IsPlaced() bool {
+	return order.Status != OrderStatusCreated && order.Status != OrderStatusCancelled
+}
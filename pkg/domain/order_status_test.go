@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrder_TransitionTo(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    OrderStatus
+		to      OrderStatus
+		wantErr error
+	}{
+		{
+			name: "should allow checking out a cart",
+			from: OrderStatusCreated,
+			to:   OrderStatusArrangingPayment,
+		},
+		{
+			name: "should allow paying an order arranging payment",
+			from: OrderStatusArrangingPayment,
+			to:   OrderStatusPaid,
+		},
+		{
+			name: "should allow shipping a paid order",
+			from: OrderStatusPaid,
+			to:   OrderStatusShipped,
+		},
+		{
+			name: "should allow delivering a shipped order",
+			from: OrderStatusShipped,
+			to:   OrderStatusDelivered,
+		},
+		{
+			name: "should allow cancelling a paid order",
+			from: OrderStatusPaid,
+			to:   OrderStatusCancelled,
+		},
+		{
+			name:    "should not allow shipping an unpaid order",
+			from:    OrderStatusArrangingPayment,
+			to:      OrderStatusShipped,
+			wantErr: ErrInvalidOrderStatusTransition,
+		},
+		{
+			name:    "should not allow checking out twice",
+			from:    OrderStatusArrangingPayment,
+			to:      OrderStatusArrangingPayment,
+			wantErr: ErrInvalidOrderStatusTransition,
+		},
+		{
+			name:    "should not allow cancelling a shipped order",
+			from:    OrderStatusShipped,
+			to:      OrderStatusCancelled,
+			wantErr: ErrInvalidOrderStatusTransition,
+		},
+		{
+			name:    "should not allow leaving cancelled",
+			from:    OrderStatusCancelled,
+			to:      OrderStatusCreated,
+			wantErr: ErrInvalidOrderStatusTransition,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			order := &Order{Status: test.from}
+
+			err := order.TransitionTo(test.to)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+				assert.Equal(t, test.from, order.Status)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.to, order.Status)
+			}
+		})
+	}
+}
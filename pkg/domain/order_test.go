@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrder_IsOwnedBy(t *testing.T) {
+	tests := []struct {
+		name  string
+		order Order
+		owner OrderOwner
+		want  bool
+	}{
+		{
+			name:  "should belong to the session that created it",
+			order: Order{SessionID: "s1"},
+			owner: OrderOwner{SessionID: "s1"},
+			want:  true,
+		},
+		{
+			name:  "should not belong to another session",
+			order: Order{SessionID: "s1"},
+			owner: OrderOwner{SessionID: "s2"},
+		},
+		{
+			name:  "should not belong to the session once claimed by a customer",
+			order: Order{SessionID: "s1", CustomerID: "c1"},
+			owner: OrderOwner{SessionID: "s1"},
+		},
+		{
+			name:  "should belong to the customer whatever the session",
+			order: Order{SessionID: "s1", CustomerID: "c1"},
+			owner: OrderOwner{SessionID: "s2", CustomerID: "c1"},
+			want:  true,
+		},
+		{
+			name:  "should not belong to nobody",
+			order: Order{},
+			owner: OrderOwner{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, test.order.IsOwnedBy(test.owner))
+		})
+	}
+}
+
+func TestOrder_Currency(t *testing.T) {
+	order := &Order{ID: "o1"}
+	currency, err := order.Currency()
+	assert.NoError(t, err)
+	assert.Equal(t, "", currency)
+
+	order.Lines = []*OrderLine{
+		{ID: "l1", UnitPrice: MustParseMoney("49.99", "USD")},
+		{ID: "l2", UnitPrice: MustParseMoney("5399.99", "USD")},
+	}
+	currency, err = order.Currency()
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", currency)
+
+	order.Lines = append(order.Lines, &OrderLine{ID: "l3", UnitPrice: MustParseMoney("450000", "IDR")})
+	_, err = order.Currency()
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+}
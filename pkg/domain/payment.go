@@ -0,0 +1,88 @@
+package domain
+
+import "time"
+
+type PaymentState string
+
+const (
+	// PaymentStatePending is a payment saved before it is sent to the
+	// payment provider. It stays Pending when the provider didn't answer,
+	// until it is sent again to find out what became of it.
+	PaymentStatePending PaymentState = "Pending"
+	// PaymentStateAuthorized is a payment whose amount is held but not
+	// collected yet.
+	PaymentStateAuthorized PaymentState = "Authorized"
+	PaymentStateSettled    PaymentState = "Settled"
+	PaymentStateDeclined   PaymentState = "Declined"
+	// PaymentStateCancelled is an authorized payment that was voided.
+	PaymentStateCancelled PaymentState = "Cancelled"
+	// PaymentStateError is a payment that failed for another reason than
+	// being declined, e.g. the provider refused the token.
+	PaymentStateError PaymentState = "Error"
+)
+
+// Payment is an attempt to pay for an order, every attempt is kept
+// whether or not it succeeded.
+type Payment struct {
+	ID     string
+	Method string
+	Amount Money
+	State  PaymentState
+	// TransactionID references the payment at the payment provider, it is
+	// empty when the provider didn't authorize the payment.
+	TransactionID string
+	// ErrorMessage tells why the payment was declined or failed.
+	ErrorMessage string
+	CreatedAt    time.Time
+}
+
+// PaymentMethod is a way customers can pay, processed by Provider.
+type PaymentMethod struct {
+	Code     string
+	Name     string
+	Provider PaymentProvider
+}
+
+// PaymentRequest asks a payment provider to authorize Amount for an
+// order. Token identifies the payment instrument at the provider, e.g. a
+// tokenized card.
+type PaymentRequest struct {
+	OrderID string
+	Amount  Money
+	Token   string
+	// IdempotencyKey makes sending the same request again return the
+	// transaction of the first one instead of holding Amount twice.
+	IdempotencyKey string
+}
+
+// PaymentProvider processes payments with a payment gateway.
+type PaymentProvider interface {
+	// Authorize holds the amount of the request and returns the id of
+	// the transaction at the provider. It returns ErrPaymentDeclined when
+	// the provider refuses the payment and ErrPaymentTimeout when it
+	// didn't answer, the transaction may then exist anyway.
+	Authorize(request PaymentRequest) (string, error)
+	// Capture collects amount of an authorized transaction. Capturing
+	// again with the same idempotency key doesn't collect amount twice.
+	// It returns ErrPaymentTimeout when the provider didn't answer.
+	Capture(transactionID string, amount Money, idempotencyKey string) error
+	// Void releases an authorized transaction that wasn't captured.
+	Void(transactionID string) error
+	// Refund gives back amount of a captured transaction and returns the
+	// id of the refund at the provider. Refunding again with the same
+	// idempotency key returns that refund instead of giving amount back
+	// twice.
+	Refund(transactionID string, amount Money, idempotencyKey string) (string, error)
+}
+
+// AmountPaid returns the sum of the settled payments of the order.
+func (order *Order) AmountPaid() Money {
+	var paid Money
+	for _, payment := range order.Payments {
+		if payment.State == PaymentStateSettled {
+			paid = paid.Add(payment.Amount)
+		}
+	}
+
+	return paid
+}
@@ -0,0 +1,56 @@
+package domain
+
+// Pricing is the priced breakdown of an order: what each line costs,
+// which promotions applied and how much they saved.
+type Pricing struct {
+	Lines     []LinePricing      `json:"lines"`
+	Subtotal  Money              `json:"subtotal"`
+	Discounts []AppliedPromotion `json:"discounts"`
+	// DiscountTotal is the sum of every discount, zero or negative.
+	DiscountTotal Money `json:"discount_total"`
+	// ShippingMethodID is empty when the order is not shipped.
+	ShippingMethodID string `json:"shipping_method_id,omitempty"`
+	Shipping         Money  `json:"shipping"`
+	Total            Money  `json:"total"`
+}
+
+type LinePricing struct {
+	OrderLineID string `json:"order_line_id"`
+	ProductID   string `json:"product_id"`
+	Quantity    int    `json:"quantity"`
+	UnitPrice   Money  `json:"unit_price"`
+	Subtotal    Money  `json:"subtotal"`
+	// Discount is the sum of the discounts given on the line, zero or
+	// negative.
+	Discount Money `json:"discount"`
+}
+
+// Total returns what the line costs once discounted.
+func (line LinePricing) Total() Money {
+	return line.Subtotal.Add(line.Discount)
+}
+
+// AppliedPromotion is a promotion that gave a discount on the order.
+type AppliedPromotion struct {
+	PromotionID string `json:"promotion_id"`
+	Name        string `json:"name"`
+	// Amount is the discount given by the promotion, it is negative.
+	Amount Money `json:"amount"`
+	// Lines tells how Amount is shared between the lines of the order.
+	Lines []LineDiscount `json:"lines,omitempty"`
+	// Promotion is how the promotion was set up when the order was
+	// checked out, nil when its condition can't be serialized.
+	Promotion *PromotionSnapshot `json:"promotion,omitempty"`
+}
+
+// Clone returns a deep copy of the pricing.
+func (pricing *Pricing) Clone() *Pricing {
+	clone := *pricing
+	clone.Lines = append([]LinePricing(nil), pricing.Lines...)
+	clone.Discounts = append([]AppliedPromotion(nil), pricing.Discounts...)
+	for i := range clone.Discounts {
+		clone.Discounts[i].Lines = append([]LineDiscount(nil), clone.Discounts[i].Lines...)
+	}
+
+	return &clone
+}
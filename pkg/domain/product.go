@@ -4,6 +4,73 @@ type Product struct {
 	ID        string
 	SKU       string
 	Name      string
-	UnitPrice float64
+	UnitPrice Money
 	Quantity  int
+	// Digital products are delivered without shipping.
+	Digital bool
+	// Weight is the shipping weight of one item in grams.
+	Weight     int
+	Dimensions Dimensions
+
+	// Categories, Tags and Collections group products for browsing, and
+	// let promotions target them, see ProductSelector.
+	Categories  []string
+	Tags        []string
+	Collections []string
+
+	// ParentID is the id of the product the product is a variant of, e.g.
+	// a MacBook Pro with 32GB of memory. Variants are sold, stocked and
+	// priced on their own, a product that has variants can only be sold
+	// through one of them.
+	ParentID string
+	// Options tell a variant apart from the other variants of its product.
+	Options []ProductOption
+	// Variants are the variants of the product, filled by ShopService.
+	// Repositories store each variant as a product of its own.
+	Variants []*Product
+}
+
+// ProductOption is a characteristic of a variant, e.g. Color: Silver.
+type ProductOption struct {
+	Name  string
+	Value string
+}
+
+// Clone returns a deep copy of the product.
+func (product *Product) Clone() *Product {
+	clone := *product
+	clone.Categories = cloneStrings(product.Categories)
+	clone.Tags = cloneStrings(product.Tags)
+	clone.Collections = cloneStrings(product.Collections)
+	if product.Options != nil {
+		clone.Options = append([]ProductOption{}, product.Options...)
+	}
+	if product.Variants != nil {
+		clone.Variants = make([]*Product, 0, len(product.Variants))
+		for _, variant := range product.Variants {
+			clone.Variants = append(clone.Variants, variant.Clone())
+		}
+	}
+
+	return &clone
+}
+
+func cloneStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+
+	return append([]string{}, values...)
+}
+
+// Dimensions are the sizes of the package of one item in millimetres.
+type Dimensions struct {
+	Length int
+	Width  int
+	Height int
+}
+
+// Volume returns the volume of the package in cubic centimetres.
+func (d Dimensions) Volume() int {
+	return d.Length * d.Width * d.Height / 1000
 }
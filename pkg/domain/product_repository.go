@@ -0,0 +1,23 @@
+package domain
+
+// ProductRepository stores the products of the shop and their stock.
+// Implementations must be safe for concurrent use and must return copies
+// so callers can't modify stored products by accident.
+type ProductRepository interface {
+	// FindAll returns every product.
+	FindAll() ([]*Product, error)
+	// FindByID returns ErrProductNotFound if there is no product with the given id.
+	FindByID(productID string) (*Product, error)
+	// FindVariants returns the variants of the product, ordered by id.
+	FindVariants(productID string) ([]*Product, error)
+	// Save creates or replaces the product.
+	Save(product *Product) error
+	// DecreaseStock decreases the quantity of several products at once,
+	// keyed by product id. Either every quantity is decreased or none is,
+	// it returns ErrProductNotFound or ErrNotEnoughStock otherwise.
+	DecreaseStock(quantities map[string]int) error
+	// IncreaseStock puts back the quantity of several products at once,
+	// keyed by product id. It returns ErrProductNotFound and increases
+	// none if one of them doesn't exist.
+	IncreaseStock(quantities map[string]int) error
+}
@@ -0,0 +1,99 @@
+package domain
+
+// ProductSelector selects the products a promotion targets. A line is
+// selected when it matches every criterion that is set, and a list
+// criterion when the line has any of its values: ProductIDs: {"a", "b"}
+// and Tags: {"sale"} selects the lines of a or b tagged sale.
+//
+// The id of a product selects its variants as well, the id of a variant
+// only that variant.
+//
+// A selector without criteria selects nothing, unless All is set.
+type ProductSelector struct {
+	All         bool
+	ProductIDs  []string
+	SKUs        []string
+	Categories  []string
+	Tags        []string
+	Collections []string
+	// MinUnitPrice and MaxUnitPrice bound the unit price of the line,
+	// both included. A zero bound is not checked, a line in another
+	// currency than a bound is not selected.
+	MinUnitPrice Money
+	MaxUnitPrice Money
+}
+
+// SelectProducts returns a selector of the products with the given ids.
+func SelectProducts(productIDs ...string) ProductSelector {
+	return ProductSelector{ProductIDs: productIDs}
+}
+
+// IsZero tells whether the selector selects nothing.
+func (selector ProductSelector) IsZero() bool {
+	return !selector.All && len(selector.ProductIDs) == 0 && len(selector.SKUs) == 0 && len(selector.Categories) == 0 &&
+		len(selector.Tags) == 0 && len(selector.Collections) == 0 &&
+		selector.MinUnitPrice.IsZero() && selector.MaxUnitPrice.IsZero()
+}
+
+// Matches tells whether the selector selects line.
+func (selector ProductSelector) Matches(line *OrderLine) bool {
+	if selector.IsZero() {
+		return false
+	}
+
+	if len(selector.ProductIDs) > 0 && !containsAny(selector.ProductIDs, line.ProductID, line.ParentProductID) {
+		return false
+	}
+
+	if len(selector.SKUs) > 0 && !containsAny(selector.SKUs, line.SKU) {
+		return false
+	}
+
+	if len(selector.Categories) > 0 && !containsAny(selector.Categories, line.Categories...) {
+		return false
+	}
+
+	if len(selector.Tags) > 0 && !containsAny(selector.Tags, line.Tags...) {
+		return false
+	}
+
+	if len(selector.Collections) > 0 && !containsAny(selector.Collections, line.Collections...) {
+		return false
+	}
+
+	if !selector.MinUnitPrice.IsZero() &&
+		(line.UnitPrice.Currency != selector.MinUnitPrice.Currency || line.UnitPrice.Cmp(selector.MinUnitPrice) < 0) {
+		return false
+	}
+
+	if !selector.MaxUnitPrice.IsZero() &&
+		(line.UnitPrice.Currency != selector.MaxUnitPrice.Currency || line.UnitPrice.Cmp(selector.MaxUnitPrice) > 0) {
+		return false
+	}
+
+	return true
+}
+
+// Lines returns the lines of order the selector selects.
+func (selector ProductSelector) Lines(order *Order) []*OrderLine {
+	var lines []*OrderLine
+	for _, line := range order.Lines {
+		if selector.Matches(line) {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+func containsAny(values []string, candidates ...string) bool {
+	for _, candidate := range candidates {
+		for _, value := range values {
+			if value == candidate {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProductSelector_Matches(t *testing.T) {
+	line := &OrderLine{
+		ID:          "line1",
+		ProductID:   "p01",
+		Quantity:    1,
+		UnitPrice:   MustParseMoney("49.99", "USD"),
+		SKU:         "120P90",
+		Categories:  []string{"smart-home", "speakers"},
+		Tags:        []string{"sale"},
+		Collections: []string{"spring"},
+	}
+
+	tests := []struct {
+		name     string
+		selector ProductSelector
+		want     bool
+	}{
+		{
+			name:     "should select nothing without criteria",
+			selector: ProductSelector{},
+			want:     false,
+		},
+		{
+			name:     "should select every line when all is set",
+			selector: ProductSelector{All: true},
+			want:     true,
+		},
+		{
+			name:     "should select by product id",
+			selector: SelectProducts("p02", "p01"),
+			want:     true,
+		},
+		{
+			name:     "should select by sku",
+			selector: ProductSelector{SKUs: []string{"43N23P"}},
+			want:     false,
+		},
+		{
+			name:     "should select lines having any of the categories",
+			selector: ProductSelector{Categories: []string{"laptops", "speakers"}},
+			want:     true,
+		},
+		{
+			name:     "should require every criterion to match",
+			selector: ProductSelector{Tags: []string{"sale"}, Collections: []string{"winter"}},
+			want:     false,
+		},
+		{
+			name:     "should select within the price range, bounds included",
+			selector: ProductSelector{MinUnitPrice: MustParseMoney("49.99", "USD"), MaxUnitPrice: MustParseMoney("100", "USD")},
+			want:     true,
+		},
+		{
+			name:     "should not select above the price range",
+			selector: ProductSelector{Categories: []string{"speakers"}, MaxUnitPrice: MustParseMoney("49.98", "USD")},
+			want:     false,
+		},
+		{
+			name:     "should not select lines in another currency than the price range",
+			selector: ProductSelector{MinUnitPrice: MustParseMoney("1", "SGD")},
+			want:     false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, test.selector.Matches(line))
+		})
+	}
+}
+
+func TestProductSelector_MatchesVariants(t *testing.T) {
+	line := &OrderLine{
+		ID:              "line1",
+		ProductID:       "macbookpro-32gb",
+		ParentProductID: "macbookpro",
+		Quantity:        1,
+		UnitPrice:       MustParseMoney("5999.99", "USD"),
+	}
+
+	assert.True(t, SelectProducts("macbookpro").Matches(line))
+	assert.True(t, SelectProducts("macbookpro-32gb").Matches(line))
+	assert.False(t, SelectProducts("macbookpro-16gb").Matches(line))
+	assert.False(t, SelectProducts("macbookpro-32gb").Matches(&OrderLine{ID: "line2", ProductID: "macbookpro"}))
+}
@@ -1,9 +1,66 @@
 package domain
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 type Promotion struct {
+	ID        string
+	Name      string
 	StartDate time.Time
 	EndDate   time.Time
 	Condition PromotionCondition
+
+	// Priority orders the promotions, the ones with the highest priority
+	// are applied first. Promotions of the same priority are applied in
+	// the order of their ID.
+	Priority int
+	// Exclusive promotions are never combined with another promotion.
+	Exclusive bool
+	// StackGroup, when set, lets at most one promotion of the group apply.
+	StackGroup string
+	// MaxOnePerLine keeps the lines the promotion discounts from being
+	// discounted by any other promotion.
+	MaxOnePerLine bool
+
+	// CouponCode, when set, makes the promotion only apply to the orders
+	// the code is applied to.
+	CouponCode string
+	// UsageLimit is how many orders can use the coupon code, and
+	// UsageLimitPerCustomer how many orders of the same customer can.
+	// They are unlimited when zero.
+	UsageLimit            int
+	UsageLimitPerCustomer int
+}
+
+// PromotionSnapshot records how a promotion was set up when it applied to
+// an order, its condition as the type and parameters building it, so the
+// order can be priced again the same way once the promotion changed.
+type PromotionSnapshot struct {
+	ConditionType string                 `json:"condition_type"`
+	Parameters    map[string]interface{} `json:"parameters"`
+	Priority      int                    `json:"priority,omitempty"`
+	Exclusive     bool                   `json:"exclusive,omitempty"`
+	StackGroup    string                 `json:"stack_group,omitempty"`
+	MaxOnePerLine bool                   `json:"max_one_per_line,omitempty"`
+}
+
+// IsActive tells whether the promotion runs at the given time.
+func (promotion Promotion) IsActive(now time.Time) bool {
+	if !promotion.StartDate.IsZero() && promotion.StartDate.After(now) {
+		return false
+	}
+
+	if !promotion.EndDate.IsZero() && promotion.EndDate.Before(now) {
+		return false
+	}
+
+	return promotion.Condition != nil
+}
+
+// NormalizeCouponCode returns code the way it is stored, coupon codes are
+// not case sensitive.
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
 }
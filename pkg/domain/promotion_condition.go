@@ -1,5 +1,136 @@
 package domain
 
+import (
+	"math/big"
+	"sort"
+)
+
+// PromotionCondition works out the discount a promotion gives on an
+// order. Conditions that can tell which lines they discount implement
+// LineDiscounter as well.
 type PromotionCondition interface {
-	CalculateDiscount(order *Order) float64
+	CalculateDiscount(order *Order) Money
+}
+
+// LineDiscount is the part of a discount given on one order line, it is
+// negative.
+type LineDiscount struct {
+	OrderLineID string `json:"order_line_id"`
+	// Quantity is how many units of the line are discounted.
+	Quantity int   `json:"quantity"`
+	Amount   Money `json:"amount"`
+}
+
+// LineDiscounter is implemented by the conditions that tell which lines
+// of the order they discount, and how many units of them.
+type LineDiscounter interface {
+	CalculateLineDiscounts(order *Order) []LineDiscount
+}
+
+// CartDiscounter is implemented by the conditions on the whole order,
+// such as "spend 500 get 50 off". They are evaluated after the conditions
+// on products, lineDiscounts holds the discount already given on each
+// line by order line ID.
+type CartDiscounter interface {
+	CalculateCartDiscounts(order *Order, lineDiscounts map[string]Money) []LineDiscount
+}
+
+// LineDiscounterOf returns condition if it is a LineDiscounter, or
+// condition adapted by ProportionalLineDiscounter otherwise.
+func LineDiscounterOf(condition PromotionCondition) LineDiscounter {
+	if discounter, ok := condition.(LineDiscounter); ok {
+		return discounter
+	}
+
+	return ProportionalLineDiscounter{Condition: condition}
+}
+
+// ProportionalLineDiscounter adapts a condition that only gives the
+// discount of the whole order: the discount is spread over every line in
+// proportion to its subtotal.
+type ProportionalLineDiscounter struct {
+	Condition PromotionCondition
+}
+
+// CalculateLineDiscounts spreads the discount of the condition over the
+// lines of the order, see SpreadDiscount.
+func (d ProportionalLineDiscounter) CalculateLineDiscounts(order *Order) []LineDiscount {
+	return SpreadDiscount(d.Condition.CalculateDiscount(order), order.Lines, (*OrderLine).Subtotal)
+}
+
+// SpreadDiscount spreads discount over lines in proportion to the amount
+// weight gives each of them. The minor units lost to rounding go to the
+// lines with the largest remainders, so the parts add up to the discount.
+// Lines with no part of the discount are left out.
+func SpreadDiscount(discount Money, lines []*OrderLine, weight func(line *OrderLine) Money) []LineDiscount {
+	weights := make([]int64, len(lines))
+	var total int64
+	for i, line := range lines {
+		if w := weight(line).Amount; w > 0 {
+			weights[i] = w
+			total += w
+		}
+	}
+
+	if discount.IsZero() || total <= 0 {
+		return nil
+	}
+
+	type share struct {
+		index     int
+		remainder *big.Int
+	}
+
+	discounts := make([]LineDiscount, len(lines))
+	shares := make([]share, 0, len(lines))
+	allocated := int64(0)
+	for i, line := range lines {
+		quotient, remainder := new(big.Int).QuoRem(
+			new(big.Int).Mul(big.NewInt(discount.Amount), big.NewInt(weights[i])),
+			big.NewInt(total),
+			new(big.Int),
+		)
+		discounts[i] = LineDiscount{
+			OrderLineID: line.ID,
+			Quantity:    line.Quantity,
+			Amount:      NewMoney(quotient.Int64(), discount.Currency),
+		}
+		if weights[i] > 0 {
+			shares = append(shares, share{index: i, remainder: remainder.Abs(remainder)})
+		}
+		allocated += quotient.Int64()
+	}
+
+	sort.SliceStable(shares, func(i, j int) bool {
+		return shares[i].remainder.Cmp(shares[j].remainder) > 0
+	})
+
+	unit := int64(1)
+	if discount.IsNegative() {
+		unit = -1
+	}
+	for i := 0; allocated != discount.Amount; i++ {
+		d := &discounts[shares[i%len(shares)].index]
+		d.Amount = d.Amount.Add(NewMoney(unit, discount.Currency))
+		allocated += unit
+	}
+
+	allocatedDiscounts := discounts[:0]
+	for _, d := range discounts {
+		if !d.Amount.IsZero() {
+			allocatedDiscounts = append(allocatedDiscounts, d)
+		}
+	}
+
+	return allocatedDiscounts
+}
+
+// SumLineDiscounts returns the total of discounts.
+func SumLineDiscounts(discounts []LineDiscount) Money {
+	var total Money
+	for _, discount := range discounts {
+		total = total.Add(discount.Amount)
+	}
+
+	return total
 }
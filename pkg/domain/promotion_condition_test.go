@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fixedDiscount struct {
+	amount Money
+}
+
+func (cond fixedDiscount) CalculateDiscount(order *Order) Money {
+	return cond.amount
+}
+
+func TestProportionalLineDiscounter_CalculateLineDiscounts(t *testing.T) {
+	order := &Order{
+		Lines: []*OrderLine{
+			{ID: "l1", Quantity: 1, UnitPrice: MustParseMoney("10", "USD")},
+			{ID: "l2", Quantity: 1, UnitPrice: MustParseMoney("10", "USD")},
+			{ID: "l3", Quantity: 2, UnitPrice: MustParseMoney("5", "USD")},
+			{ID: "l4", Quantity: 1, UnitPrice: MustParseMoney("0", "USD")},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		discount Money
+		want     []LineDiscount
+	}{
+		{
+			name:     "should give the minor units lost to rounding to the largest remainders",
+			discount: MustParseMoney("-10", "USD"),
+			want: []LineDiscount{
+				{OrderLineID: "l1", Quantity: 1, Amount: MustParseMoney("-3.34", "USD")},
+				{OrderLineID: "l2", Quantity: 1, Amount: MustParseMoney("-3.33", "USD")},
+				{OrderLineID: "l3", Quantity: 2, Amount: MustParseMoney("-3.33", "USD")},
+			},
+		},
+		{
+			name:     "should leave out lines that get nothing",
+			discount: MustParseMoney("-0.02", "USD"),
+			want: []LineDiscount{
+				{OrderLineID: "l1", Quantity: 1, Amount: MustParseMoney("-0.01", "USD")},
+				{OrderLineID: "l2", Quantity: 1, Amount: MustParseMoney("-0.01", "USD")},
+			},
+		},
+		{
+			name:     "should return nothing without discount",
+			discount: Money{},
+			want:     nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sut := LineDiscounterOf(fixedDiscount{amount: test.discount})
+
+			got := sut.CalculateLineDiscounts(order)
+			assert.Equal(t, test.want, got)
+			if len(got) > 0 {
+				assert.Equal(t, test.discount, SumLineDiscounts(got))
+			}
+		})
+	}
+}
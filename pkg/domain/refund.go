@@ -0,0 +1,67 @@
+package domain
+
+import "time"
+
+type RefundState string
+
+const (
+	// RefundStatePending is a refund recorded on the order but not sent
+	// to the payment provider yet.
+	RefundStatePending RefundState = "Pending"
+	RefundStateSettled RefundState = "Settled"
+	// RefundStateFailed is a refund the payment provider didn't make, it
+	// must be refunded by hand.
+	RefundStateFailed RefundState = "Failed"
+)
+
+// Refund gives money back to the customer for some lines of a paid
+// order, their items are back in stock.
+type Refund struct {
+	ID    string
+	Lines []RefundLine
+	// Amount includes the discounts of the promotions that no longer
+	// apply to what the customer keeps, so it can be less than what the
+	// refunded lines cost.
+	Amount Money
+	Reason string
+	State  RefundState
+	// PaymentID is the payment the refund was sent back to, it is empty
+	// when the order was paid outside of the shop and must be refunded by
+	// hand.
+	PaymentID string
+	// TransactionID references the refund at the payment provider.
+	TransactionID string
+	// ErrorMessage tells why the payment provider didn't make the refund.
+	ErrorMessage string
+	CreatedAt    time.Time
+}
+
+// RefundLine is a quantity of an order line to refund.
+type RefundLine struct {
+	OrderLineID string
+	Quantity    int
+}
+
+// RefundedQuantity returns how many items of the order line were refunded.
+func (order *Order) RefundedQuantity(lineID string) int {
+	quantity := 0
+	for _, refund := range order.Refunds {
+		for _, line := range refund.Lines {
+			if line.OrderLineID == lineID {
+				quantity += line.Quantity
+			}
+		}
+	}
+
+	return quantity
+}
+
+// AmountRefunded returns the sum of the refunds of the order.
+func (order *Order) AmountRefunded() Money {
+	var refunded Money
+	for _, refund := range order.Refunds {
+		refunded = refunded.Add(refund.Amount)
+	}
+
+	return refunded
+}
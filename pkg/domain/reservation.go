@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// Reservation holds some stock of a product for an order that is not
+// checked out yet, so it can't be sold to anybody else until it expires.
+type Reservation struct {
+	OrderID   string
+	ProductID string
+	Quantity  int
+	ExpiresAt time.Time
+}
+
+// IsActive tells whether the reservation still holds stock at the given time.
+func (reservation *Reservation) IsActive(now time.Time) bool {
+	return now.Before(reservation.ExpiresAt)
+}
+
+// StockLevel tells how much of a product is on hand and how much of it
+// can still be sold.
+type StockLevel struct {
+	ProductID string
+	OnHand    int
+	// Reserved is the quantity held by active reservations.
+	Reserved int
+	// Available is the quantity that can still be added to a cart.
+	Available int
+}
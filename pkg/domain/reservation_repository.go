@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// ReservationRepository stores stock reservations, an order holds at most
+// one reservation per product. Implementations must be safe for concurrent use.
+type ReservationRepository interface {
+	// FindByOrder returns the reservations of the order, expired ones included.
+	FindByOrder(orderID string) ([]*Reservation, error)
+	// ReservedQuantity returns the quantity of the product held by
+	// reservations that are still active at now.
+	ReservedQuantity(productID string, now time.Time) (int, error)
+	// Save creates or replaces the reservation of the order for the product.
+	Save(reservation *Reservation) error
+	// Delete releases the reservation of the order for the product, it
+	// does nothing if there is none.
+	Delete(orderID string, productID string) error
+	// DeleteByOrder releases every reservation of the order.
+	DeleteByOrder(orderID string) error
+	// DeleteExpired releases the reservations that are expired at now and
+	// returns how many there were.
+	DeleteExpired(now time.Time) (int, error)
+}
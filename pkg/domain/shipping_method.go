@@ -0,0 +1,57 @@
+package domain
+
+// ShippingMethod is a way of shipping orders, its Calculator prices it.
+type ShippingMethod struct {
+	ID          string
+	Code        string
+	Name        string
+	Description string
+	Calculator  ShippingCalculator
+}
+
+// ShippingCalculator computes the cost of a shipment.
+type ShippingCalculator interface {
+	// CalculateShippingCost returns ErrShippingMethodNotEligible when the
+	// shipment can't be shipped this way, e.g. to an unserved region.
+	CalculateShippingCost(shipment *Shipment) (Money, error)
+}
+
+// Shipment is what gets shipped for an order: its physical products,
+// where they go and what they are worth.
+type Shipment struct {
+	Address *Address
+	Items   []ShipmentItem
+	// Value is the price of the order before shipping, discounts included.
+	Value Money
+}
+
+type ShipmentItem struct {
+	Product  *Product
+	Quantity int
+}
+
+// Weight returns the total weight of the shipment in grams.
+func (shipment *Shipment) Weight() int {
+	weight := 0
+	for _, item := range shipment.Items {
+		weight += item.Product.Weight * item.Quantity
+	}
+
+	return weight
+}
+
+// Volume returns the total volume of the shipment in cubic centimetres.
+func (shipment *Shipment) Volume() int {
+	volume := 0
+	for _, item := range shipment.Items {
+		volume += item.Product.Dimensions.Volume() * item.Quantity
+	}
+
+	return volume
+}
+
+// ShippingQuote is the cost of shipping an order with a shipping method.
+type ShippingQuote struct {
+	Method ShippingMethod
+	Cost   Money
+}
@@ -1,9 +1,36 @@
 package domain
 
 type ShopService interface {
-	CreateCart() *Order
-	ListProducts() []*Product
+	CreateCart() (*Order, error)
+	GetActiveOrder(owner OrderOwner) (*Order, error)
+	GetOrCreateActiveOrder(owner OrderOwner) (*Order, error)
+	RegisterCustomer(emailAddress string, password string) (*Customer, error)
+	Authenticate(emailAddress string, password string) (*Customer, error)
+	GetCustomer(customerID string) (*Customer, error)
+	GetCustomerByEmailAddress(emailAddress string) (*Customer, error)
+	MergeSessionCart(sessionID string, customerID string) (*Order, error)
+	GetOrder(orderID string) (*Order, error)
+	ListProducts() ([]*Product, error)
+	GetProduct(productID string) (*Product, error)
+	GetStockLevel(productID string) (*StockLevel, error)
+	GetActivePromotions() []Promotion
 	AddItemToCart(orderID string, productID string, quantity int) (*Order, error)
 	RemoveItemFromCart(orderID string, productID string) (*Order, error)
-	Checkout(orderID string) (totalAmount float64, err error)
+	SetShippingAddress(orderID string, address Address) (*Order, error)
+	SetBillingAddress(orderID string, address Address) (*Order, error)
+	GetShippingMethod(methodID string) (*ShippingMethod, error)
+	GetEligibleShippingMethods(orderID string) ([]ShippingQuote, error)
+	SetShippingMethod(orderID string, methodID string) (*Order, error)
+	ApplyCoupon(orderID string, code string) (*Order, error)
+	RemoveCoupon(orderID string, code string) (*Order, error)
+	QuoteOrder(orderID string) (*Pricing, error)
+	Checkout(orderID string) (*Pricing, error)
+	GetPaymentMethods() []PaymentMethod
+	AddPayment(orderID string, method string, token string) (*Order, error)
+	MarkOrderAsPaid(orderID string) (*Order, error)
+	ShipOrder(orderID string) (*Order, error)
+	DeliverOrder(orderID string) (*Order, error)
+	CancelOrder(orderID string) (*Order, error)
+	RefundOrderLines(orderID string, lines []RefundLine, reason string) (*Refund, error)
+	ReleaseExpiredReservations() (int, error)
 }
@@ -0,0 +1,13 @@
+package domain
+
+// Store gives access to every repository and lets several writes be
+// committed atomically.
+type Store interface {
+	Products() ProductRepository
+	Orders() OrderRepository
+	Reservations() ReservationRepository
+	Customers() CustomerRepository
+	// Transaction runs fn with a Store whose writes are committed only if
+	// fn returns nil, they are all rolled back otherwise.
+	Transaction(fn func(tx Store) error) error
+}
@@ -0,0 +1,29 @@
+package domain
+
+import "strings"
+
+// FieldError tells why the value of a field is invalid.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// ValidationError reports every invalid field of an input at once.
+type ValidationError struct {
+	// Err is the sentinel error the validation error wraps, e.g. ErrInvalidAddress.
+	Err    error
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, field.Field+" "+field.Message)
+	}
+
+	return e.Err.Error() + ": " + strings.Join(messages, ", ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,173 @@
+// Package paymentprovider implements the payment gateways orders can be
+// paid with.
+package paymentprovider
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/rs/xid"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+// Tokens making the fake provider simulate failures, any other token is
+// authorized.
+const (
+	FakeTokenDecline        = "decline"
+	FakeTokenTimeout        = "timeout"
+	FakeTokenCaptureFailure = "capture-failure"
+)
+
+// ErrFakeTimeout is returned by the fake provider to simulate a gateway
+// that doesn't answer in time. The payment is authorized anyway, as it
+// would be by a gateway whose answer got lost, sending it again with the
+// same idempotency key returns its transaction.
+var ErrFakeTimeout = fmt.Errorf("%w: fake payment provider", domain.ErrPaymentTimeout)
+
+var errUnknownTransaction = errors.New("unknown transaction")
+
+// Fake is an in-process payment provider keeping its transactions in
+// memory, for tests and demos. The token of a payment request chooses
+// the outcome of the payment.
+type Fake struct {
+	mu           sync.Mutex
+	transactions map[string]*FakeTransaction
+	// authorizations are the ids of the transactions by idempotency key.
+	authorizations map[string]string
+	// captures are the idempotency keys of the captures made.
+	captures map[string]bool
+	// refunds are the ids of the refunds by idempotency key.
+	refunds map[string]string
+}
+
+// FakeTransaction is the state of a payment at the fake provider.
+type FakeTransaction struct {
+	Authorized domain.Money
+	Captured   domain.Money
+	Refunded   domain.Money
+	Voided     bool
+
+	failCapture bool
+}
+
+func NewFake() *Fake {
+	return &Fake{
+		transactions:   make(map[string]*FakeTransaction),
+		authorizations: make(map[string]string),
+		captures:       make(map[string]bool),
+		refunds:        make(map[string]string),
+	}
+}
+
+func (f *Fake) Authorize(request domain.PaymentRequest) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if transactionID, ok := f.authorizations[request.IdempotencyKey]; ok {
+		return transactionID, nil
+	}
+
+	if request.Token == FakeTokenDecline {
+		return "", fmt.Errorf("%w: insufficient funds", domain.ErrPaymentDeclined)
+	}
+
+	transactionID := xid.New().String()
+	f.transactions[transactionID] = &FakeTransaction{
+		Authorized:  request.Amount,
+		failCapture: request.Token == FakeTokenCaptureFailure,
+	}
+	if request.IdempotencyKey != "" {
+		f.authorizations[request.IdempotencyKey] = transactionID
+	}
+
+	if request.Token == FakeTokenTimeout {
+		return "", ErrFakeTimeout
+	}
+
+	return transactionID, nil
+}
+
+func (f *Fake) Capture(transactionID string, amount domain.Money, idempotencyKey string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.captures[idempotencyKey] {
+		return nil
+	}
+
+	transaction, ok := f.transactions[transactionID]
+	switch {
+	case !ok:
+		return fmt.Errorf("%w: %s", errUnknownTransaction, transactionID)
+	case transaction.failCapture:
+		return errors.New("capture failed")
+	case transaction.Voided:
+		return errors.New("cannot capture a voided transaction")
+	case transaction.Captured.Add(amount).Cmp(transaction.Authorized) > 0:
+		return fmt.Errorf("cannot capture %s, only %s is authorized", amount, transaction.Authorized)
+	}
+
+	transaction.Captured = transaction.Captured.Add(amount)
+	if idempotencyKey != "" {
+		f.captures[idempotencyKey] = true
+	}
+
+	return nil
+}
+
+func (f *Fake) Void(transactionID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	transaction, ok := f.transactions[transactionID]
+	switch {
+	case !ok:
+		return fmt.Errorf("%w: %s", errUnknownTransaction, transactionID)
+	case !transaction.Captured.IsZero():
+		return errors.New("cannot void a captured transaction")
+	}
+
+	transaction.Voided = true
+	return nil
+}
+
+func (f *Fake) Refund(transactionID string, amount domain.Money, idempotencyKey string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if refundID, ok := f.refunds[idempotencyKey]; ok {
+		return refundID, nil
+	}
+
+	transaction, ok := f.transactions[transactionID]
+	switch {
+	case !ok:
+		return "", fmt.Errorf("%w: %s", errUnknownTransaction, transactionID)
+	case transaction.Refunded.Add(amount).Cmp(transaction.Captured) > 0:
+		return "", fmt.Errorf("cannot refund %s, only %s is captured", amount, transaction.Captured.Sub(transaction.Refunded))
+	}
+
+	transaction.Refunded = transaction.Refunded.Add(amount)
+	refundID := xid.New().String()
+	if idempotencyKey != "" {
+		f.refunds[idempotencyKey] = refundID
+	}
+
+	return refundID, nil
+}
+
+// Transaction returns a copy of the transaction with transactionID, it
+// returns false when there is none.
+func (f *Fake) Transaction(transactionID string) (FakeTransaction, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	transaction, ok := f.transactions[transactionID]
+	if !ok {
+		return FakeTransaction{}, false
+	}
+
+	return *transaction, true
+}
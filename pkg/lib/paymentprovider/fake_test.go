@@ -0,0 +1,114 @@
+package paymentprovider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+func TestFake_Authorize(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{
+			name:  "should authorize any other token",
+			token: "tok_visa",
+		},
+		{
+			name:    "should decline",
+			token:   FakeTokenDecline,
+			wantErr: domain.ErrPaymentDeclined,
+		},
+		{
+			name:    "should time out",
+			token:   FakeTokenTimeout,
+			wantErr: domain.ErrPaymentTimeout,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sut := NewFake()
+			amount := domain.MustParseMoney("49.99", "USD")
+
+			transactionID, err := sut.Authorize(domain.PaymentRequest{OrderID: "o01", Amount: amount, Token: test.token})
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+				assert.Empty(t, transactionID)
+				return
+			}
+
+			require.NoError(t, err)
+			transaction, ok := sut.Transaction(transactionID)
+			assert.True(t, ok)
+			assert.Equal(t, amount, transaction.Authorized)
+		})
+	}
+}
+
+func TestFake_Lifecycle(t *testing.T) {
+	sut := NewFake()
+	amount := domain.MustParseMoney("100", "USD")
+
+	transactionID, err := sut.Authorize(domain.PaymentRequest{OrderID: "o01", Amount: amount, Token: "tok_visa"})
+	require.NoError(t, err)
+
+	_, err = sut.Refund(transactionID, domain.MustParseMoney("1", "USD"), "r01")
+	assert.Error(t, err, "should not refund what is not captured")
+
+	assert.Error(t, sut.Capture(transactionID, domain.MustParseMoney("100.01", "USD"), "c01"), "should not capture more than authorized")
+	assert.NoError(t, sut.Capture(transactionID, amount, "c02"))
+	assert.NoError(t, sut.Capture(transactionID, amount, "c02"), "should capture once per idempotency key")
+	assert.Error(t, sut.Void(transactionID), "should not void a captured transaction")
+
+	refundID, err := sut.Refund(transactionID, domain.MustParseMoney("40", "USD"), "r02")
+	assert.NoError(t, err)
+	again, err := sut.Refund(transactionID, domain.MustParseMoney("40", "USD"), "r02")
+	assert.NoError(t, err)
+	assert.Equal(t, refundID, again, "should refund once per idempotency key")
+	_, err = sut.Refund(transactionID, domain.MustParseMoney("60.01", "USD"), "r03")
+	assert.Error(t, err, "should not refund more than captured")
+
+	transaction, _ := sut.Transaction(transactionID)
+	assert.Equal(t, domain.MustParseMoney("40", "USD"), transaction.Refunded)
+}
+
+func TestFake_CaptureFailure(t *testing.T) {
+	sut := NewFake()
+	amount := domain.MustParseMoney("100", "USD")
+
+	transactionID, err := sut.Authorize(domain.PaymentRequest{OrderID: "o01", Amount: amount, Token: FakeTokenCaptureFailure})
+	require.NoError(t, err)
+
+	assert.Error(t, sut.Capture(transactionID, amount, "c01"))
+	assert.NoError(t, sut.Void(transactionID))
+	assert.Error(t, sut.Capture(transactionID, amount, "c02"))
+
+	transaction, _ := sut.Transaction(transactionID)
+	assert.True(t, transaction.Voided)
+}
+
+func TestFake_Timeout(t *testing.T) {
+	sut := NewFake()
+	amount := domain.MustParseMoney("100", "USD")
+	request := domain.PaymentRequest{OrderID: "o01", Amount: amount, Token: FakeTokenTimeout, IdempotencyKey: "p01"}
+
+	_, err := sut.Authorize(request)
+	require.ErrorIs(t, err, ErrFakeTimeout)
+
+	request.Token = "tok_visa"
+	transactionID, err := sut.Authorize(request)
+	require.NoError(t, err)
+	again, err := sut.Authorize(request)
+	require.NoError(t, err)
+	assert.Equal(t, transactionID, again, "should authorize once per idempotency key")
+
+	transaction, ok := sut.Transaction(transactionID)
+	assert.True(t, ok, "should authorize the payment that timed out")
+	assert.Equal(t, amount, transaction.Authorized)
+}
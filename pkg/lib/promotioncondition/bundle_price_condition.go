@@ -0,0 +1,125 @@
+package promotioncondition
+
+import "github.com/donnpebe/shoppo/pkg/domain"
+
+const BundlePriceType = "bundle_price"
+
+// BundlePrice sells a set of products for a fixed price, e.g. "MacBook
+// Pro + Google Home + Alexa Speaker for 5500". ProductIDs is a multiset, a
+// product listed twice takes two units of it, and takes units of any of
+// its variants. The bundle applies once for every complete set in the
+// order.
+type BundlePrice struct {
+	ProductIDs []string
+	Price      domain.Money
+}
+
+func newBundlePrice(params *Parameters) domain.PromotionCondition {
+	currency := params.Currency("currencyCode")
+	price := params.Money("price", currency)
+	if price.Currency != "" && price.Amount <= 0 {
+		params.Fail("price", "must be more than 0")
+	}
+
+	return BundlePrice{
+		ProductIDs: params.Strings("productIds"),
+		Price:      price,
+	}
+}
+
+func (cond BundlePrice) ConditionType() string {
+	return BundlePriceType
+}
+
+func (cond BundlePrice) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"productIds":   stringList(cond.ProductIDs),
+		"price":        cond.Price.Decimal(),
+		"currencyCode": cond.Price.Currency,
+	}
+}
+
+func (cond BundlePrice) CalculateDiscount(order *domain.Order) domain.Money {
+	return domain.SumLineDiscounts(cond.CalculateLineDiscounts(order))
+}
+
+// CalculateLineDiscounts takes the units of as many complete bundles as
+// the order holds, from the lines of each product in turn, and spreads the
+// difference between their price and the bundle price over their lines in
+// proportion to the price of the units taken.
+func (cond BundlePrice) CalculateLineDiscounts(order *domain.Order) []domain.LineDiscount {
+	if len(cond.ProductIDs) == 0 {
+		return nil
+	}
+
+	required := make(map[string]int, len(cond.ProductIDs))
+	for _, productID := range cond.ProductIDs {
+		required[productID]++
+	}
+
+	available := make(map[string]int, len(required))
+	for _, line := range order.Lines {
+		if line.UnitPrice.Currency != cond.Price.Currency {
+			return nil
+		}
+		available[bundled(line, required)] += line.Quantity
+	}
+
+	bundles := -1
+	for productID, quantity := range required {
+		if n := available[productID] / quantity; bundles < 0 || n < bundles {
+			bundles = n
+		}
+	}
+	if bundles <= 0 {
+		return nil
+	}
+
+	left := make(map[string]int, len(required))
+	for productID, quantity := range required {
+		left[productID] = quantity * bundles
+	}
+
+	taken := make(map[string]int, len(order.Lines))
+	var lines []*domain.OrderLine
+	var regular domain.Money
+	for _, line := range order.Lines {
+		productID := bundled(line, required)
+		quantity := line.Quantity
+		if quantity > left[productID] {
+			quantity = left[productID]
+		}
+		if quantity == 0 {
+			continue
+		}
+
+		left[productID] -= quantity
+		taken[line.ID] = quantity
+		lines = append(lines, line)
+		regular = regular.Add(line.UnitPrice.Mul(quantity))
+	}
+
+	discount := cond.Price.Mul(bundles).Sub(regular)
+	if !discount.IsNegative() {
+		return nil
+	}
+
+	discounts := domain.SpreadDiscount(discount, lines, func(line *domain.OrderLine) domain.Money {
+		return line.UnitPrice.Mul(taken[line.ID])
+	})
+	for i := range discounts {
+		discounts[i].Quantity = taken[discounts[i].OrderLineID]
+	}
+
+	return discounts
+}
+
+// bundled returns the product of the bundle line is a unit of, the
+// variant itself when the bundle lists it and its product otherwise.
+func bundled(line *domain.OrderLine, required map[string]int) string {
+	if _, ok := required[line.ProductID]; ok || line.ParentProductID == "" {
+		return line.ProductID
+	}
+
+	return line.ParentProductID
+}
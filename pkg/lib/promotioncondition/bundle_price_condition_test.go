@@ -0,0 +1,156 @@
+package promotioncondition
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+func TestBundlePrice_CalculateLineDiscounts(t *testing.T) {
+	line := func(id string, productID string, quantity int, unitPrice string) *domain.OrderLine {
+		return &domain.OrderLine{ID: id, ProductID: productID, Quantity: quantity, UnitPrice: domain.MustParseMoney(unitPrice, "USD")}
+	}
+	bundle := BundlePrice{ProductIDs: []string{"p01", "p02", "p03"}, Price: domain.MustParseMoney("400", "USD")}
+
+	tests := []struct {
+		name      string
+		condition BundlePrice
+		lines     []*domain.OrderLine
+		want      []domain.LineDiscount
+	}{
+		{
+			name:      "should spread the bundle discount over its lines",
+			condition: bundle,
+			lines:     []*domain.OrderLine{line("line1", "p01", 1, "300"), line("line2", "p02", 1, "100"), line("line3", "p03", 1, "100")},
+			want: []domain.LineDiscount{
+				{OrderLineID: "line1", Quantity: 1, Amount: domain.MustParseMoney("-60", "USD")},
+				{OrderLineID: "line2", Quantity: 1, Amount: domain.MustParseMoney("-20", "USD")},
+				{OrderLineID: "line3", Quantity: 1, Amount: domain.MustParseMoney("-20", "USD")},
+			},
+		},
+		{
+			name:      "should apply once for every complete bundle",
+			condition: bundle,
+			lines:     []*domain.OrderLine{line("line1", "p01", 2, "300"), line("line2", "p02", 3, "100"), line("line3", "p03", 2, "100")},
+			want: []domain.LineDiscount{
+				{OrderLineID: "line1", Quantity: 2, Amount: domain.MustParseMoney("-120", "USD")},
+				{OrderLineID: "line2", Quantity: 2, Amount: domain.MustParseMoney("-40", "USD")},
+				{OrderLineID: "line3", Quantity: 2, Amount: domain.MustParseMoney("-40", "USD")},
+			},
+		},
+		{
+			name:      "should take as many units as a product is listed",
+			condition: BundlePrice{ProductIDs: []string{"p01", "p02", "p02"}, Price: domain.MustParseMoney("400", "USD")},
+			lines:     []*domain.OrderLine{line("line1", "p01", 1, "300"), line("line2", "p02", 1, "100"), line("line3", "p02", 2, "100")},
+			want: []domain.LineDiscount{
+				{OrderLineID: "line1", Quantity: 1, Amount: domain.MustParseMoney("-60", "USD")},
+				{OrderLineID: "line2", Quantity: 1, Amount: domain.MustParseMoney("-20", "USD")},
+				{OrderLineID: "line3", Quantity: 1, Amount: domain.MustParseMoney("-20", "USD")},
+			},
+		},
+		{
+			name:      "should take units of the variants of a product",
+			condition: bundle,
+			lines: []*domain.OrderLine{
+				{ID: "line1", ProductID: "p01-32gb", ParentProductID: "p01", Quantity: 1, UnitPrice: domain.MustParseMoney("300", "USD")},
+				line("line2", "p02", 1, "100"),
+				line("line3", "p03", 1, "100"),
+			},
+			want: []domain.LineDiscount{
+				{OrderLineID: "line1", Quantity: 1, Amount: domain.MustParseMoney("-60", "USD")},
+				{OrderLineID: "line2", Quantity: 1, Amount: domain.MustParseMoney("-20", "USD")},
+				{OrderLineID: "line3", Quantity: 1, Amount: domain.MustParseMoney("-20", "USD")},
+			},
+		},
+		{
+			name:      "should return nothing when the bundle is incomplete",
+			condition: BundlePrice{ProductIDs: []string{"p01", "p02", "p02"}, Price: domain.MustParseMoney("400", "USD")},
+			lines:     []*domain.OrderLine{line("line1", "p01", 3, "300"), line("line2", "p02", 1, "100")},
+		},
+		{
+			name:      "should return nothing when the bundle costs more than its products",
+			condition: BundlePrice{ProductIDs: []string{"p01", "p02"}, Price: domain.MustParseMoney("400", "USD")},
+			lines:     []*domain.OrderLine{line("line1", "p01", 1, "300"), line("line2", "p02", 1, "100")},
+		},
+		{
+			name:      "should return nothing for an order in another currency",
+			condition: bundle,
+			lines: []*domain.OrderLine{
+				{ID: "line1", ProductID: "p01", Quantity: 1, UnitPrice: domain.MustParseMoney("300", "SGD")},
+				{ID: "line2", ProductID: "p02", Quantity: 1, UnitPrice: domain.MustParseMoney("100", "SGD")},
+				{ID: "line3", ProductID: "p03", Quantity: 1, UnitPrice: domain.MustParseMoney("100", "SGD")},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.condition.CalculateLineDiscounts(&domain.Order{Lines: test.lines})
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestNewBundlePrice(t *testing.T) {
+	tests := []struct {
+		name       string
+		params     map[string]interface{}
+		want       domain.PromotionCondition
+		wantFields []domain.FieldError
+	}{
+		{
+			name: "should build the bundle",
+			params: map[string]interface{}{
+				"productIds":   []interface{}{"macbookpro", "googlehome", "alexaspeaker"},
+				"price":        5500,
+				"currencyCode": "USD",
+			},
+			want: BundlePrice{
+				ProductIDs: []string{"macbookpro", "googlehome", "alexaspeaker"},
+				Price:      domain.MustParseMoney("5500", "USD"),
+			},
+		},
+		{
+			name: "should report invalid parameters",
+			params: map[string]interface{}{
+				"productIds":   []interface{}{"macbookpro", ""},
+				"price":        "0",
+				"currencyCode": "USD",
+			},
+			wantFields: []domain.FieldError{
+				{Field: "parameters.price", Message: "must be more than 0"},
+				{Field: "parameters.productIds[1]", Message: "must be a non-empty string"},
+			},
+		},
+		{
+			name:   "should report missing parameters",
+			params: map[string]interface{}{"productIds": []interface{}{}},
+			wantFields: []domain.FieldError{
+				{Field: "parameters.currencyCode", Message: "is required"},
+				{Field: "parameters.price", Message: "is required"},
+				{Field: "parameters.productIds", Message: "must be a non-empty list"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := DefaultRegistry.New(BundlePriceType, test.params)
+			if test.wantFields != nil {
+				assert.ErrorIs(t, err, domain.ErrInvalidPromotion)
+
+				var validationErr *domain.ValidationError
+				require.True(t, errors.As(err, &validationErr))
+				assert.Equal(t, test.wantFields, validationErr.Fields)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package promotioncondition
+
+import (
+	"sort"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+const BuyNGetMDiscountType = "buy_n_get_m_discount"
+
+// BuyNGetMDiscount gives DiscountInPercent off GetQuantity units of the
+// products GetProducts selects for every BuyQuantity units bought of the
+// products BuyProducts selects, e.g. "buy 2 shirts, get 1 pair of socks
+// free". The cheapest units are the discounted ones.
+//
+// Both sets may overlap, as in "buy 2 shirts, get the cheapest third one
+// half price": a unit is then either bought or discounted, never both.
+// The discount applies as many times as the order allows, at most
+// MaxApplications times unless it is zero.
+type BuyNGetMDiscount struct {
+	BuyProducts       domain.ProductSelector
+	BuyQuantity       int
+	GetProducts       domain.ProductSelector
+	GetQuantity       int
+	DiscountInPercent float64
+	MaxApplications   int
+}
+
+func newBuyNGetMDiscount(params *Parameters) domain.PromotionCondition {
+	percent := params.Float("discountInPercent")
+	if percent <= 0 || percent > 100 {
+		params.Fail("discountInPercent", "must be more than 0 and at most 100")
+	}
+
+	cond := BuyNGetMDiscount{
+		BuyProducts:       productsOrSelector(params, "buyProductIds", "buyProducts"),
+		BuyQuantity:       params.PositiveInt("buyQuantity"),
+		GetProducts:       productsOrSelector(params, "getProductIds", "getProducts"),
+		GetQuantity:       params.PositiveInt("getQuantity"),
+		DiscountInPercent: percent,
+	}
+
+	if params.Has("maxApplications") {
+		cond.MaxApplications = params.PositiveInt("maxApplications")
+	}
+
+	return cond
+}
+
+func (cond BuyNGetMDiscount) ConditionType() string {
+	return BuyNGetMDiscountType
+}
+
+func (cond BuyNGetMDiscount) Parameters() map[string]interface{} {
+	params := map[string]interface{}{
+		"buyQuantity":       cond.BuyQuantity,
+		"getQuantity":       cond.GetQuantity,
+		"discountInPercent": cond.DiscountInPercent,
+	}
+	setProductsOrSelector(params, "buyProductIds", "buyProducts", cond.BuyProducts)
+	setProductsOrSelector(params, "getProductIds", "getProducts", cond.GetProducts)
+	if cond.MaxApplications > 0 {
+		params["maxApplications"] = cond.MaxApplications
+	}
+
+	return params
+}
+
+func (cond BuyNGetMDiscount) CalculateDiscount(order *domain.Order) domain.Money {
+	return domain.SumLineDiscounts(cond.CalculateLineDiscounts(order))
+}
+
+// CalculateLineDiscounts discounts the cheapest units of the get
+// products, GetQuantity of them for every application.
+func (cond BuyNGetMDiscount) CalculateLineDiscounts(order *domain.Order) []domain.LineDiscount {
+	if cond.BuyQuantity <= 0 || cond.GetQuantity <= 0 {
+		return nil
+	}
+
+	// Units are counted by the sets they are in: a unit in both sets can
+	// be bought or discounted, but not both.
+	var buyUnits, getUnits, units int
+	var getLines []*domain.OrderLine
+	for _, line := range order.Lines {
+		inBuy, inGet := cond.BuyProducts.Matches(line), cond.GetProducts.Matches(line)
+		if inBuy {
+			buyUnits += line.Quantity
+		}
+		if inGet {
+			getUnits += line.Quantity
+			getLines = append(getLines, line)
+		}
+		if inBuy || inGet {
+			units += line.Quantity
+		}
+	}
+
+	applications := minInt(buyUnits/cond.BuyQuantity, getUnits/cond.GetQuantity, units/(cond.BuyQuantity+cond.GetQuantity))
+	if cond.MaxApplications > 0 {
+		applications = minInt(applications, cond.MaxApplications)
+	}
+	if applications <= 0 {
+		return nil
+	}
+
+	// A unit in both sets that is discounted can't be bought as well, only
+	// the ones not needed to buy for the applications can be discounted.
+	overlapping := buyUnits - applications*cond.BuyQuantity
+	discounted := applications * cond.GetQuantity
+
+	sort.SliceStable(getLines, func(i, j int) bool {
+		return getLines[i].UnitPrice.Cmp(getLines[j].UnitPrice) < 0
+	})
+
+	var discounts []domain.LineDiscount
+	for _, line := range getLines {
+		if discounted == 0 {
+			break
+		}
+
+		quantity := minInt(line.Quantity, discounted)
+		if cond.BuyProducts.Matches(line) {
+			quantity = minInt(quantity, overlapping)
+			overlapping -= quantity
+		}
+		if quantity == 0 {
+			continue
+		}
+		discounted -= quantity
+
+		discounts = append(discounts, domain.LineDiscount{
+			OrderLineID: line.ID,
+			Quantity:    quantity,
+			Amount:      line.UnitPrice.Mul(quantity).Percent(cond.DiscountInPercent).Neg(),
+		})
+	}
+
+	return discounts
+}
+
+func minInt(values ...int) int {
+	min := values[0]
+	for _, value := range values[1:] {
+		if value < min {
+			min = value
+		}
+	}
+
+	return min
+}
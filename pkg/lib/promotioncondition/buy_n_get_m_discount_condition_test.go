@@ -0,0 +1,209 @@
+package promotioncondition
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+func TestBuyNGetMDiscount_CalculateLineDiscounts(t *testing.T) {
+	line := func(id string, productID string, quantity int, unitPrice string) *domain.OrderLine {
+		return &domain.OrderLine{ID: id, ProductID: productID, Quantity: quantity, UnitPrice: domain.MustParseMoney(unitPrice, "USD")}
+	}
+	// Buy 2 shirts, get 1 pair of socks free.
+	shirtsForSocks := BuyNGetMDiscount{
+		BuyProducts:       domain.SelectProducts("shirt", "polo"),
+		BuyQuantity:       2,
+		GetProducts:       domain.SelectProducts("socks"),
+		GetQuantity:       1,
+		DiscountInPercent: 100,
+	}
+	// Buy 2 clothes, get the cheapest third one half price.
+	clothes := []string{"shirt", "polo", "pants"}
+	thirdHalfPrice := BuyNGetMDiscount{
+		BuyProducts:       domain.SelectProducts(clothes...),
+		BuyQuantity:       2,
+		GetProducts:       domain.SelectProducts(clothes...),
+		GetQuantity:       1,
+		DiscountInPercent: 50,
+	}
+
+	tests := []struct {
+		name      string
+		condition BuyNGetMDiscount
+		lines     []*domain.OrderLine
+		want      []domain.LineDiscount
+	}{
+		{
+			name:      "should discount a get product for every buy quantity",
+			condition: shirtsForSocks,
+			lines:     []*domain.OrderLine{line("line1", "shirt", 3, "30"), line("line2", "polo", 1, "25"), line("line3", "socks", 3, "5")},
+			want: []domain.LineDiscount{
+				{OrderLineID: "line3", Quantity: 2, Amount: domain.MustParseMoney("-10", "USD")},
+			},
+		},
+		{
+			name: "should discount the cheapest get products first",
+			condition: BuyNGetMDiscount{
+				BuyProducts:       domain.SelectProducts("shirt"),
+				BuyQuantity:       1,
+				GetProducts:       domain.SelectProducts("socks", "tie"),
+				GetQuantity:       2,
+				DiscountInPercent: 100,
+			},
+			lines: []*domain.OrderLine{line("line1", "tie", 2, "15"), line("line2", "shirt", 1, "30"), line("line3", "socks", 1, "5")},
+			want: []domain.LineDiscount{
+				{OrderLineID: "line3", Quantity: 1, Amount: domain.MustParseMoney("-5", "USD")},
+				{OrderLineID: "line1", Quantity: 1, Amount: domain.MustParseMoney("-15", "USD")},
+			},
+		},
+		{
+			name:      "should discount the cheapest unit when both sets overlap",
+			condition: thirdHalfPrice,
+			lines:     []*domain.OrderLine{line("line1", "shirt", 2, "30"), line("line2", "pants", 1, "20")},
+			want: []domain.LineDiscount{
+				{OrderLineID: "line2", Quantity: 1, Amount: domain.MustParseMoney("-10", "USD")},
+			},
+		},
+		{
+			name:      "should never discount a unit that is bought",
+			condition: thirdHalfPrice,
+			lines:     []*domain.OrderLine{line("line1", "shirt", 2, "30"), line("line2", "pants", 3, "20"), line("line3", "polo", 2, "25")},
+			want: []domain.LineDiscount{
+				{OrderLineID: "line2", Quantity: 2, Amount: domain.MustParseMoney("-20", "USD")},
+			},
+		},
+		{
+			name:      "should return nothing when overlapping sets miss a unit",
+			condition: thirdHalfPrice,
+			lines:     []*domain.OrderLine{line("line1", "shirt", 1, "30"), line("line2", "pants", 1, "20")},
+		},
+		{
+			name: "should keep enough units to buy when only some get products are bought as well",
+			condition: BuyNGetMDiscount{
+				BuyProducts:       domain.SelectProducts("socks"),
+				BuyQuantity:       1,
+				GetProducts:       domain.SelectProducts("socks", "tie"),
+				GetQuantity:       1,
+				DiscountInPercent: 100,
+			},
+			lines: []*domain.OrderLine{line("line1", "socks", 1, "5"), line("line2", "tie", 1, "15")},
+			want: []domain.LineDiscount{
+				{OrderLineID: "line2", Quantity: 1, Amount: domain.MustParseMoney("-15", "USD")},
+			},
+		},
+		{
+			name: "should apply at most max applications times",
+			condition: BuyNGetMDiscount{
+				BuyProducts:       shirtsForSocks.BuyProducts,
+				BuyQuantity:       2,
+				GetProducts:       shirtsForSocks.GetProducts,
+				GetQuantity:       1,
+				DiscountInPercent: 100,
+				MaxApplications:   1,
+			},
+			lines: []*domain.OrderLine{line("line1", "shirt", 4, "30"), line("line2", "socks", 2, "5")},
+			want: []domain.LineDiscount{
+				{OrderLineID: "line2", Quantity: 1, Amount: domain.MustParseMoney("-5", "USD")},
+			},
+		},
+		{
+			name:      "should return nothing when not enough products are bought",
+			condition: shirtsForSocks,
+			lines:     []*domain.OrderLine{line("line1", "shirt", 1, "30"), line("line2", "socks", 2, "5")},
+		},
+		{
+			name:      "should return nothing when no get product is in the order",
+			condition: shirtsForSocks,
+			lines:     []*domain.OrderLine{line("line1", "shirt", 4, "30")},
+		},
+		{
+			name: "should round the percentage of each line",
+			condition: BuyNGetMDiscount{
+				BuyProducts:       domain.SelectProducts("shirt"),
+				BuyQuantity:       1,
+				GetProducts:       domain.SelectProducts("socks"),
+				GetQuantity:       1,
+				DiscountInPercent: 33.3,
+			},
+			lines: []*domain.OrderLine{line("line1", "shirt", 3, "30"), line("line2", "socks", 3, "4.99")},
+			want: []domain.LineDiscount{
+				{OrderLineID: "line2", Quantity: 3, Amount: domain.MustParseMoney("-4.99", "USD")},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.condition.CalculateLineDiscounts(&domain.Order{Lines: test.lines})
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestNewBuyNGetMDiscount(t *testing.T) {
+	tests := []struct {
+		name       string
+		params     map[string]interface{}
+		want       domain.PromotionCondition
+		wantFields []domain.FieldError
+	}{
+		{
+			name: "should build the condition",
+			params: map[string]interface{}{
+				"buyProductIds":     []interface{}{"shirt", "polo"},
+				"buyQuantity":       2,
+				"getProductIds":     []interface{}{"socks"},
+				"getQuantity":       1,
+				"discountInPercent": 100,
+				"maxApplications":   3,
+			},
+			want: BuyNGetMDiscount{
+				BuyProducts:       domain.SelectProducts("shirt", "polo"),
+				BuyQuantity:       2,
+				GetProducts:       domain.SelectProducts("socks"),
+				GetQuantity:       1,
+				DiscountInPercent: 100,
+				MaxApplications:   3,
+			},
+		},
+		{
+			name: "should report invalid parameters",
+			params: map[string]interface{}{
+				"buyProductIds":     "shirt",
+				"buyQuantity":       0,
+				"getProductIds":     []interface{}{"socks"},
+				"discountInPercent": 150,
+				"maxApplications":   -1,
+			},
+			wantFields: []domain.FieldError{
+				{Field: "parameters.discountInPercent", Message: "must be more than 0 and at most 100"},
+				{Field: "parameters.buyProductIds", Message: "must be a non-empty list"},
+				{Field: "parameters.buyQuantity", Message: "must be a positive integer"},
+				{Field: "parameters.getQuantity", Message: "is required"},
+				{Field: "parameters.maxApplications", Message: "must be a positive integer"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := DefaultRegistry.New(BuyNGetMDiscountType, test.params)
+			if test.wantFields != nil {
+				assert.ErrorIs(t, err, domain.ErrInvalidPromotion)
+
+				var validationErr *domain.ValidationError
+				require.True(t, errors.As(err, &validationErr))
+				assert.Equal(t, test.wantFields, validationErr.Fields)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
@@ -1,32 +1,80 @@
 package promotioncondition
 
-import "github.com/donnpebe/shoppo/pkg/domain"
+import (
+	"sort"
 
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+const BuyXProductGetFreeProductType = "buy_x_product_get_free_product"
+
+// BuyXProductGetFreeProductCondition gives a free product for every X
+// product bought. The X and free products are those of XProductID and
+// FreeProductID or, when they are empty, the ones XProducts and
+// FreeProducts select.
 type BuyXProductGetFreeProductCondition struct {
 	XProductID    string
 	FreeProductID string
+	XProducts     domain.ProductSelector
+	FreeProducts  domain.ProductSelector
 }
 
-func (cond BuyXProductGetFreeProductCondition) CalculateDiscount(order *domain.Order) float64 {
-	promoProductQuantity := 0
-	var freeProductLine *domain.OrderLine
-	for _, line := range order.Lines {
-		if line.ProductID == cond.XProductID {
-			promoProductQuantity += line.Quantity
-		}
+func newBuyXProductGetFreeProductCondition(params *Parameters) domain.PromotionCondition {
+	var cond BuyXProductGetFreeProductCondition
+	cond.XProductID, cond.XProducts = productOrSelector(params, "xProductId", "xProducts")
+	cond.FreeProductID, cond.FreeProducts = productOrSelector(params, "freeProductId", "freeProducts")
 
-		if line.ProductID == cond.FreeProductID {
-			freeProductLine = line
-		}
+	return cond
+}
+
+func (cond BuyXProductGetFreeProductCondition) ConditionType() string {
+	return BuyXProductGetFreeProductType
+}
+
+func (cond BuyXProductGetFreeProductCondition) Parameters() map[string]interface{} {
+	params := make(map[string]interface{})
+	setProductOrSelector(params, "xProductId", cond.XProductID, "xProducts", cond.XProducts)
+	setProductOrSelector(params, "freeProductId", cond.FreeProductID, "freeProducts", cond.FreeProducts)
+
+	return params
+}
+
+func (cond BuyXProductGetFreeProductCondition) CalculateDiscount(order *domain.Order) domain.Money {
+	return domain.SumLineDiscounts(cond.CalculateLineDiscounts(order))
+}
+
+// CalculateLineDiscounts makes one unit of a free product free for every
+// unit of an X product, the cheapest free products first.
+func (cond BuyXProductGetFreeProductCondition) CalculateLineDiscounts(order *domain.Order) []domain.LineDiscount {
+	promoProductQuantity := 0
+	for _, line := range targetedLines(order, cond.XProductID, cond.XProducts) {
+		promoProductQuantity += line.Quantity
 	}
 
-	if freeProductLine == nil {
-		return 0
+	freeProductLines := targetedLines(order, cond.FreeProductID, cond.FreeProducts)
+	if len(freeProductLines) == 0 || promoProductQuantity == 0 {
+		return nil
 	}
 
-	if freeProductLine.Quantity >= promoProductQuantity {
-		return -float64(promoProductQuantity) * freeProductLine.UnitPrice
+	sort.SliceStable(freeProductLines, func(i, j int) bool {
+		return freeProductLines[i].UnitPrice.Cmp(freeProductLines[j].UnitPrice) < 0
+	})
+
+	var discounts []domain.LineDiscount
+	for _, line := range freeProductLines {
+		if promoProductQuantity == 0 {
+			break
+		}
+
+		freeQuantity := minInt(line.Quantity, promoProductQuantity)
+		promoProductQuantity -= freeQuantity
+
+		discounts = append(discounts, domain.LineDiscount{
+			OrderLineID: line.ID,
+			Quantity:    freeQuantity,
+			Amount:      line.UnitPrice.Mul(freeQuantity).Neg(),
+		})
 	}
 
-	return -float64(freeProductLine.Quantity) * freeProductLine.UnitPrice
+	return discounts
 }
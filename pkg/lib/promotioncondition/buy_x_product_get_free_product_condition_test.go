@@ -12,7 +12,7 @@ func TestBuyXProductGetFreeProductCondition_CalculateDiscount(t *testing.T) {
 	tests := []struct {
 		name  string
 		input *domain.Order
-		want  float64
+		want  domain.Money
 	}{
 		{
 			name: "should return correct discount amount",
@@ -22,17 +22,17 @@ func TestBuyXProductGetFreeProductCondition_CalculateDiscount(t *testing.T) {
 						ID:        "line1",
 						ProductID: "p02",
 						Quantity:  1,
-						UnitPrice: 5399.99,
+						UnitPrice: domain.MustParseMoney("5399.99", "USD"),
 					},
 					{
 						ID:        "line2",
 						ProductID: "p04",
 						Quantity:  1,
-						UnitPrice: 30.0,
+						UnitPrice: domain.MustParseMoney("30", "USD"),
 					},
 				},
 			},
-			want: -30.0,
+			want: domain.MustParseMoney("-30", "USD"),
 		},
 		{
 			name: "should return zero discount if cannot find XProduct id in order",
@@ -42,17 +42,17 @@ func TestBuyXProductGetFreeProductCondition_CalculateDiscount(t *testing.T) {
 						ID:        "line1",
 						ProductID: "p03",
 						Quantity:  1,
-						UnitPrice: 109.50,
+						UnitPrice: domain.MustParseMoney("109.50", "USD"),
 					},
 					{
 						ID:        "line2",
 						ProductID: "p04",
 						Quantity:  1,
-						UnitPrice: 30.0,
+						UnitPrice: domain.MustParseMoney("30", "USD"),
 					},
 				},
 			},
-			want: 0.0,
+			want: domain.Money{},
 		},
 		{
 			name: "should return zero discount if free product line not included",
@@ -62,11 +62,11 @@ func TestBuyXProductGetFreeProductCondition_CalculateDiscount(t *testing.T) {
 						ID:        "line1",
 						ProductID: "p02",
 						Quantity:  1,
-						UnitPrice: 5399.99,
+						UnitPrice: domain.MustParseMoney("5399.99", "USD"),
 					},
 				},
 			},
-			want: 0,
+			want: domain.Money{},
 		},
 	}
 
@@ -82,3 +82,17 @@ func TestBuyXProductGetFreeProductCondition_CalculateDiscount(t *testing.T) {
 		})
 	}
 }
+
+func TestBuyXProductGetFreeProductCondition_CalculateLineDiscounts(t *testing.T) {
+	order := &domain.Order{
+		Lines: []*domain.OrderLine{
+			{ID: "line1", ProductID: "p02", Quantity: 2, UnitPrice: domain.MustParseMoney("5399.99", "USD")},
+			{ID: "line2", ProductID: "p04", Quantity: 3, UnitPrice: domain.MustParseMoney("30", "USD")},
+		},
+	}
+
+	sut := BuyXProductGetFreeProductCondition{XProductID: "p02", FreeProductID: "p04"}
+
+	got := sut.CalculateLineDiscounts(order)
+	assert.Equal(t, []domain.LineDiscount{{OrderLineID: "line2", Quantity: 2, Amount: domain.MustParseMoney("-60", "USD")}}, got)
+}
@@ -0,0 +1,167 @@
+package promotioncondition
+
+import "github.com/donnpebe/shoppo/pkg/domain"
+
+const CartThresholdDiscountType = "cart_threshold_discount"
+
+// CartThresholdTier gives a discount once the order reaches MinSubtotal,
+// either Amount off the order or DiscountInPercent of it.
+type CartThresholdTier struct {
+	MinSubtotal       domain.Money
+	Amount            domain.Money
+	DiscountInPercent float64
+}
+
+// CartThresholdDiscount discounts the whole order once it reaches a
+// threshold, e.g. "spend 500 get 50 off" or "spend 1000 get 10% off". Of
+// its tiers, the one with the highest threshold reached applies.
+//
+// The order is measured after the discounts of product promotions, a
+// discounted line counts for what is left to pay for it. When
+// ExcludeDiscountedLines is set, discounted lines count neither towards
+// the threshold nor for the discount.
+type CartThresholdDiscount struct {
+	Tiers                  []CartThresholdTier
+	ExcludeDiscountedLines bool
+}
+
+func newCartThresholdDiscount(params *Parameters) domain.PromotionCondition {
+	currency := params.Currency("currencyCode")
+
+	var cond CartThresholdDiscount
+	thresholds := make(map[domain.Money]bool)
+	for _, tierParams := range params.Objects("tiers") {
+		tier := CartThresholdTier{MinSubtotal: tierParams.Money("minSubtotal", currency)}
+		switch {
+		case tier.MinSubtotal.IsNegative():
+			tierParams.Fail("minSubtotal", "must not be negative")
+		case thresholds[tier.MinSubtotal]:
+			tierParams.Fail("minSubtotal", "is already the threshold of another tier")
+		}
+		thresholds[tier.MinSubtotal] = true
+
+		hasAmount, hasPercent := tierParams.Has("discountAmount"), tierParams.Has("discountInPercent")
+		switch {
+		case hasAmount && hasPercent, !hasAmount && !hasPercent:
+			tierParams.Fail("discountAmount", "either discountAmount or discountInPercent is required")
+		case hasAmount:
+			tier.Amount = tierParams.Money("discountAmount", currency)
+			if tier.Amount.Currency != "" && tier.Amount.Amount <= 0 {
+				tierParams.Fail("discountAmount", "must be more than 0")
+			}
+		default:
+			tier.DiscountInPercent = tierParams.Float("discountInPercent")
+			if tier.DiscountInPercent <= 0 || tier.DiscountInPercent > 100 {
+				tierParams.Fail("discountInPercent", "must be more than 0 and at most 100")
+			}
+		}
+
+		cond.Tiers = append(cond.Tiers, tier)
+	}
+
+	if params.Has("excludeDiscountedLines") {
+		cond.ExcludeDiscountedLines = params.Bool("excludeDiscountedLines")
+	}
+
+	return cond
+}
+
+func (cond CartThresholdDiscount) ConditionType() string {
+	return CartThresholdDiscountType
+}
+
+func (cond CartThresholdDiscount) Parameters() map[string]interface{} {
+	tiers := make([]interface{}, 0, len(cond.Tiers))
+	for _, tier := range cond.Tiers {
+		params := map[string]interface{}{"minSubtotal": tier.MinSubtotal.Decimal()}
+		if tier.DiscountInPercent > 0 {
+			params["discountInPercent"] = tier.DiscountInPercent
+		} else {
+			params["discountAmount"] = tier.Amount.Decimal()
+		}
+		tiers = append(tiers, params)
+	}
+
+	return map[string]interface{}{
+		"currencyCode":           cond.currency(),
+		"tiers":                  tiers,
+		"excludeDiscountedLines": cond.ExcludeDiscountedLines,
+	}
+}
+
+func (cond CartThresholdDiscount) CalculateDiscount(order *domain.Order) domain.Money {
+	return domain.SumLineDiscounts(cond.CalculateCartDiscounts(order, nil))
+}
+
+// CalculateLineDiscounts is the discount of an order no other promotion
+// discounted.
+func (cond CartThresholdDiscount) CalculateLineDiscounts(order *domain.Order) []domain.LineDiscount {
+	return cond.CalculateCartDiscounts(order, nil)
+}
+
+// CalculateCartDiscounts spreads the discount of the tier the order
+// reaches over the lines that count, in proportion to what is left to pay
+// for them.
+func (cond CartThresholdDiscount) CalculateCartDiscounts(order *domain.Order, lineDiscounts map[string]domain.Money) []domain.LineDiscount {
+	currency := cond.currency()
+	left := func(line *domain.OrderLine) domain.Money {
+		return line.Subtotal().Add(lineDiscounts[line.ID])
+	}
+
+	var lines []*domain.OrderLine
+	var subtotal domain.Money
+	for _, line := range order.Lines {
+		// Orders are in a single currency, the tiers don't apply to
+		// orders in another one.
+		if line.UnitPrice.Currency != currency {
+			return nil
+		}
+
+		if cond.ExcludeDiscountedLines && !lineDiscounts[line.ID].IsZero() {
+			continue
+		}
+
+		lines = append(lines, line)
+		subtotal = subtotal.Add(left(line))
+	}
+
+	tier, ok := cond.tier(subtotal)
+	if !ok || subtotal.Amount <= 0 {
+		return nil
+	}
+
+	discount := tier.Amount.Neg()
+	if tier.DiscountInPercent > 0 {
+		discount = subtotal.Percent(tier.DiscountInPercent).Neg()
+	}
+	if discount.Neg().Cmp(subtotal) > 0 {
+		discount = subtotal.Neg()
+	}
+
+	return domain.SpreadDiscount(discount, lines, left)
+}
+
+// tier returns the tier of the highest threshold subtotal reaches.
+func (cond CartThresholdDiscount) tier(subtotal domain.Money) (CartThresholdTier, bool) {
+	var reached CartThresholdTier
+	found := false
+	for _, tier := range cond.Tiers {
+		if subtotal.Cmp(tier.MinSubtotal) < 0 {
+			continue
+		}
+
+		if !found || tier.MinSubtotal.Cmp(reached.MinSubtotal) > 0 {
+			reached, found = tier, true
+		}
+	}
+
+	return reached, found
+}
+
+func (cond CartThresholdDiscount) currency() string {
+	if len(cond.Tiers) == 0 {
+		return ""
+	}
+
+	return cond.Tiers[0].MinSubtotal.Currency
+}
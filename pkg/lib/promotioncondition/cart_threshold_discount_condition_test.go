@@ -0,0 +1,190 @@
+package promotioncondition
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+func TestCartThresholdDiscount_CalculateCartDiscounts(t *testing.T) {
+	order := &domain.Order{
+		Lines: []*domain.OrderLine{
+			{ID: "line1", ProductID: "p01", Quantity: 3, UnitPrice: domain.MustParseMoney("100", "USD")},
+			{ID: "line2", ProductID: "p02", Quantity: 1, UnitPrice: domain.MustParseMoney("300", "USD")},
+		},
+	}
+	tiers := []CartThresholdTier{
+		{MinSubtotal: domain.MustParseMoney("500", "USD"), Amount: domain.MustParseMoney("50", "USD")},
+		{MinSubtotal: domain.MustParseMoney("1000", "USD"), DiscountInPercent: 10},
+	}
+
+	tests := []struct {
+		name          string
+		condition     CartThresholdDiscount
+		order         *domain.Order
+		lineDiscounts map[string]domain.Money
+		want          []domain.LineDiscount
+	}{
+		{
+			name:      "should spread the amount off over the lines",
+			condition: CartThresholdDiscount{Tiers: tiers},
+			order:     order,
+			want: []domain.LineDiscount{
+				{OrderLineID: "line1", Quantity: 3, Amount: domain.MustParseMoney("-25", "USD")},
+				{OrderLineID: "line2", Quantity: 1, Amount: domain.MustParseMoney("-25", "USD")},
+			},
+		},
+		{
+			name:      "should apply the tier of the highest threshold reached",
+			condition: CartThresholdDiscount{Tiers: tiers},
+			order: &domain.Order{
+				Lines: []*domain.OrderLine{
+					{ID: "line1", ProductID: "p01", Quantity: 12, UnitPrice: domain.MustParseMoney("100", "USD")},
+				},
+			},
+			want: []domain.LineDiscount{
+				{OrderLineID: "line1", Quantity: 12, Amount: domain.MustParseMoney("-120", "USD")},
+			},
+		},
+		{
+			name:          "should measure the order after the discounts of other promotions",
+			condition:     CartThresholdDiscount{Tiers: tiers},
+			order:         order,
+			lineDiscounts: map[string]domain.Money{"line1": domain.MustParseMoney("-100.01", "USD")},
+		},
+		{
+			name:      "should leave discounted lines out when they don't count",
+			condition: CartThresholdDiscount{Tiers: tiers, ExcludeDiscountedLines: true},
+			order: &domain.Order{
+				Lines: []*domain.OrderLine{
+					{ID: "line1", ProductID: "p01", Quantity: 3, UnitPrice: domain.MustParseMoney("100", "USD")},
+					{ID: "line2", ProductID: "p02", Quantity: 1, UnitPrice: domain.MustParseMoney("500", "USD")},
+				},
+			},
+			lineDiscounts: map[string]domain.Money{"line1": domain.MustParseMoney("-10", "USD")},
+			want: []domain.LineDiscount{
+				{OrderLineID: "line2", Quantity: 1, Amount: domain.MustParseMoney("-50", "USD")},
+			},
+		},
+		{
+			name: "should never discount more than the order",
+			condition: CartThresholdDiscount{Tiers: []CartThresholdTier{
+				{MinSubtotal: domain.MustParseMoney("0", "USD"), Amount: domain.MustParseMoney("1000", "USD")},
+			}},
+			order: order,
+			want: []domain.LineDiscount{
+				{OrderLineID: "line1", Quantity: 3, Amount: domain.MustParseMoney("-300", "USD")},
+				{OrderLineID: "line2", Quantity: 1, Amount: domain.MustParseMoney("-300", "USD")},
+			},
+		},
+		{
+			name:      "should return nothing below the lowest threshold",
+			condition: CartThresholdDiscount{Tiers: tiers},
+			order: &domain.Order{
+				Lines: []*domain.OrderLine{
+					{ID: "line1", ProductID: "p01", Quantity: 4, UnitPrice: domain.MustParseMoney("124.99", "USD")},
+				},
+			},
+		},
+		{
+			name:      "should return nothing for an order in another currency",
+			condition: CartThresholdDiscount{Tiers: tiers},
+			order: &domain.Order{
+				Lines: []*domain.OrderLine{
+					{ID: "line1", ProductID: "p01", Quantity: 1, UnitPrice: domain.MustParseMoney("1000000", "IDR")},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.condition.CalculateCartDiscounts(test.order, test.lineDiscounts)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestNewCartThresholdDiscount(t *testing.T) {
+	tests := []struct {
+		name       string
+		params     map[string]interface{}
+		want       domain.PromotionCondition
+		wantFields []domain.FieldError
+	}{
+		{
+			name: "should build tiers from numbers and decimal strings",
+			params: map[string]interface{}{
+				"currencyCode": "USD",
+				"tiers": []interface{}{
+					map[string]interface{}{"minSubtotal": 500, "discountAmount": "50"},
+					map[string]interface{}{"minSubtotal": 1000.5, "discountInPercent": 10},
+				},
+				"excludeDiscountedLines": true,
+			},
+			want: CartThresholdDiscount{
+				Tiers: []CartThresholdTier{
+					{MinSubtotal: domain.MustParseMoney("500", "USD"), Amount: domain.MustParseMoney("50", "USD")},
+					{MinSubtotal: domain.MustParseMoney("1000.50", "USD"), DiscountInPercent: 10},
+				},
+				ExcludeDiscountedLines: true,
+			},
+		},
+		{
+			name: "should report the invalid parameters of each tier",
+			params: map[string]interface{}{
+				"currencyCode": "USD",
+				"tiers": []interface{}{
+					map[string]interface{}{"minSubtotal": 500, "discountAmount": 50, "discountInPercent": 10},
+					map[string]interface{}{"minSubtotal": 500, "discountAmount": 0.001},
+					"free shipping",
+					map[string]interface{}{"minsubtotal": 100, "discountInPercent": 110},
+				},
+				"excludeDiscountedLines": "yes",
+			},
+			wantFields: []domain.FieldError{
+				{Field: "parameters.tiers[2]", Message: "must be an object"},
+				{Field: "parameters.tiers[0].discountAmount", Message: "either discountAmount or discountInPercent is required"},
+				{Field: "parameters.tiers[1].minSubtotal", Message: "is already the threshold of another tier"},
+				{Field: "parameters.tiers[1].discountAmount", Message: "must be an amount of USD"},
+				{Field: "parameters.tiers[3].minSubtotal", Message: "is required"},
+				{Field: "parameters.tiers[3].discountInPercent", Message: "must be more than 0 and at most 100"},
+				{Field: "parameters.excludeDiscountedLines", Message: "must be a boolean"},
+				{Field: "parameters.tiers[3].minsubtotal", Message: `unknown parameter "minsubtotal"`},
+			},
+		},
+		{
+			name: "should report unknown currencies",
+			params: map[string]interface{}{
+				"currencyCode": "XYZ",
+				"tiers": []interface{}{
+					map[string]interface{}{"minSubtotal": 500, "discountAmount": 50},
+				},
+			},
+			wantFields: []domain.FieldError{
+				{Field: "parameters.currencyCode", Message: `unknown currency "XYZ"`},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := DefaultRegistry.New(CartThresholdDiscountType, test.params)
+			if test.wantFields != nil {
+				assert.ErrorIs(t, err, domain.ErrInvalidPromotion)
+
+				var validationErr *domain.ValidationError
+				require.True(t, errors.As(err, &validationErr))
+				assert.Equal(t, test.wantFields, validationErr.Fields)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
@@ -35,10 +35,10 @@ func (m *MockPromotionCondition) EXPECT() *MockPromotionConditionMockRecorder {
 }
 
 // CalculateDiscount mocks base method.
-func (m *MockPromotionCondition) CalculateDiscount(arg0 *domain.Order) float64 {
+func (m *MockPromotionCondition) CalculateDiscount(arg0 *domain.Order) domain.Money {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CalculateDiscount", arg0)
-	ret0, _ := ret[0].(float64)
+	ret0, _ := ret[0].(domain.Money)
 	return ret0
 }
 
@@ -0,0 +1,334 @@
+package promotioncondition
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+// Parameters are the parameters a condition is built from, as decoded
+// from JSON or YAML. Reading a parameter records why it is invalid, so
+// every invalid parameter is reported at once.
+type Parameters struct {
+	values map[string]interface{}
+	used   map[string]bool
+	fields []domain.FieldError
+
+	// parent and prefix are set on the parameters of an object nested in
+	// a list, see Objects.
+	parent   *Parameters
+	prefix   string
+	children []*Parameters
+}
+
+func newParameters(values map[string]interface{}) *Parameters {
+	return &Parameters{values: values, used: make(map[string]bool, len(values))}
+}
+
+// Fail reports the parameter name as invalid. Only the first reason is
+// kept, a value that isn't a number is not also reported out of range.
+func (params *Parameters) Fail(name string, message string) {
+	if params.parent != nil {
+		params.parent.Fail(params.prefix+"."+name, message)
+		return
+	}
+
+	field := "parameters." + name
+	for _, failed := range params.fields {
+		if failed.Field == field {
+			return
+		}
+	}
+
+	params.fields = append(params.fields, domain.FieldError{Field: field, Message: message})
+}
+
+// Has tells whether the parameter name is set, optional parameters are
+// only read when they are.
+func (params *Parameters) Has(name string) bool {
+	params.used[name] = true
+
+	value, ok := params.values[name]
+	return ok && value != nil
+}
+
+// lookup returns the value of the parameter, failing when it is missing.
+func (params *Parameters) lookup(name string) (interface{}, bool) {
+	if !params.Has(name) {
+		params.Fail(name, "is required")
+		return nil, false
+	}
+
+	return params.values[name], true
+}
+
+// String returns the parameter name, which must be a non-empty string.
+func (params *Parameters) String(name string) string {
+	value, ok := params.lookup(name)
+	if !ok {
+		return ""
+	}
+
+	s, ok := value.(string)
+	if !ok || s == "" {
+		params.Fail(name, "must be a non-empty string")
+		return ""
+	}
+
+	return s
+}
+
+// Strings returns the parameter name, which must be a non-empty list of
+// non-empty strings.
+func (params *Parameters) Strings(name string) []string {
+	value, ok := params.lookup(name)
+	if !ok {
+		return nil
+	}
+
+	list, ok := value.([]interface{})
+	if !ok || len(list) == 0 {
+		params.Fail(name, "must be a non-empty list")
+		return nil
+	}
+
+	strings := make([]string, 0, len(list))
+	for i, item := range list {
+		s, ok := item.(string)
+		if !ok || s == "" {
+			params.Fail(fmt.Sprintf("%s[%d]", name, i), "must be a non-empty string")
+			continue
+		}
+
+		strings = append(strings, s)
+	}
+
+	return strings
+}
+
+// stringList returns values the way lists are decoded from JSON or YAML.
+func stringList(values []string) []interface{} {
+	list := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		list = append(list, value)
+	}
+
+	return list
+}
+
+// Float returns the parameter name, which must be a finite number.
+func (params *Parameters) Float(name string) float64 {
+	value, ok := params.lookup(name)
+	if !ok {
+		return 0
+	}
+
+	// JSON numbers are decoded as float64 and YAML integers as int, YAML
+	// also decodes .nan and .inf as float64.
+	switch n := value.(type) {
+	case float64:
+		if math.IsNaN(n) || math.IsInf(n, 0) {
+			params.Fail(name, "must be a finite number")
+			return 0
+		}
+		return n
+	case int:
+		return float64(n)
+	}
+
+	params.Fail(name, "must be a number")
+	return 0
+}
+
+// PositiveInt returns the parameter name, which must be an integer more
+// than zero.
+func (params *Parameters) PositiveInt(name string) int {
+	value, ok := params.lookup(name)
+	if !ok {
+		return 0
+	}
+
+	var n int
+	switch v := value.(type) {
+	case int:
+		n = v
+	case float64:
+		if math.IsInf(v, 0) || v != math.Trunc(v) || v > math.MaxInt32 {
+			params.Fail(name, "must be a positive integer")
+			return 0
+		}
+		n = int(v)
+	default:
+		params.Fail(name, "must be a positive integer")
+		return 0
+	}
+
+	if n <= 0 {
+		params.Fail(name, "must be a positive integer")
+		return 0
+	}
+
+	return n
+}
+
+// Bool returns the parameter name, which must be a boolean.
+func (params *Parameters) Bool(name string) bool {
+	value, ok := params.lookup(name)
+	if !ok {
+		return false
+	}
+
+	b, ok := value.(bool)
+	if !ok {
+		params.Fail(name, "must be a boolean")
+	}
+
+	return b
+}
+
+// Money returns the parameter name as money of currency, it must be a
+// number or a decimal string such as "49.99". Nothing is reported when
+// currency is empty, the currency parameter itself should have failed.
+func (params *Parameters) Money(name string, currency string) domain.Money {
+	value, ok := params.lookup(name)
+	if !ok || currency == "" {
+		return domain.Money{}
+	}
+
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		s = strconv.Itoa(v)
+	default:
+		params.Fail(name, "must be an amount")
+		return domain.Money{}
+	}
+
+	money, err := domain.ParseMoney(s, currency)
+	if err != nil {
+		params.Fail(name, fmt.Sprintf("must be an amount of %s", currency))
+		return domain.Money{}
+	}
+
+	return money
+}
+
+// Currency returns the parameter name, which must be the code of a known
+// currency.
+func (params *Parameters) Currency(name string) string {
+	code := params.String(name)
+	if code == "" {
+		return ""
+	}
+
+	if _, err := domain.LookupCurrency(code); err != nil {
+		params.Fail(name, fmt.Sprintf("unknown currency %q", code))
+		return ""
+	}
+
+	return code
+}
+
+// Object returns the parameter name, which must be an object read as
+// parameters of its own, see Objects.
+func (params *Parameters) Object(name string) *Parameters {
+	value, ok := params.lookup(name)
+	if !ok {
+		return nil
+	}
+
+	values, ok := value.(map[string]interface{})
+	if !ok {
+		params.Fail(name, "must be an object")
+		return nil
+	}
+
+	return params.child(name, values)
+}
+
+// Objects returns the parameter name, which must be a non-empty list of
+// objects. Each object is read as parameters of its own, their invalid
+// parameters are reported as e.g. "parameters.tiers[0].minSubtotal".
+func (params *Parameters) Objects(name string) []*Parameters {
+	value, ok := params.lookup(name)
+	if !ok {
+		return nil
+	}
+
+	list, ok := value.([]interface{})
+	if !ok || len(list) == 0 {
+		params.Fail(name, "must be a non-empty list")
+		return nil
+	}
+
+	objects := make([]*Parameters, 0, len(list))
+	for i, item := range list {
+		values, ok := item.(map[string]interface{})
+		if !ok {
+			params.Fail(fmt.Sprintf("%s[%d]", name, i), "must be an object")
+			continue
+		}
+
+		objects = append(objects, params.child(fmt.Sprintf("%s[%d]", name, i), values))
+	}
+
+	return objects
+}
+
+func (params *Parameters) child(prefix string, values map[string]interface{}) *Parameters {
+	child := newParameters(values)
+	child.parent = params
+	child.prefix = prefix
+	params.children = append(params.children, child)
+
+	return child
+}
+
+// Time returns the parameter name, which must be an RFC 3339 timestamp.
+func (params *Parameters) Time(name string) time.Time {
+	value, ok := params.lookup(name)
+	if !ok {
+		return time.Time{}
+	}
+
+	// YAML decodes timestamps while JSON leaves them as strings.
+	switch v := value.(type) {
+	case time.Time:
+		return v
+	case string:
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t
+		}
+	}
+
+	params.Fail(name, "must be an RFC 3339 timestamp")
+	return time.Time{}
+}
+
+// failUnused fails the parameters the condition doesn't know, they are
+// most likely misspelled.
+func (params *Parameters) failUnused() {
+	for _, child := range params.children {
+		child.failUnused()
+	}
+
+	var unused []string
+	for name := range params.values {
+		if !params.used[name] {
+			unused = append(unused, name)
+		}
+	}
+	sort.Strings(unused)
+
+	for _, name := range unused {
+		params.Fail(name, fmt.Sprintf("unknown parameter %q", name))
+	}
+}
@@ -2,18 +2,73 @@ package promotioncondition
 
 import "github.com/donnpebe/shoppo/pkg/domain"
 
+const ProductPercentageDiscountType = "product_percentage_discount"
+
+// ProductPercentageDiscount discounts a product by DiscountInPercent when
+// at least MinQuantity units of it and its variants are bought. It
+// targets the product of ProductID or, when it is empty, every product
+// Products selects, each on its own.
 type ProductPercentageDiscount struct {
 	ProductID         string
+	Products          domain.ProductSelector
 	MinQuantity       int
 	DiscountInPercent float64
 }
 
-func (cond ProductPercentageDiscount) CalculateDiscount(order *domain.Order) float64 {
-	for _, line := range order.Lines {
-		if line.ProductID == cond.ProductID && line.Quantity >= cond.MinQuantity {
-			return -line.UnitPrice * float64(line.Quantity) * (cond.DiscountInPercent / 100)
+func newProductPercentageDiscount(params *Parameters) domain.PromotionCondition {
+	percent := params.Float("discountInPercent")
+	if percent <= 0 || percent > 100 {
+		params.Fail("discountInPercent", "must be more than 0 and at most 100")
+	}
+
+	productID, products := productOrSelector(params, "productId", "products")
+	return ProductPercentageDiscount{
+		ProductID:         productID,
+		Products:          products,
+		MinQuantity:       params.PositiveInt("minQuantity"),
+		DiscountInPercent: percent,
+	}
+}
+
+func (cond ProductPercentageDiscount) ConditionType() string {
+	return ProductPercentageDiscountType
+}
+
+func (cond ProductPercentageDiscount) Parameters() map[string]interface{} {
+	params := map[string]interface{}{
+		"minQuantity":       cond.MinQuantity,
+		"discountInPercent": cond.DiscountInPercent,
+	}
+	setProductOrSelector(params, "productId", cond.ProductID, "products", cond.Products)
+
+	return params
+}
+
+func (cond ProductPercentageDiscount) CalculateDiscount(order *domain.Order) domain.Money {
+	return domain.SumLineDiscounts(cond.CalculateLineDiscounts(order))
+}
+
+// CalculateLineDiscounts discounts every unit of the lines of the
+// targeted products whose units, variants included, add up to
+// MinQuantity.
+func (cond ProductPercentageDiscount) CalculateLineDiscounts(order *domain.Order) []domain.LineDiscount {
+	targeted := targetedLines(order, cond.ProductID, cond.Products)
+
+	units := make(map[string]int)
+	for _, line := range targeted {
+		units[parentProductID(line)] += line.Quantity
+	}
+
+	var discounts []domain.LineDiscount
+	for _, line := range targeted {
+		if units[parentProductID(line)] >= cond.MinQuantity {
+			discounts = append(discounts, domain.LineDiscount{
+				OrderLineID: line.ID,
+				Quantity:    line.Quantity,
+				Amount:      line.Subtotal().Percent(cond.DiscountInPercent).Neg(),
+			})
 		}
 	}
 
-	return 0
+	return discounts
 }
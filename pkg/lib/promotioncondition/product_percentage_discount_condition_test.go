@@ -12,7 +12,7 @@ func TestProductPercentageDiscountCondition_CalculateDiscount(t *testing.T) {
 	tests := []struct {
 		name  string
 		input *domain.Order
-		want  float64
+		want  domain.Money
 	}{
 		{
 			name: "should return correct discount amount",
@@ -22,11 +22,11 @@ func TestProductPercentageDiscountCondition_CalculateDiscount(t *testing.T) {
 						ID:        "line1",
 						ProductID: "p03",
 						Quantity:  3,
-						UnitPrice: 109.5,
+						UnitPrice: domain.MustParseMoney("109.50", "USD"),
 					},
 				},
 			},
-			want: -32.85,
+			want: domain.MustParseMoney("-32.85", "USD"),
 		},
 		{
 			name: "should return zero if quantity in order less than required min quantity",
@@ -36,11 +36,21 @@ func TestProductPercentageDiscountCondition_CalculateDiscount(t *testing.T) {
 						ID:        "line1",
 						ProductID: "p03",
 						Quantity:  2,
-						UnitPrice: 109.5,
+						UnitPrice: domain.MustParseMoney("109.50", "USD"),
 					},
 				},
 			},
-			want: 0,
+			want: domain.Money{},
+		},
+		{
+			name: "should count the variants of the product together",
+			input: &domain.Order{
+				Lines: []*domain.OrderLine{
+					{ID: "line1", ProductID: "p03-s", ParentProductID: "p03", Quantity: 2, UnitPrice: domain.MustParseMoney("20", "USD")},
+					{ID: "line2", ProductID: "p03-m", ParentProductID: "p03", Quantity: 1, UnitPrice: domain.MustParseMoney("20", "USD")},
+				},
+			},
+			want: domain.MustParseMoney("-6", "USD"),
 		},
 		{
 			name: "should return zero if product id not found in order",
@@ -50,11 +60,11 @@ func TestProductPercentageDiscountCondition_CalculateDiscount(t *testing.T) {
 						ID:        "line1",
 						ProductID: "p02",
 						Quantity:  3,
-						UnitPrice: 5399.99,
+						UnitPrice: domain.MustParseMoney("5399.99", "USD"),
 					},
 				},
 			},
-			want: 0,
+			want: domain.Money{},
 		},
 	}
 
@@ -1,21 +1,103 @@
 package promotioncondition
 
-import "github.com/donnpebe/shoppo/pkg/domain"
+import (
+	"sort"
 
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+const ProductQuantityDiscountType = "product_quantity_discount"
+
+// ProductQuantityDiscount discounts DiscountedQuantity units of a product
+// for every RequiredQuantity units of it, e.g. "3 for the price of 2". It
+// targets the product of ProductID or, when it is empty, every product
+// Products selects, each on its own. The units of the variants of a
+// product are counted together, so they can be mixed, and the cheapest
+// ones are the discounted ones.
 type ProductQuantityDiscount struct {
 	ProductID          string
+	Products           domain.ProductSelector
 	RequiredQuantity   int
 	DiscountedQuantity int
 }
 
-// Applies to multiples of required quantity too
-func (cond ProductQuantityDiscount) CalculateDiscount(order *domain.Order) float64 {
-	for _, line := range order.Lines {
-		if line.ProductID == cond.ProductID {
-			multiplier := line.Quantity / cond.RequiredQuantity
-			return -float64(multiplier*cond.DiscountedQuantity) * float64(line.UnitPrice)
+func newProductQuantityDiscount(params *Parameters) domain.PromotionCondition {
+	requiredQuantity := params.PositiveInt("requiredQuantity")
+	discountedQuantity := params.PositiveInt("discountedQuantity")
+	if discountedQuantity > requiredQuantity {
+		params.Fail("discountedQuantity", "must not be more than requiredQuantity")
+	}
+
+	productID, products := productOrSelector(params, "productId", "products")
+	return ProductQuantityDiscount{
+		ProductID:          productID,
+		Products:           products,
+		RequiredQuantity:   requiredQuantity,
+		DiscountedQuantity: discountedQuantity,
+	}
+}
+
+func (cond ProductQuantityDiscount) ConditionType() string {
+	return ProductQuantityDiscountType
+}
+
+func (cond ProductQuantityDiscount) Parameters() map[string]interface{} {
+	params := map[string]interface{}{
+		"requiredQuantity":   cond.RequiredQuantity,
+		"discountedQuantity": cond.DiscountedQuantity,
+	}
+	setProductOrSelector(params, "productId", cond.ProductID, "products", cond.Products)
+
+	return params
+}
+
+func (cond ProductQuantityDiscount) CalculateDiscount(order *domain.Order) domain.Money {
+	return domain.SumLineDiscounts(cond.CalculateLineDiscounts(order))
+}
+
+// CalculateLineDiscounts discounts the cheapest units of each targeted
+// product, DiscountedQuantity of them for every RequiredQuantity units of
+// the product and its variants.
+func (cond ProductQuantityDiscount) CalculateLineDiscounts(order *domain.Order) []domain.LineDiscount {
+	if cond.RequiredQuantity <= 0 {
+		return nil
+	}
+
+	// Lines are grouped by product, in the order the products come in.
+	var products []string
+	lines := make(map[string][]*domain.OrderLine)
+	for _, line := range targetedLines(order, cond.ProductID, cond.Products) {
+		productID := parentProductID(line)
+		if _, ok := lines[productID]; !ok {
+			products = append(products, productID)
+		}
+		lines[productID] = append(lines[productID], line)
+	}
+
+	var discounts []domain.LineDiscount
+	for _, productID := range products {
+		variants := lines[productID]
+		units := 0
+		for _, line := range variants {
+			units += line.Quantity
+		}
+		discounted := units / cond.RequiredQuantity * cond.DiscountedQuantity
+
+		sort.SliceStable(variants, func(i, j int) bool {
+			return variants[i].UnitPrice.Cmp(variants[j].UnitPrice) < 0
+		})
+
+		for _, line := range variants {
+			quantity := minInt(line.Quantity, discounted)
+			discounted -= quantity
+
+			discounts = append(discounts, domain.LineDiscount{
+				OrderLineID: line.ID,
+				Quantity:    quantity,
+				Amount:      line.UnitPrice.Mul(quantity).Neg(),
+			})
 		}
 	}
 
-	return 0
+	return discounts
 }
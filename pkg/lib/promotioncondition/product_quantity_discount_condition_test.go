@@ -12,7 +12,7 @@ func TestProductQuantityDiscountCondition_CalculateDiscount(t *testing.T) {
 	tests := []struct {
 		name  string
 		input *domain.Order
-		want  float64
+		want  domain.Money
 	}{
 		{
 			name: "should return correct discount amount",
@@ -22,11 +22,11 @@ func TestProductQuantityDiscountCondition_CalculateDiscount(t *testing.T) {
 						ID:        "line1",
 						ProductID: "p01",
 						Quantity:  3,
-						UnitPrice: 49.99,
+						UnitPrice: domain.MustParseMoney("49.99", "USD"),
 					},
 				},
 			},
-			want: -49.99,
+			want: domain.MustParseMoney("-49.99", "USD"),
 		},
 		{
 			name: "should return correct discount amount even with multiple of required quantity",
@@ -36,11 +36,11 @@ func TestProductQuantityDiscountCondition_CalculateDiscount(t *testing.T) {
 						ID:        "line1",
 						ProductID: "p01",
 						Quantity:  3 * 2,
-						UnitPrice: 49.99,
+						UnitPrice: domain.MustParseMoney("49.99", "USD"),
 					},
 				},
 			},
-			want: -49.99 * 2,
+			want: domain.MustParseMoney("-99.98", "USD"),
 		},
 		{
 			name: "should return zero if quantity is less than required promo quantity",
@@ -50,11 +50,11 @@ func TestProductQuantityDiscountCondition_CalculateDiscount(t *testing.T) {
 						ID:        "line1",
 						ProductID: "p01",
 						Quantity:  2,
-						UnitPrice: 49.99,
+						UnitPrice: domain.MustParseMoney("49.99", "USD"),
 					},
 				},
 			},
-			want: 0,
+			want: domain.MustParseMoney("0", "USD"),
 		},
 		{
 			name: "should return zero if product id not found in order",
@@ -64,11 +64,11 @@ func TestProductQuantityDiscountCondition_CalculateDiscount(t *testing.T) {
 						ID:        "line1",
 						ProductID: "p03",
 						Quantity:  3,
-						UnitPrice: 109.50,
+						UnitPrice: domain.MustParseMoney("109.50", "USD"),
 					},
 				},
 			},
-			want: 0,
+			want: domain.Money{},
 		},
 	}
 
@@ -85,3 +85,54 @@ func TestProductQuantityDiscountCondition_CalculateDiscount(t *testing.T) {
 		})
 	}
 }
+
+func TestProductQuantityDiscountCondition_CalculateLineDiscounts(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition ProductQuantityDiscount
+		want      []domain.LineDiscount
+	}{
+		{
+			name:      "should count the variants of a product together and discount the cheapest",
+			condition: ProductQuantityDiscount{ProductID: "tshirt", RequiredQuantity: 3, DiscountedQuantity: 1},
+			want: []domain.LineDiscount{
+				{OrderLineID: "line2", Quantity: 1, Amount: domain.MustParseMoney("-15", "USD")},
+				{OrderLineID: "line1", Quantity: 0, Amount: domain.MustParseMoney("0", "USD")},
+			},
+		},
+		{
+			name: "should discount as many units as the variants add up to",
+			condition: ProductQuantityDiscount{
+				Products:           domain.ProductSelector{ProductIDs: []string{"tshirt"}},
+				RequiredQuantity:   2,
+				DiscountedQuantity: 1,
+			},
+			want: []domain.LineDiscount{
+				{OrderLineID: "line2", Quantity: 1, Amount: domain.MustParseMoney("-15", "USD")},
+				{OrderLineID: "line1", Quantity: 0, Amount: domain.MustParseMoney("0", "USD")},
+			},
+		},
+		{
+			name:      "should only count the variant a promotion targets",
+			condition: ProductQuantityDiscount{ProductID: "tshirt-l", RequiredQuantity: 2, DiscountedQuantity: 1},
+			want: []domain.LineDiscount{
+				{OrderLineID: "line1", Quantity: 1, Amount: domain.MustParseMoney("-20", "USD")},
+			},
+		},
+	}
+
+	order := &domain.Order{
+		Lines: []*domain.OrderLine{
+			{ID: "line1", ProductID: "tshirt-l", ParentProductID: "tshirt", Quantity: 2, UnitPrice: domain.MustParseMoney("20", "USD")},
+			{ID: "line2", ProductID: "tshirt-s", ParentProductID: "tshirt", Quantity: 1, UnitPrice: domain.MustParseMoney("15", "USD")},
+			{ID: "line3", ProductID: "p01", Quantity: 3, UnitPrice: domain.MustParseMoney("49.99", "USD")},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.condition.CalculateLineDiscounts(order)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
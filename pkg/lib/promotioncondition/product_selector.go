@@ -0,0 +1,168 @@
+package promotioncondition
+
+import (
+	"fmt"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+// ProductSelector returns the parameter name, an object selecting
+// products:
+//
+//	{
+//	  "productIds": ["macbookpro"], "skus": ["43N23P"],
+//	  "categories": ["laptops"], "tags": ["sale"], "collections": ["spring"],
+//	  "minUnitPrice": 100, "maxUnitPrice": "999.99", "currencyCode": "USD",
+//	  "all": true
+//	}
+//
+// Every criterion is optional but at least one must be set, see
+// domain.ProductSelector.
+func (params *Parameters) ProductSelector(name string) domain.ProductSelector {
+	object := params.Object(name)
+	if object == nil {
+		return domain.ProductSelector{}
+	}
+
+	var selector domain.ProductSelector
+	if object.Has("all") {
+		selector.All = object.Bool("all")
+	}
+	for _, list := range []struct {
+		name   string
+		values *[]string
+	}{
+		{"productIds", &selector.ProductIDs},
+		{"skus", &selector.SKUs},
+		{"categories", &selector.Categories},
+		{"tags", &selector.Tags},
+		{"collections", &selector.Collections},
+	} {
+		if object.Has(list.name) {
+			*list.values = object.Strings(list.name)
+		}
+	}
+
+	hasMin, hasMax := object.Has("minUnitPrice"), object.Has("maxUnitPrice")
+	if hasMin || hasMax {
+		currency := object.Currency("currencyCode")
+		if hasMin {
+			selector.MinUnitPrice = object.Money("minUnitPrice", currency)
+		}
+		if hasMax {
+			selector.MaxUnitPrice = object.Money("maxUnitPrice", currency)
+		}
+		if hasMin && hasMax && selector.MaxUnitPrice.Cmp(selector.MinUnitPrice) < 0 {
+			object.Fail("maxUnitPrice", "must not be less than minUnitPrice")
+		}
+	} else if object.Has("currencyCode") {
+		object.Fail("currencyCode", "is only used with minUnitPrice or maxUnitPrice")
+	}
+
+	if selector.IsZero() {
+		params.Fail(name, "must select products by at least one criterion")
+	}
+
+	return selector
+}
+
+// ProductSelectorParameters returns the parameters ProductSelector reads
+// selector back from.
+func ProductSelectorParameters(selector domain.ProductSelector) map[string]interface{} {
+	params := make(map[string]interface{})
+	if selector.All {
+		params["all"] = true
+	}
+	for name, values := range map[string][]string{
+		"productIds":  selector.ProductIDs,
+		"skus":        selector.SKUs,
+		"categories":  selector.Categories,
+		"tags":        selector.Tags,
+		"collections": selector.Collections,
+	} {
+		if len(values) > 0 {
+			params[name] = stringList(values)
+		}
+	}
+	if !selector.MinUnitPrice.IsZero() {
+		params["minUnitPrice"] = selector.MinUnitPrice.Decimal()
+		params["currencyCode"] = selector.MinUnitPrice.Currency
+	}
+	if !selector.MaxUnitPrice.IsZero() {
+		params["maxUnitPrice"] = selector.MaxUnitPrice.Decimal()
+		params["currencyCode"] = selector.MaxUnitPrice.Currency
+	}
+
+	return params
+}
+
+// productOrSelector reads the products a condition targets, either the
+// single product of idName or the products selected by selectorName.
+func productOrSelector(params *Parameters, idName string, selectorName string) (string, domain.ProductSelector) {
+	if !params.Has(selectorName) {
+		return params.String(idName), domain.ProductSelector{}
+	}
+
+	if params.Has(idName) {
+		params.Fail(idName, fmt.Sprintf("must not be set with %s", selectorName))
+	}
+
+	return "", params.ProductSelector(selectorName)
+}
+
+// productsOrSelector reads the products a condition targets, either the
+// products listed by idsName or the products selected by selectorName.
+func productsOrSelector(params *Parameters, idsName string, selectorName string) domain.ProductSelector {
+	if !params.Has(selectorName) {
+		return domain.SelectProducts(params.Strings(idsName)...)
+	}
+
+	if params.Has(idsName) {
+		params.Fail(idsName, fmt.Sprintf("must not be set with %s", selectorName))
+	}
+
+	return params.ProductSelector(selectorName)
+}
+
+// setProductOrSelector is the reverse of productOrSelector.
+func setProductOrSelector(params map[string]interface{}, idName string, productID string, selectorName string, selector domain.ProductSelector) {
+	if productID != "" {
+		params[idName] = productID
+		return
+	}
+
+	params[selectorName] = ProductSelectorParameters(selector)
+}
+
+// setProductsOrSelector is the reverse of productsOrSelector, selectors
+// of product ids only are written as the list of ids.
+func setProductsOrSelector(params map[string]interface{}, idsName string, selectorName string, selector domain.ProductSelector) {
+	others := selector
+	others.ProductIDs = nil
+	if len(selector.ProductIDs) > 0 && others.IsZero() {
+		params[idsName] = stringList(selector.ProductIDs)
+		return
+	}
+
+	params[selectorName] = ProductSelectorParameters(selector)
+}
+
+// targetedLines returns the lines of the product productID or, when it is
+// empty, the lines selector selects.
+func targetedLines(order *domain.Order, productID string, selector domain.ProductSelector) []*domain.OrderLine {
+	if productID != "" {
+		selector = domain.SelectProducts(productID)
+	}
+
+	return selector.Lines(order)
+}
+
+// parentProductID returns the product the line is a variant of, or the
+// product of the line when it is not a variant.
+func parentProductID(line *domain.OrderLine) string {
+	if line.ParentProductID != "" {
+		return line.ParentProductID
+	}
+
+	return line.ProductID
+}
@@ -0,0 +1,164 @@
+package promotioncondition
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+func TestParameters_ProductSelector(t *testing.T) {
+	tests := []struct {
+		name       string
+		values     map[string]interface{}
+		want       domain.ProductSelector
+		wantFields []domain.FieldError
+	}{
+		{
+			name: "should read every criterion",
+			values: map[string]interface{}{"products": map[string]interface{}{
+				"productIds":   []interface{}{"p01"},
+				"skus":         []interface{}{"120P90"},
+				"categories":   []interface{}{"speakers"},
+				"tags":         []interface{}{"sale"},
+				"collections":  []interface{}{"spring"},
+				"minUnitPrice": 10,
+				"maxUnitPrice": "99.99",
+				"currencyCode": "USD",
+			}},
+			want: domain.ProductSelector{
+				ProductIDs:   []string{"p01"},
+				SKUs:         []string{"120P90"},
+				Categories:   []string{"speakers"},
+				Tags:         []string{"sale"},
+				Collections:  []string{"spring"},
+				MinUnitPrice: domain.MustParseMoney("10", "USD"),
+				MaxUnitPrice: domain.MustParseMoney("99.99", "USD"),
+			},
+		},
+		{
+			name:   "should select every product",
+			values: map[string]interface{}{"products": map[string]interface{}{"all": true}},
+			want:   domain.ProductSelector{All: true},
+		},
+		{
+			name:   "should require a criterion",
+			values: map[string]interface{}{"products": map[string]interface{}{}},
+			wantFields: []domain.FieldError{
+				{Field: "parameters.products", Message: "must select products by at least one criterion"},
+			},
+		},
+		{
+			name: "should report invalid criteria",
+			values: map[string]interface{}{"products": map[string]interface{}{
+				"tags":         "sale",
+				"minUnitPrice": 100,
+				"maxUnitPrice": 10,
+				"currencyCode": "USD",
+				"category":     []interface{}{"speakers"},
+			}},
+			wantFields: []domain.FieldError{
+				{Field: "parameters.products.tags", Message: "must be a non-empty list"},
+				{Field: "parameters.products.maxUnitPrice", Message: "must not be less than minUnitPrice"},
+				{Field: "parameters.products.category", Message: `unknown parameter "category"`},
+			},
+		},
+		{
+			name: "should only take a currency with a price range",
+			values: map[string]interface{}{"products": map[string]interface{}{
+				"categories":   []interface{}{"speakers"},
+				"currencyCode": "USD",
+			}},
+			want: domain.ProductSelector{Categories: []string{"speakers"}},
+			wantFields: []domain.FieldError{
+				{Field: "parameters.products.currencyCode", Message: "is only used with minUnitPrice or maxUnitPrice"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			params := newParameters(test.values)
+			got := params.ProductSelector("products")
+			params.failUnused()
+
+			assert.Equal(t, test.wantFields, params.fields)
+			if test.wantFields == nil {
+				assert.Equal(t, test.want, got)
+			}
+		})
+	}
+}
+
+func TestProductSelector_Conditions(t *testing.T) {
+	order := &domain.Order{
+		Lines: []*domain.OrderLine{
+			{ID: "line1", ProductID: "googlehome", Quantity: 3, UnitPrice: domain.MustParseMoney("49.99", "USD"), Categories: []string{"speakers"}},
+			{ID: "line2", ProductID: "alexaspeaker", Quantity: 3, UnitPrice: domain.MustParseMoney("109.50", "USD"), Categories: []string{"speakers"}, Tags: []string{"sale"}},
+			{ID: "line3", ProductID: "macbookpro", Quantity: 1, UnitPrice: domain.MustParseMoney("5399.99", "USD"), Categories: []string{"laptops"}},
+			{ID: "line4", ProductID: "raspberrypi", Quantity: 2, UnitPrice: domain.MustParseMoney("30", "USD"), Tags: []string{"accessory"}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		condition domain.LineDiscounter
+		want      []domain.LineDiscount
+	}{
+		{
+			name: "should discount every line of a category",
+			condition: ProductPercentageDiscount{
+				Products:          domain.ProductSelector{Categories: []string{"speakers"}},
+				MinQuantity:       3,
+				DiscountInPercent: 10,
+			},
+			want: []domain.LineDiscount{
+				{OrderLineID: "line1", Quantity: 3, Amount: domain.MustParseMoney("-15", "USD")},
+				{OrderLineID: "line2", Quantity: 3, Amount: domain.MustParseMoney("-32.85", "USD")},
+			},
+		},
+		{
+			name: "should apply a quantity discount to each tagged product on its own",
+			condition: ProductQuantityDiscount{
+				Products:           domain.ProductSelector{Tags: []string{"sale", "accessory"}},
+				RequiredQuantity:   2,
+				DiscountedQuantity: 1,
+			},
+			want: []domain.LineDiscount{
+				{OrderLineID: "line2", Quantity: 1, Amount: domain.MustParseMoney("-109.50", "USD")},
+				{OrderLineID: "line4", Quantity: 1, Amount: domain.MustParseMoney("-30", "USD")},
+			},
+		},
+		{
+			name: "should give the cheapest free products for every X product",
+			condition: BuyXProductGetFreeProductCondition{
+				XProducts:    domain.ProductSelector{Categories: []string{"laptops"}},
+				FreeProducts: domain.ProductSelector{MaxUnitPrice: domain.MustParseMoney("50", "USD")},
+			},
+			want: []domain.LineDiscount{
+				{OrderLineID: "line4", Quantity: 1, Amount: domain.MustParseMoney("-30", "USD")},
+			},
+		},
+		{
+			name: "should buy and get products selected by price range",
+			condition: BuyNGetMDiscount{
+				BuyProducts:       domain.ProductSelector{MinUnitPrice: domain.MustParseMoney("1000", "USD")},
+				BuyQuantity:       1,
+				GetProducts:       domain.ProductSelector{Categories: []string{"speakers"}},
+				GetQuantity:       2,
+				DiscountInPercent: 50,
+			},
+			want: []domain.LineDiscount{
+				{OrderLineID: "line1", Quantity: 2, Amount: domain.MustParseMoney("-49.99", "USD")},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.condition.CalculateLineDiscounts(order)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
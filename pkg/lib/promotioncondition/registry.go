@@ -0,0 +1,158 @@
+package promotioncondition
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+var (
+	ErrConditionTypeAlreadyRegistered = errors.New("condition type already registered")
+	ErrConditionNotSerializable       = errors.New("condition is not serializable")
+)
+
+// ConditionType is a type of promotion condition that can be built from
+// parameters, so promotions of that type can be configured without code.
+type ConditionType struct {
+	// Name identifies the type in configuration, e.g.
+	// "product_quantity_discount".
+	Name string
+	// New builds a condition from its parameters, the invalid ones are
+	// reported with params.Fail.
+	New func(params *Parameters) domain.PromotionCondition
+}
+
+// Serializable is a condition that can be turned back into the type and
+// parameters it is built from.
+type Serializable interface {
+	domain.PromotionCondition
+	// ConditionType returns the name of the type of the condition.
+	ConditionType() string
+	// Parameters returns the parameters that build the same condition.
+	Parameters() map[string]interface{}
+}
+
+// Registry knows the types of promotion conditions by name.
+type Registry struct {
+	mu    sync.RWMutex
+	types map[string]ConditionType
+}
+
+func NewRegistry() *Registry {
+	return &Registry{types: make(map[string]ConditionType)}
+}
+
+// DefaultRegistry knows the conditions of this package, it is where
+// other packages register their own conditions.
+var DefaultRegistry = newDefaultRegistry()
+
+func newDefaultRegistry() *Registry {
+	registry := NewRegistry()
+	for _, conditionType := range []ConditionType{
+		{Name: BuyXProductGetFreeProductType, New: newBuyXProductGetFreeProductCondition},
+		{Name: ProductQuantityDiscountType, New: newProductQuantityDiscount},
+		{Name: ProductPercentageDiscountType, New: newProductPercentageDiscount},
+		{Name: CartThresholdDiscountType, New: newCartThresholdDiscount},
+		{Name: BundlePriceType, New: newBundlePrice},
+		{Name: BuyNGetMDiscountType, New: newBuyNGetMDiscount},
+	} {
+		if err := registry.Register(conditionType); err != nil {
+			panic(err)
+		}
+	}
+
+	return registry
+}
+
+// Register adds a condition type to the default registry, usually from
+// the init function of the package defining the condition.
+func Register(conditionType ConditionType) error {
+	return DefaultRegistry.Register(conditionType)
+}
+
+func (registry *Registry) Register(conditionType ConditionType) error {
+	if conditionType.Name == "" || conditionType.New == nil {
+		return errors.New("condition type needs a name and a constructor")
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if _, ok := registry.types[conditionType.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrConditionTypeAlreadyRegistered, conditionType.Name)
+	}
+
+	registry.types[conditionType.Name] = conditionType
+	return nil
+}
+
+// Types returns the names of the registered condition types in
+// alphabetical order.
+func (registry *Registry) Types() []string {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	names := make([]string, 0, len(registry.types))
+	for name := range registry.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// New builds a condition of the type named typeName from its parameters.
+// It returns a *domain.ValidationError wrapping domain.ErrInvalidPromotion
+// when the type is unknown or some parameters are invalid.
+func (registry *Registry) New(typeName string, values map[string]interface{}) (domain.PromotionCondition, error) {
+	registry.mu.RLock()
+	conditionType, ok := registry.types[typeName]
+	registry.mu.RUnlock()
+
+	if !ok {
+		message := "is required"
+		if typeName != "" {
+			message = fmt.Sprintf("unknown type %q, expected one of %s", typeName, strings.Join(registry.Types(), ", "))
+		}
+
+		return nil, &domain.ValidationError{
+			Err:    domain.ErrInvalidPromotion,
+			Fields: []domain.FieldError{{Field: "type", Message: message}},
+		}
+	}
+
+	params := newParameters(values)
+	condition := conditionType.New(params)
+	params.failUnused()
+
+	if len(params.fields) > 0 {
+		return nil, &domain.ValidationError{Err: domain.ErrInvalidPromotion, Fields: params.fields}
+	}
+
+	return condition, nil
+}
+
+// Encode returns the type and parameters that build condition back. The
+// condition must be Serializable and its type registered.
+func (registry *Registry) Encode(condition domain.PromotionCondition) (string, map[string]interface{}, error) {
+	serializable, ok := condition.(Serializable)
+	if !ok {
+		return "", nil, fmt.Errorf("%w: %T", ErrConditionNotSerializable, condition)
+	}
+
+	typeName := serializable.ConditionType()
+
+	registry.mu.RLock()
+	_, ok = registry.types[typeName]
+	registry.mu.RUnlock()
+
+	if !ok {
+		return "", nil, fmt.Errorf("%w: type %q of %T is not registered", ErrConditionNotSerializable, typeName, condition)
+	}
+
+	return typeName, serializable.Parameters(), nil
+}
@@ -0,0 +1,211 @@
+package promotioncondition
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+	"github.com/donnpebe/shoppo/pkg/lib/promotioncondition/mock"
+)
+
+// seasonalDiscount stands for a condition defined outside of shoppo.
+type seasonalDiscount struct {
+	ProductID string
+	Until     time.Time
+}
+
+func (cond seasonalDiscount) CalculateDiscount(order *domain.Order) domain.Money {
+	return domain.Money{}
+}
+
+func (cond seasonalDiscount) ConditionType() string {
+	return "seasonal"
+}
+
+func (cond seasonalDiscount) Parameters() map[string]interface{} {
+	return map[string]interface{}{"productId": cond.ProductID, "until": cond.Until.Format(time.RFC3339)}
+}
+
+func newSeasonalDiscount(params *Parameters) domain.PromotionCondition {
+	return seasonalDiscount{ProductID: params.String("productId"), Until: params.Time("until")}
+}
+
+func TestRegistry_New(t *testing.T) {
+	sut := NewRegistry()
+	require.NoError(t, sut.Register(ConditionType{Name: "seasonal", New: newSeasonalDiscount}))
+
+	tests := []struct {
+		name       string
+		typeName   string
+		params     map[string]interface{}
+		want       domain.PromotionCondition
+		wantFields []domain.FieldError
+	}{
+		{
+			name:     "should build a registered condition",
+			typeName: "seasonal",
+			params:   map[string]interface{}{"productId": "p01", "until": "2022-12-31T23:59:59Z"},
+			want:     seasonalDiscount{ProductID: "p01", Until: time.Date(2022, 12, 31, 23, 59, 59, 0, time.UTC)},
+		},
+		{
+			name:     "should accept timestamps decoded by YAML",
+			typeName: "seasonal",
+			params:   map[string]interface{}{"productId": "p01", "until": time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC)},
+			want:     seasonalDiscount{ProductID: "p01", Until: time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:     "should report invalid and unknown parameters",
+			typeName: "seasonal",
+			params:   map[string]interface{}{"productId": "p01", "until": "tomorrow", "untill": "2022-12-31T23:59:59Z"},
+			wantFields: []domain.FieldError{
+				{Field: "parameters.until", Message: "must be an RFC 3339 timestamp"},
+				{Field: "parameters.untill", Message: `unknown parameter "untill"`},
+			},
+		},
+		{
+			name:     "should report unknown types",
+			typeName: BuyXProductGetFreeProductType,
+			wantFields: []domain.FieldError{
+				{Field: "type", Message: `unknown type "buy_x_product_get_free_product", expected one of seasonal`},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := sut.New(test.typeName, test.params)
+			if test.wantFields != nil {
+				assert.ErrorIs(t, err, domain.ErrInvalidPromotion)
+
+				var validationErr *domain.ValidationError
+				require.True(t, errors.As(err, &validationErr))
+				assert.Equal(t, test.wantFields, validationErr.Fields)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestRegistry_Register(t *testing.T) {
+	sut := NewRegistry()
+	require.NoError(t, sut.Register(ConditionType{Name: "seasonal", New: newSeasonalDiscount}))
+
+	err := sut.Register(ConditionType{Name: "seasonal", New: newSeasonalDiscount})
+	assert.ErrorIs(t, err, ErrConditionTypeAlreadyRegistered)
+
+	assert.Error(t, sut.Register(ConditionType{Name: "loyalty"}))
+	assert.Equal(t, []string{"seasonal"}, sut.Types())
+}
+
+func TestRegistry_Encode(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition domain.PromotionCondition
+	}{
+		{
+			name: "should round trip BuyXProductGetFreeProductCondition",
+			condition: BuyXProductGetFreeProductCondition{
+				XProductID:    "macbookpro",
+				FreeProductID: "raspberrypi",
+			},
+		},
+		{
+			name: "should round trip ProductQuantityDiscount",
+			condition: ProductQuantityDiscount{
+				ProductID:          "googlehome",
+				RequiredQuantity:   3,
+				DiscountedQuantity: 1,
+			},
+		},
+		{
+			name: "should round trip ProductPercentageDiscount",
+			condition: ProductPercentageDiscount{
+				ProductID:         "alexaspeaker",
+				MinQuantity:       3,
+				DiscountInPercent: 12.5,
+			},
+		},
+		{
+			name: "should round trip CartThresholdDiscount",
+			condition: CartThresholdDiscount{
+				Tiers: []CartThresholdTier{
+					{MinSubtotal: domain.MustParseMoney("500", "USD"), Amount: domain.MustParseMoney("49.99", "USD")},
+					{MinSubtotal: domain.MustParseMoney("1000", "USD"), DiscountInPercent: 10},
+				},
+				ExcludeDiscountedLines: true,
+			},
+		},
+		{
+			name: "should round trip BundlePrice",
+			condition: BundlePrice{
+				ProductIDs: []string{"macbookpro", "googlehome", "googlehome"},
+				Price:      domain.MustParseMoney("5449.95", "USD"),
+			},
+		},
+		{
+			name: "should round trip BuyNGetMDiscount",
+			condition: BuyNGetMDiscount{
+				BuyProducts:       domain.SelectProducts("googlehome", "alexaspeaker"),
+				BuyQuantity:       2,
+				GetProducts:       domain.SelectProducts("raspberrypi"),
+				GetQuantity:       1,
+				DiscountInPercent: 50,
+				MaxApplications:   2,
+			},
+		},
+		{
+			name: "should round trip product selectors",
+			condition: BuyXProductGetFreeProductCondition{
+				XProducts: domain.ProductSelector{
+					Categories:   []string{"laptops"},
+					MinUnitPrice: domain.MustParseMoney("1000", "USD"),
+				},
+				FreeProducts: domain.ProductSelector{
+					ProductIDs:  []string{"raspberrypi"},
+					SKUs:        []string{"R07B8"},
+					Tags:        []string{"accessory"},
+					Collections: []string{"spring"},
+				},
+			},
+		},
+		{
+			name: "should round trip selectors of every product",
+			condition: BuyNGetMDiscount{
+				BuyProducts:       domain.ProductSelector{All: true},
+				BuyQuantity:       2,
+				GetProducts:       domain.ProductSelector{All: true, MaxUnitPrice: domain.MustParseMoney("50", "USD")},
+				GetQuantity:       1,
+				DiscountInPercent: 100,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			typeName, params, err := DefaultRegistry.Encode(test.condition)
+			require.NoError(t, err)
+
+			got, err := DefaultRegistry.New(typeName, params)
+			assert.NoError(t, err)
+			assert.Equal(t, test.condition, got)
+		})
+	}
+}
+
+func TestRegistry_EncodeNotSerializable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	_, _, err := DefaultRegistry.Encode(mock.NewMockPromotionCondition(ctrl))
+	assert.ErrorIs(t, err, ErrConditionNotSerializable)
+
+	_, _, err = DefaultRegistry.Encode(seasonalDiscount{ProductID: "p01"})
+	assert.ErrorIs(t, err, ErrConditionNotSerializable, "should not encode conditions of unregistered types")
+}
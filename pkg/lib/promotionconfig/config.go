@@ -0,0 +1,280 @@
+// Package promotionconfig loads promotions from JSON or YAML files, so
+// they can change without a deploy.
+//
+// A file lists promotions, each with the type of its condition and the
+// parameters of that type:
+//
+//	promotions:
+//	  - id: googlehome-3-for-2
+//	    name: 3 Google Homes for the price of 2
+//	    type: product_quantity_discount
+//	    parameters:
+//	      productId: googlehome
+//	      requiredQuantity: 3
+//	      discountedQuantity: 1
+//	    startDate: 2022-03-01T00:00:00Z
+//	    endDate: 2022-03-31T23:59:59Z
+//	    priority: 10
+//	    stackGroup: googlehome
+//
+// The dates are RFC 3339 timestamps and optional, so are priority,
+// exclusive, stackGroup and maxOnePerLine which tell how the promotion
+// combines with the others. A promotion with a couponCode only applies
+// to the orders the code is applied to, at most usageLimit times and
+// usageLimitPerCustomer times per customer when they are set. The types
+// are the ones of the promotioncondition registry, where other packages
+// can register their own.
+package promotionconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+	"github.com/donnpebe/shoppo/pkg/lib/promotioncondition"
+)
+
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+)
+
+// File is the content of a promotions file.
+type File struct {
+	Promotions []Promotion `json:"promotions" yaml:"promotions"`
+}
+
+// Promotion describes a promotion, its condition is built by the
+// condition type from the parameters.
+type Promotion struct {
+	ID         string                 `json:"id" yaml:"id"`
+	Name       string                 `json:"name" yaml:"name"`
+	Type       string                 `json:"type" yaml:"type"`
+	Parameters map[string]interface{} `json:"parameters" yaml:"parameters"`
+	StartDate  *time.Time             `json:"startDate,omitempty" yaml:"startDate,omitempty"`
+	EndDate    *time.Time             `json:"endDate,omitempty" yaml:"endDate,omitempty"`
+
+	Priority      int    `json:"priority,omitempty" yaml:"priority,omitempty"`
+	Exclusive     bool   `json:"exclusive,omitempty" yaml:"exclusive,omitempty"`
+	StackGroup    string `json:"stackGroup,omitempty" yaml:"stackGroup,omitempty"`
+	MaxOnePerLine bool   `json:"maxOnePerLine,omitempty" yaml:"maxOnePerLine,omitempty"`
+
+	CouponCode            string `json:"couponCode,omitempty" yaml:"couponCode,omitempty"`
+	UsageLimit            int    `json:"usageLimit,omitempty" yaml:"usageLimit,omitempty"`
+	UsageLimitPerCustomer int    `json:"usageLimitPerCustomer,omitempty" yaml:"usageLimitPerCustomer,omitempty"`
+}
+
+// Load reads the promotions of the file at path, its format is told by
+// its extension: .json, .yaml or .yml.
+func Load(path string) ([]domain.Promotion, error) {
+	var format Format
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		format = FormatJSON
+	case ".yaml", ".yml":
+		format = FormatYAML
+	default:
+		return nil, fmt.Errorf("unknown promotions file format %q, expected .json, .yaml or .yml", filepath.Ext(path))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	promotions, err := Parse(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return promotions, nil
+}
+
+// Parse builds the promotions described in data. It returns a
+// *domain.ValidationError listing every invalid field when a promotion
+// is invalid, e.g. of an unknown type or with a bad parameter.
+func Parse(data []byte, format Format) ([]domain.Promotion, error) {
+	var file File
+	switch format {
+	case FormatJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&file); err != nil {
+			return nil, fmt.Errorf("%w: %v", domain.ErrInvalidPromotion, err)
+		}
+	case FormatYAML:
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		// An empty file has no promotion.
+		if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: %v", domain.ErrInvalidPromotion, err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown promotions file format %q", format)
+	}
+
+	return file.Build()
+}
+
+// NewFile describes the promotions, their conditions must be
+// serializable by the registry.
+func NewFile(promotions []domain.Promotion, registry *promotioncondition.Registry) (File, error) {
+	file := File{Promotions: make([]Promotion, 0, len(promotions))}
+	for _, promotion := range promotions {
+		typeName, params, err := registry.Encode(promotion.Condition)
+		if err != nil {
+			return File{}, fmt.Errorf("promotion %s: %w", promotion.ID, err)
+		}
+
+		config := Promotion{
+			ID:            promotion.ID,
+			Name:          promotion.Name,
+			Type:          typeName,
+			Parameters:    params,
+			Priority:      promotion.Priority,
+			Exclusive:     promotion.Exclusive,
+			StackGroup:    promotion.StackGroup,
+			MaxOnePerLine: promotion.MaxOnePerLine,
+
+			CouponCode:            promotion.CouponCode,
+			UsageLimit:            promotion.UsageLimit,
+			UsageLimitPerCustomer: promotion.UsageLimitPerCustomer,
+		}
+		if !promotion.StartDate.IsZero() {
+			startDate := promotion.StartDate
+			config.StartDate = &startDate
+		}
+		if !promotion.EndDate.IsZero() {
+			endDate := promotion.EndDate
+			config.EndDate = &endDate
+		}
+
+		file.Promotions = append(file.Promotions, config)
+	}
+
+	return file, nil
+}
+
+// Marshal encodes the file in format.
+func (file File) Marshal(format Format) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return json.MarshalIndent(file, "", "  ")
+	case FormatYAML:
+		return yaml.Marshal(file)
+	default:
+		return nil, fmt.Errorf("unknown promotions file format %q", format)
+	}
+}
+
+// Build builds the promotions of the file with the condition types of
+// promotioncondition.DefaultRegistry.
+func (file File) Build() ([]domain.Promotion, error) {
+	return file.BuildWith(promotioncondition.DefaultRegistry)
+}
+
+// BuildWith builds the promotions of the file with the condition types
+// of registry.
+func (file File) BuildWith(registry *promotioncondition.Registry) ([]domain.Promotion, error) {
+	var fields []domain.FieldError
+	fail := func(field string, message string) {
+		fields = append(fields, domain.FieldError{Field: field, Message: message})
+	}
+
+	promotions := make([]domain.Promotion, 0, len(file.Promotions))
+	ids := make(map[string]bool, len(file.Promotions))
+	couponCodes := make(map[string]bool)
+	for i, config := range file.Promotions {
+		path := fmt.Sprintf("promotions[%d]", i)
+
+		switch {
+		case config.ID == "":
+			fail(path+".id", "is required")
+		case ids[config.ID]:
+			fail(path+".id", fmt.Sprintf("%q is already used by another promotion", config.ID))
+		}
+		ids[config.ID] = true
+
+		if config.Name == "" {
+			fail(path+".name", "is required")
+		}
+
+		if config.StartDate != nil && config.EndDate != nil && config.EndDate.Before(*config.StartDate) {
+			fail(path+".endDate", "must not be before startDate")
+		}
+
+		couponCode := domain.NormalizeCouponCode(config.CouponCode)
+		if couponCode != "" {
+			if couponCodes[couponCode] {
+				fail(path+".couponCode", fmt.Sprintf("%q is already used by another promotion", couponCode))
+			}
+			couponCodes[couponCode] = true
+		}
+
+		limits := []struct {
+			field string
+			limit int
+		}{
+			{"usageLimit", config.UsageLimit},
+			{"usageLimitPerCustomer", config.UsageLimitPerCustomer},
+		}
+		for _, limit := range limits {
+			switch {
+			case limit.limit < 0:
+				fail(path+"."+limit.field, "must not be negative")
+			case limit.limit > 0 && couponCode == "":
+				fail(path+"."+limit.field, "requires a couponCode")
+			}
+		}
+
+		condition, err := registry.New(config.Type, config.Parameters)
+		var validationErr *domain.ValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			for _, field := range validationErr.Fields {
+				fail(path+"."+field.Field, field.Message)
+			}
+		case err != nil:
+			return nil, err
+		}
+
+		promotion := domain.Promotion{
+			ID:            config.ID,
+			Name:          config.Name,
+			Condition:     condition,
+			Priority:      config.Priority,
+			Exclusive:     config.Exclusive,
+			StackGroup:    config.StackGroup,
+			MaxOnePerLine: config.MaxOnePerLine,
+
+			CouponCode:            couponCode,
+			UsageLimit:            config.UsageLimit,
+			UsageLimitPerCustomer: config.UsageLimitPerCustomer,
+		}
+		if config.StartDate != nil {
+			promotion.StartDate = *config.StartDate
+		}
+		if config.EndDate != nil {
+			promotion.EndDate = *config.EndDate
+		}
+
+		promotions = append(promotions, promotion)
+	}
+
+	if len(fields) > 0 {
+		return nil, &domain.ValidationError{Err: domain.ErrInvalidPromotion, Fields: fields}
+	}
+
+	return promotions, nil
+}
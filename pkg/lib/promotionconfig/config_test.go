@@ -0,0 +1,383 @@
+package promotionconfig
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+	"github.com/donnpebe/shoppo/pkg/lib/promotioncondition"
+)
+
+func TestParse(t *testing.T) {
+	want := []domain.Promotion{
+		{
+			ID:   "macbookpro-free-raspberrypi",
+			Name: "Free Raspberry Pi with every MacBook Pro",
+			Condition: promotioncondition.BuyXProductGetFreeProductCondition{
+				XProductID:    "macbookpro",
+				FreeProductID: "raspberrypi",
+			},
+			CouponCode:            "FREEPI",
+			UsageLimit:            100,
+			UsageLimitPerCustomer: 1,
+		},
+		{
+			ID:        "alexaspeaker-10-percent",
+			Name:      "10% off Alexa Speakers when buying 3 or more",
+			StartDate: time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC),
+			EndDate:   time.Date(2022, 3, 31, 23, 59, 59, 0, time.UTC),
+			Condition: promotioncondition.ProductPercentageDiscount{
+				ProductID:         "alexaspeaker",
+				MinQuantity:       3,
+				DiscountInPercent: 10.5,
+			},
+			Priority:      10,
+			Exclusive:     true,
+			StackGroup:    "alexaspeaker",
+			MaxOnePerLine: true,
+		},
+	}
+
+	tests := []struct {
+		name   string
+		data   string
+		format Format
+	}{
+		{
+			name:   "should parse YAML",
+			format: FormatYAML,
+			data: `
+promotions:
+  - id: macbookpro-free-raspberrypi
+    name: Free Raspberry Pi with every MacBook Pro
+    type: buy_x_product_get_free_product
+    parameters:
+      xProductId: macbookpro
+      freeProductId: raspberrypi
+    couponCode: freepi
+    usageLimit: 100
+    usageLimitPerCustomer: 1
+  - id: alexaspeaker-10-percent
+    name: 10% off Alexa Speakers when buying 3 or more
+    type: product_percentage_discount
+    parameters:
+      productId: alexaspeaker
+      minQuantity: 3
+      discountInPercent: 10.5
+    startDate: 2022-03-01T00:00:00Z
+    endDate: 2022-03-31T23:59:59Z
+    priority: 10
+    exclusive: true
+    stackGroup: alexaspeaker
+    maxOnePerLine: true
+`,
+		},
+		{
+			name:   "should parse JSON",
+			format: FormatJSON,
+			data: `{"promotions": [
+				{
+					"id": "macbookpro-free-raspberrypi",
+					"name": "Free Raspberry Pi with every MacBook Pro",
+					"type": "buy_x_product_get_free_product",
+					"parameters": {"xProductId": "macbookpro", "freeProductId": "raspberrypi"},
+					"couponCode": "FreePi",
+					"usageLimit": 100,
+					"usageLimitPerCustomer": 1
+				},
+				{
+					"id": "alexaspeaker-10-percent",
+					"name": "10% off Alexa Speakers when buying 3 or more",
+					"type": "product_percentage_discount",
+					"parameters": {"productId": "alexaspeaker", "minQuantity": 3, "discountInPercent": 10.5},
+					"startDate": "2022-03-01T00:00:00Z",
+					"endDate": "2022-03-31T23:59:59Z",
+					"priority": 10,
+					"exclusive": true,
+					"stackGroup": "alexaspeaker",
+					"maxOnePerLine": true
+				}
+			]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Parse([]byte(test.data), test.format)
+			require.NoError(t, err)
+			require.Len(t, got, len(want))
+			for i := range want {
+				assert.Equal(t, want[i].ID, got[i].ID)
+				assert.Equal(t, want[i].Name, got[i].Name)
+				assert.True(t, want[i].StartDate.Equal(got[i].StartDate))
+				assert.True(t, want[i].EndDate.Equal(got[i].EndDate))
+				assert.Equal(t, want[i].Condition, got[i].Condition)
+				assert.Equal(t, want[i].Priority, got[i].Priority)
+				assert.Equal(t, want[i].Exclusive, got[i].Exclusive)
+				assert.Equal(t, want[i].StackGroup, got[i].StackGroup)
+				assert.Equal(t, want[i].MaxOnePerLine, got[i].MaxOnePerLine)
+				assert.Equal(t, want[i].CouponCode, got[i].CouponCode)
+				assert.Equal(t, want[i].UsageLimit, got[i].UsageLimit)
+				assert.Equal(t, want[i].UsageLimitPerCustomer, got[i].UsageLimitPerCustomer)
+			}
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		wantFields []domain.FieldError
+	}{
+		{
+			name: "should reject unknown and missing types",
+			data: `
+promotions:
+  - id: p1
+    name: P1
+    type: loyalty
+  - id: p2
+    name: P2
+`,
+			wantFields: []domain.FieldError{
+				{Field: "promotions[0].type", Message: `unknown type "loyalty", expected one of bundle_price, buy_n_get_m_discount, buy_x_product_get_free_product, cart_threshold_discount, product_percentage_discount, product_quantity_discount`},
+				{Field: "promotions[1].type", Message: "is required"},
+			},
+		},
+		{
+			name: "should report every bad parameter",
+			data: `
+promotions:
+  - id: p1
+    name: P1
+    type: product_quantity_discount
+    parameters:
+      productId: 42
+      requiredQuantity: 2.5
+      discountedQuantity: 1
+      discountedQuantiy: 1
+`,
+			wantFields: []domain.FieldError{
+				{Field: "promotions[0].parameters.requiredQuantity", Message: "must be a positive integer"},
+				{Field: "promotions[0].parameters.discountedQuantity", Message: "must not be more than requiredQuantity"},
+				{Field: "promotions[0].parameters.productId", Message: "must be a non-empty string"},
+				{Field: "promotions[0].parameters.discountedQuantiy", Message: `unknown parameter "discountedQuantiy"`},
+			},
+		},
+		{
+			name: "should reject missing parameters and out of range percentages",
+			data: `
+promotions:
+  - id: p1
+    name: P1
+    type: product_percentage_discount
+    parameters:
+      discountInPercent: 150
+`,
+			wantFields: []domain.FieldError{
+				{Field: "promotions[0].parameters.discountInPercent", Message: "must be more than 0 and at most 100"},
+				{Field: "promotions[0].parameters.productId", Message: "is required"},
+				{Field: "promotions[0].parameters.minQuantity", Message: "is required"},
+			},
+		},
+		{
+			name: "should reject duplicated ids, missing names and reversed dates",
+			data: `
+promotions:
+  - id: p1
+    name: P1
+    type: buy_x_product_get_free_product
+    parameters: {xProductId: a, freeProductId: b}
+  - id: p1
+    type: buy_x_product_get_free_product
+    parameters: {xProductId: a, freeProductId: b}
+    startDate: 2022-03-31T00:00:00Z
+    endDate: 2022-03-01T00:00:00Z
+`,
+			wantFields: []domain.FieldError{
+				{Field: "promotions[1].id", Message: `"p1" is already used by another promotion`},
+				{Field: "promotions[1].name", Message: "is required"},
+				{Field: "promotions[1].endDate", Message: "must not be before startDate"},
+			},
+		},
+		{
+			name: "should reject duplicated coupon codes and bad usage limits",
+			data: `
+promotions:
+  - id: p1
+    name: P1
+    type: buy_x_product_get_free_product
+    parameters: {xProductId: a, freeProductId: b}
+    couponCode: save10
+    usageLimit: -1
+  - id: p2
+    name: P2
+    type: buy_x_product_get_free_product
+    parameters: {xProductId: a, freeProductId: b}
+    couponCode: SAVE10
+  - id: p3
+    name: P3
+    type: buy_x_product_get_free_product
+    parameters: {xProductId: a, freeProductId: b}
+    usageLimitPerCustomer: 1
+`,
+			wantFields: []domain.FieldError{
+				{Field: "promotions[0].usageLimit", Message: "must not be negative"},
+				{Field: "promotions[1].couponCode", Message: `"SAVE10" is already used by another promotion`},
+				{Field: "promotions[2].usageLimitPerCustomer", Message: "requires a couponCode"},
+			},
+		},
+		{
+			name: "should reject numbers that are not finite",
+			data: `
+promotions:
+  - id: p1
+    name: P1
+    type: product_percentage_discount
+    parameters: {productId: a, minQuantity: 1, discountInPercent: .nan}
+  - id: p2
+    name: P2
+    type: cart_threshold_discount
+    parameters:
+      currencyCode: USD
+      tiers:
+        - {minSubtotal: .inf, discountInPercent: -.inf}
+  - id: p3
+    name: P3
+    type: product_quantity_discount
+    parameters: {productId: a, requiredQuantity: -.inf, discountedQuantity: .inf}
+`,
+			wantFields: []domain.FieldError{
+				{Field: "promotions[0].parameters.discountInPercent", Message: "must be a finite number"},
+				{Field: "promotions[1].parameters.tiers[0].minSubtotal", Message: "must be an amount of USD"},
+				{Field: "promotions[1].parameters.tiers[0].discountInPercent", Message: "must be a finite number"},
+				{Field: "promotions[2].parameters.requiredQuantity", Message: "must be a positive integer"},
+				{Field: "promotions[2].parameters.discountedQuantity", Message: "must be a positive integer"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse([]byte(test.data), FormatYAML)
+			assert.ErrorIs(t, err, domain.ErrInvalidPromotion)
+
+			var validationErr *domain.ValidationError
+			require.True(t, errors.As(err, &validationErr))
+			assert.Equal(t, test.wantFields, validationErr.Fields)
+		})
+	}
+}
+
+func TestParse_Malformed(t *testing.T) {
+	_, err := Parse([]byte(`{"promotions": [{"id": "p1", "kind": "x"}]}`), FormatJSON)
+	assert.ErrorIs(t, err, domain.ErrInvalidPromotion)
+
+	_, err = Parse([]byte("promotions:\n  - id: [p1"), FormatYAML)
+	assert.ErrorIs(t, err, domain.ErrInvalidPromotion)
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "promotions.yml")
+	require.NoError(t, os.WriteFile(path, []byte("promotions: []\n"), 0o600))
+	got, err := Load(path)
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+
+	path = filepath.Join(dir, "promotions.toml")
+	require.NoError(t, os.WriteFile(path, []byte(""), 0o600))
+	_, err = Load(path)
+	assert.Error(t, err)
+}
+
+func TestNewFile(t *testing.T) {
+	promotions := []domain.Promotion{
+		{
+			ID:   "googlehome-3-for-2",
+			Name: "3 Google Homes for the price of 2",
+			Condition: promotioncondition.ProductQuantityDiscount{
+				ProductID:          "googlehome",
+				RequiredQuantity:   3,
+				DiscountedQuantity: 1,
+			},
+			Priority:      5,
+			StackGroup:    "googlehome",
+			MaxOnePerLine: true,
+
+			CouponCode:            "THREE",
+			UsageLimit:            10,
+			UsageLimitPerCustomer: 2,
+		},
+		{
+			ID:        "alexaspeaker-10-percent",
+			Name:      "10% off Alexa Speakers when buying 3 or more",
+			StartDate: time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC),
+			Condition: promotioncondition.ProductPercentageDiscount{
+				ProductID:         "alexaspeaker",
+				MinQuantity:       3,
+				DiscountInPercent: 10,
+			},
+			Exclusive: true,
+		},
+		{
+			ID:   "spend-500-get-50-off",
+			Name: "50 off orders of 500 or more, 10% off orders of 1000 or more",
+			Condition: promotioncondition.CartThresholdDiscount{
+				Tiers: []promotioncondition.CartThresholdTier{
+					{MinSubtotal: domain.MustParseMoney("500", "USD"), Amount: domain.MustParseMoney("50", "USD")},
+					{MinSubtotal: domain.MustParseMoney("1000", "USD"), DiscountInPercent: 10},
+				},
+				ExcludeDiscountedLines: true,
+			},
+		},
+	}
+
+	for _, format := range []Format{FormatJSON, FormatYAML} {
+		t.Run(string(format), func(t *testing.T) {
+			file, err := NewFile(promotions, promotioncondition.DefaultRegistry)
+			require.NoError(t, err)
+
+			data, err := file.Marshal(format)
+			require.NoError(t, err)
+
+			got, err := Parse(data, format)
+			assert.NoError(t, err)
+			assert.Equal(t, promotions, got)
+		})
+	}
+}
+
+func TestFile_BuildWith(t *testing.T) {
+	registry := promotioncondition.NewRegistry()
+	require.NoError(t, registry.Register(promotioncondition.ConditionType{
+		Name: "loyalty",
+		New: func(params *promotioncondition.Parameters) domain.PromotionCondition {
+			return promotioncondition.ProductPercentageDiscount{
+				ProductID:         params.String("productId"),
+				MinQuantity:       1,
+				DiscountInPercent: 5,
+			}
+		},
+	}))
+
+	file := File{Promotions: []Promotion{
+		{ID: "p1", Name: "P1", Type: "loyalty", Parameters: map[string]interface{}{"productId": "p01"}},
+	}}
+
+	got, err := file.BuildWith(registry)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, promotioncondition.ProductPercentageDiscount{ProductID: "p01", MinQuantity: 1, DiscountInPercent: 5}, got[0].Condition)
+
+	_, err = file.Build()
+	assert.ErrorIs(t, err, domain.ErrInvalidPromotion, "should not know the type outside of its registry")
+}
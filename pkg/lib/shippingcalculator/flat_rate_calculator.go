@@ -0,0 +1,13 @@
+// Package shippingcalculator implements the ways shipping cost is computed.
+package shippingcalculator
+
+import "github.com/donnpebe/shoppo/pkg/domain"
+
+// FlatRate costs the same whatever is shipped.
+type FlatRate struct {
+	Cost domain.Money
+}
+
+func (calc FlatRate) CalculateShippingCost(shipment *domain.Shipment) (domain.Money, error) {
+	return calc.Cost, nil
+}
@@ -0,0 +1,21 @@
+package shippingcalculator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+func TestFlatRate_CalculateShippingCost(t *testing.T) {
+	calc := FlatRate{Cost: domain.MustParseMoney("15", "USD")}
+
+	cost, err := calc.CalculateShippingCost(&domain.Shipment{
+		Items: []domain.ShipmentItem{
+			{Product: &domain.Product{ID: "p01", Weight: 25000}, Quantity: 3},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, domain.MustParseMoney("15", "USD"), cost)
+}
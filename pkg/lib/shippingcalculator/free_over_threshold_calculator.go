@@ -0,0 +1,24 @@
+package shippingcalculator
+
+import "github.com/donnpebe/shoppo/pkg/domain"
+
+// FreeOverThreshold ships for free the shipments worth at least
+// Threshold and costs what Calculator says otherwise.
+type FreeOverThreshold struct {
+	Threshold  domain.Money
+	Calculator domain.ShippingCalculator
+}
+
+// CalculateShippingCost returns ErrCurrencyMismatch for a shipment in
+// another currency than Threshold.
+func (calc FreeOverThreshold) CalculateShippingCost(shipment *domain.Shipment) (domain.Money, error) {
+	if err := shipment.Value.MatchCurrency(calc.Threshold); err != nil {
+		return domain.Money{}, err
+	}
+
+	if shipment.Value.Cmp(calc.Threshold) >= 0 {
+		return domain.NewMoney(0, calc.Threshold.Currency), nil
+	}
+
+	return calc.Calculator.CalculateShippingCost(shipment)
+}
@@ -0,0 +1,56 @@
+package shippingcalculator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+func TestFreeOverThreshold_CalculateShippingCost(t *testing.T) {
+	calc := FreeOverThreshold{
+		Threshold:  domain.MustParseMoney("100", "USD"),
+		Calculator: FlatRate{Cost: domain.MustParseMoney("10", "USD")},
+	}
+
+	tests := []struct {
+		name  string
+		value domain.Money
+		want  domain.Money
+	}{
+		{
+			name:  "should cost what the calculator says below threshold",
+			value: domain.MustParseMoney("99.99", "USD"),
+			want:  domain.MustParseMoney("10", "USD"),
+		},
+		{
+			name:  "should be free at threshold",
+			value: domain.MustParseMoney("100", "USD"),
+			want:  domain.NewMoney(0, "USD"),
+		},
+		{
+			name:  "should be free over threshold",
+			value: domain.MustParseMoney("250", "USD"),
+			want:  domain.NewMoney(0, "USD"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cost, err := calc.CalculateShippingCost(&domain.Shipment{Value: test.value})
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, cost)
+		})
+	}
+}
+
+func TestFreeOverThreshold_CurrencyMismatch(t *testing.T) {
+	calc := FreeOverThreshold{
+		Threshold:  domain.MustParseMoney("100", "USD"),
+		Calculator: FlatRate{Cost: domain.MustParseMoney("10", "USD")},
+	}
+
+	_, err := calc.CalculateShippingCost(&domain.Shipment{Value: domain.MustParseMoney("2000000", "IDR")})
+	assert.ErrorIs(t, err, domain.ErrCurrencyMismatch)
+}
@@ -0,0 +1,55 @@
+package shippingcalculator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+// PerRegion costs what the calculator of the first region matching the
+// shipping address says, shipments to other regions are not eligible.
+type PerRegion struct {
+	Regions []Region
+}
+
+// Region is a country, or some provinces of it.
+type Region struct {
+	Country string
+	// Provinces restricts the region to some provinces of the country,
+	// the region is the whole country when empty.
+	Provinces  []string
+	Calculator domain.ShippingCalculator
+}
+
+func (calc PerRegion) CalculateShippingCost(shipment *domain.Shipment) (domain.Money, error) {
+	if shipment.Address == nil {
+		return domain.Money{}, fmt.Errorf("%w: no shipping address", domain.ErrShippingMethodNotEligible)
+	}
+
+	for _, region := range calc.Regions {
+		if region.matches(shipment.Address) {
+			return region.Calculator.CalculateShippingCost(shipment)
+		}
+	}
+
+	return domain.Money{}, fmt.Errorf("%w: %s is not served", domain.ErrShippingMethodNotEligible, shipment.Address.Country)
+}
+
+func (region Region) matches(address *domain.Address) bool {
+	if !strings.EqualFold(region.Country, address.Country) {
+		return false
+	}
+
+	if len(region.Provinces) == 0 {
+		return true
+	}
+
+	for _, province := range region.Provinces {
+		if strings.EqualFold(province, address.Province) {
+			return true
+		}
+	}
+
+	return false
+}
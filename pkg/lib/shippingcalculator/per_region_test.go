@@ -0,0 +1,65 @@
+package shippingcalculator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+func TestPerRegion_CalculateShippingCost(t *testing.T) {
+	calc := PerRegion{
+		Regions: []Region{
+			{
+				Country:    "ID",
+				Provinces:  []string{"DKI Jakarta", "Banten"},
+				Calculator: FlatRate{Cost: domain.MustParseMoney("2", "USD")},
+			},
+			{
+				Country:    "ID",
+				Calculator: FlatRate{Cost: domain.MustParseMoney("5", "USD")},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		address *domain.Address
+		want    domain.Money
+		wantErr error
+	}{
+		{
+			name:    "should use the first matching region",
+			address: &domain.Address{Country: "ID", Province: "dki jakarta"},
+			want:    domain.MustParseMoney("2", "USD"),
+		},
+		{
+			name:    "should fall back to the whole country",
+			address: &domain.Address{Country: "id", Province: "Bali"},
+			want:    domain.MustParseMoney("5", "USD"),
+		},
+		{
+			name:    "should not be eligible for unserved countries",
+			address: &domain.Address{Country: "SG", Province: "Singapore"},
+			wantErr: domain.ErrShippingMethodNotEligible,
+		},
+		{
+			name:    "should not be eligible without shipping address",
+			wantErr: domain.ErrShippingMethodNotEligible,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cost, err := calc.CalculateShippingCost(&domain.Shipment{Address: test.address})
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, cost)
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package shippingcalculator
+
+import (
+	"fmt"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+// WeightBased costs BaseCost plus CostPerKg for every started kilogram.
+type WeightBased struct {
+	BaseCost  domain.Money
+	CostPerKg domain.Money
+	// VolumetricDivisor, in cubic centimetres per kilogram, makes bulky
+	// shipments charged by their volumetric weight when it is more than
+	// their actual weight. Couriers commonly use 5000 or 6000, it is
+	// ignored when zero.
+	VolumetricDivisor int
+	// MaxWeight in grams above which the shipment is not eligible, there
+	// is no limit when zero.
+	MaxWeight int
+}
+
+func (calc WeightBased) CalculateShippingCost(shipment *domain.Shipment) (domain.Money, error) {
+	weight := shipment.Weight()
+	if calc.MaxWeight > 0 && weight > calc.MaxWeight {
+		return domain.Money{}, fmt.Errorf("%w: %d g is over %d g", domain.ErrShippingMethodNotEligible, weight, calc.MaxWeight)
+	}
+
+	if calc.VolumetricDivisor > 0 {
+		volumetricWeight := shipment.Volume() * 1000 / calc.VolumetricDivisor
+		if volumetricWeight > weight {
+			weight = volumetricWeight
+		}
+	}
+
+	kilograms := (weight + 999) / 1000
+
+	return calc.BaseCost.Add(calc.CostPerKg.Mul(kilograms)), nil
+}
@@ -0,0 +1,75 @@
+package shippingcalculator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+func TestWeightBased_CalculateShippingCost(t *testing.T) {
+	calc := WeightBased{
+		BaseCost:          domain.MustParseMoney("5", "USD"),
+		CostPerKg:         domain.MustParseMoney("2", "USD"),
+		VolumetricDivisor: 5000,
+		MaxWeight:         30000,
+	}
+
+	tests := []struct {
+		name    string
+		items   []domain.ShipmentItem
+		want    domain.Money
+		wantErr error
+	}{
+		{
+			name: "should charge every started kilogram",
+			items: []domain.ShipmentItem{
+				{Product: &domain.Product{ID: "p01", Weight: 600}, Quantity: 2},
+			},
+			want: domain.MustParseMoney("9", "USD"),
+		},
+		{
+			name: "should charge the base cost only for weightless products",
+			items: []domain.ShipmentItem{
+				{Product: &domain.Product{ID: "p01"}, Quantity: 1},
+			},
+			want: domain.MustParseMoney("5", "USD"),
+		},
+		{
+			name: "should charge bulky shipments by their volumetric weight",
+			items: []domain.ShipmentItem{
+				{
+					Product: &domain.Product{
+						ID:         "p01",
+						Weight:     1000,
+						Dimensions: domain.Dimensions{Length: 500, Width: 400, Height: 300},
+					},
+					Quantity: 1,
+				},
+			},
+			// 60000 cm³ / 5000 cm³ per kg = 12 kg
+			want: domain.MustParseMoney("29", "USD"),
+		},
+		{
+			name: "should not be eligible for shipments over max weight",
+			items: []domain.ShipmentItem{
+				{Product: &domain.Product{ID: "p01", Weight: 10001}, Quantity: 3},
+			},
+			wantErr: domain.ErrShippingMethodNotEligible,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cost, err := calc.CalculateShippingCost(&domain.Shipment{Items: test.items})
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, cost)
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package services
+
+import "sync"
+
+// orderLocks hands out one mutex per order id, so edits to the same order
+// are serialized while different orders can still be edited concurrently.
+type orderLocks struct {
+	mu    sync.Mutex
+	locks map[string]*orderLock
+}
+
+type orderLock struct {
+	sync.Mutex
+	refs int
+}
+
+func newOrderLocks() *orderLocks {
+	return &orderLocks{
+		locks: make(map[string]*orderLock),
+	}
+}
+
+// lock blocks until the lock of orderID is acquired and returns the
+// function releasing it.
+func (l *orderLocks) lock(orderID string) (unlock func()) {
+	l.mu.Lock()
+	lock, ok := l.locks[orderID]
+	if !ok {
+		lock = &orderLock{}
+		l.locks[orderID] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	lock.Lock()
+
+	return func() {
+		lock.Unlock()
+
+		l.mu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(l.locks, orderID)
+		}
+		l.mu.Unlock()
+	}
+}
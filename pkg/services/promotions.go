@@ -0,0 +1,275 @@
+package services
+
+import (
+	"sort"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+// candidate is a promotion with the discount its condition gives on
+// each line of the order. The discount of a cart condition depends on the
+// promotions applied before it and is only known once they are.
+type candidate struct {
+	promotion domain.Promotion
+	discounts []domain.LineDiscount
+	cart      domain.CartDiscounter
+}
+
+// candidates returns the promotions that give a discount on the order,
+// sorted from the highest priority down, ties broken by ID so the outcome
+// never depends on the order they are configured in.
+func candidates(order *domain.Order, promotions []domain.Promotion) []candidate {
+	sorted := append([]domain.Promotion(nil), promotions...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Priority != sorted[j].Priority {
+			return sorted[i].Priority > sorted[j].Priority
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	var found []candidate
+	for _, promotion := range sorted {
+		discounts := domain.LineDiscounterOf(promotion.Condition).CalculateLineDiscounts(order)
+		// A cart condition discounts no more once products are discounted
+		// than it does on its own, what it gives on its own tells whether
+		// it may apply.
+		for _, discount := range discounts {
+			if discount.Amount.IsNegative() {
+				cart, _ := promotion.Condition.(domain.CartDiscounter)
+				found = append(found, candidate{promotion: promotion, discounts: discounts, cart: cart})
+				break
+			}
+		}
+	}
+
+	return found
+}
+
+// applyPromotions chooses which of promotions apply to the order and
+// returns their discount, see combine.
+func applyPromotions(order *domain.Order, promotions []domain.Promotion) []domain.AppliedPromotion {
+	return combine(order, candidates(order, promotions))
+}
+
+// combine applies the candidates in turn. A promotion is skipped when
+// another one of its stack group already applied, an exclusive promotion
+// only applies on its own. No line is ever discounted below zero, a
+// promotion only gets what is left of the lines it discounts and is
+// skipped when nothing is left.
+//
+// Cart conditions are evaluated on the order discounted by the promotions
+// on products: when their turn comes they take their stack group, then
+// they are applied once every promotion on products is. An exclusive cart
+// promotion applies right away since it applies alone.
+func combine(order *domain.Order, candidates []candidate) []domain.AppliedPromotion {
+	remaining := make(map[string]domain.Money, len(order.Lines))
+	for _, line := range order.Lines {
+		remaining[line.ID] = line.Subtotal()
+	}
+	// discounted holds the lines that got a discount, locked the ones
+	// no other promotion may discount.
+	discounted := make(map[string]bool)
+	locked := make(map[string]bool)
+	usedGroups := make(map[string]bool)
+
+	var applied []domain.AppliedPromotion
+	// grant applies what is left of the discounts of promotion, it
+	// returns false when nothing is.
+	grant := func(promotion domain.Promotion, discounts []domain.LineDiscount) bool {
+		var granted []domain.LineDiscount
+		var total domain.Money
+		for _, discount := range discounts {
+			left, ok := remaining[discount.OrderLineID]
+			if !ok || locked[discount.OrderLineID] || !discount.Amount.IsNegative() {
+				continue
+			}
+
+			// A discount in another currency than the line doesn't apply.
+			if left.MatchCurrency(discount.Amount) != nil {
+				continue
+			}
+
+			if promotion.MaxOnePerLine && discounted[discount.OrderLineID] {
+				continue
+			}
+
+			amount := discount.Amount
+			if amount.Neg().Cmp(left) > 0 {
+				amount = left.Neg()
+			}
+			if amount.IsZero() {
+				continue
+			}
+
+			granted = append(granted, domain.LineDiscount{OrderLineID: discount.OrderLineID, Quantity: discount.Quantity, Amount: amount})
+			total = total.Add(amount)
+		}
+
+		if total.IsZero() {
+			return false
+		}
+
+		for _, discount := range granted {
+			remaining[discount.OrderLineID] = remaining[discount.OrderLineID].Add(discount.Amount)
+			discounted[discount.OrderLineID] = true
+			if promotion.MaxOnePerLine {
+				locked[discount.OrderLineID] = true
+			}
+		}
+
+		applied = append(applied, domain.AppliedPromotion{
+			PromotionID: promotion.ID,
+			Name:        promotion.Name,
+			Amount:      total,
+			Lines:       granted,
+		})
+
+		return true
+	}
+
+	var carts []candidate
+	for _, candidate := range candidates {
+		promotion := candidate.promotion
+		if promotion.Exclusive && (len(applied) > 0 || len(carts) > 0) {
+			continue
+		}
+
+		if promotion.StackGroup != "" && usedGroups[promotion.StackGroup] {
+			continue
+		}
+
+		if candidate.cart != nil && !promotion.Exclusive {
+			carts = append(carts, candidate)
+			if promotion.StackGroup != "" {
+				usedGroups[promotion.StackGroup] = true
+			}
+			continue
+		}
+
+		discounts := candidate.discounts
+		if candidate.cart != nil {
+			discounts = candidate.cart.CalculateCartDiscounts(order, nil)
+		}
+		if !grant(promotion, discounts) {
+			continue
+		}
+
+		if promotion.StackGroup != "" {
+			usedGroups[promotion.StackGroup] = true
+		}
+
+		if promotion.Exclusive {
+			break
+		}
+	}
+
+	if len(carts) == 0 {
+		return applied
+	}
+
+	// productDiscounts holds the discount of each line once the
+	// promotions on products are applied.
+	productDiscounts := make(map[string]domain.Money, len(order.Lines))
+	for _, line := range order.Lines {
+		if discount := remaining[line.ID].Sub(line.Subtotal()); !discount.IsZero() {
+			productDiscounts[line.ID] = discount
+		}
+	}
+	for _, candidate := range carts {
+		grant(candidate.promotion, candidate.cart.CalculateCartDiscounts(order, productDiscounts))
+	}
+
+	return applied
+}
+
+// bestDeal chooses the promotions that give the order its lowest total.
+//
+// Promotions that can't apply together, because they are exclusive,
+// share a stack group or a line of a max one per line promotion, compete
+// for the lines of the order: rather than letting the one of highest
+// priority win, bestDeal combines every subset of the promotions and
+// keeps the one with the largest discount. Ties go to the subset with
+// the most promotions of highest priority.
+//
+// At most maxCombinations subsets are evaluated. When there are more,
+// bestDeal starts from every promotion and keeps leaving out the
+// promotion whose absence saves the most until leaving out any promotion
+// no longer helps or the budget is spent.
+func bestDeal(order *domain.Order, promotions []domain.Promotion, maxCombinations int) []domain.AppliedPromotion {
+	found := candidates(order, promotions)
+	best := combine(order, found)
+	if len(found) < 2 {
+		return best
+	}
+	bestDiscount := sumDiscounts(best)
+
+	evaluate := func(included []bool) ([]domain.AppliedPromotion, domain.Money) {
+		subset := make([]candidate, 0, len(found))
+		for i, candidate := range found {
+			if included[i] {
+				subset = append(subset, candidate)
+			}
+		}
+
+		applied := combine(order, subset)
+		return applied, sumDiscounts(applied)
+	}
+
+	// Subsets are enumerated from every promotion down, so that the ones
+	// with more promotions of higher priority come first.
+	if len(found) < 63 && int64(1)<<len(found) <= int64(maxCombinations) {
+		included := make([]bool, len(found))
+		for mask := int64(1)<<len(found) - 2; mask > 0; mask-- {
+			for i := range found {
+				included[i] = mask&(int64(1)<<(len(found)-1-i)) != 0
+			}
+
+			applied, discount := evaluate(included)
+			if discount.Cmp(bestDiscount) < 0 {
+				best, bestDiscount = applied, discount
+			}
+		}
+
+		return best
+	}
+
+	included := make([]bool, len(found))
+	for i := range included {
+		included[i] = true
+	}
+
+	evaluations := 1
+	for evaluations < maxCombinations {
+		improvement := -1
+		for i := range found {
+			if !included[i] || evaluations >= maxCombinations {
+				continue
+			}
+
+			included[i] = false
+			applied, discount := evaluate(included)
+			included[i] = true
+			evaluations++
+
+			if discount.Cmp(bestDiscount) < 0 {
+				best, bestDiscount, improvement = applied, discount, i
+			}
+		}
+
+		if improvement < 0 {
+			break
+		}
+		included[improvement] = false
+	}
+
+	return best
+}
+
+func sumDiscounts(discounts []domain.AppliedPromotion) domain.Money {
+	var total domain.Money
+	for _, discount := range discounts {
+		total = total.Add(discount.Amount)
+	}
+
+	return total
+}
@@ -0,0 +1,481 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+	"github.com/donnpebe/shoppo/pkg/lib/promotioncondition"
+)
+
+// fixedDiscount is a condition that doesn't tell which lines it discounts.
+type fixedDiscount struct {
+	amount domain.Money
+}
+
+func (cond fixedDiscount) CalculateDiscount(order *domain.Order) domain.Money {
+	return cond.amount
+}
+
+// withoutLines returns discounts without the share of each line.
+func withoutLines(discounts []domain.AppliedPromotion) []domain.AppliedPromotion {
+	var stripped []domain.AppliedPromotion
+	for _, discount := range discounts {
+		discount.Lines = nil
+		stripped = append(stripped, discount)
+	}
+
+	return stripped
+}
+
+func TestApplyPromotions(t *testing.T) {
+	order := &domain.Order{
+		Lines: []*domain.OrderLine{
+			{ID: "l1", ProductID: "googlehome", Quantity: 3, UnitPrice: domain.MustParseMoney("49.99", "USD")},
+			{ID: "l2", ProductID: "alexaspeaker", Quantity: 3, UnitPrice: domain.MustParseMoney("109.50", "USD")},
+			{ID: "l3", ProductID: "raspberrypi", Quantity: 1, UnitPrice: domain.MustParseMoney("30", "USD")},
+		},
+	}
+
+	googleHome3For2 := domain.Promotion{
+		ID:        "googlehome-3-for-2",
+		Condition: promotioncondition.ProductQuantityDiscount{ProductID: "googlehome", RequiredQuantity: 3, DiscountedQuantity: 1},
+	}
+	googleHome10Percent := domain.Promotion{
+		ID:        "googlehome-10-percent",
+		Condition: promotioncondition.ProductPercentageDiscount{ProductID: "googlehome", MinQuantity: 3, DiscountInPercent: 10},
+	}
+	googleHomeFree := domain.Promotion{
+		ID:        "googlehome-free",
+		Condition: promotioncondition.ProductQuantityDiscount{ProductID: "googlehome", RequiredQuantity: 3, DiscountedQuantity: 3},
+	}
+	alexa10Percent := domain.Promotion{
+		ID:        "alexaspeaker-10-percent",
+		Condition: promotioncondition.ProductPercentageDiscount{ProductID: "alexaspeaker", MinQuantity: 3, DiscountInPercent: 10},
+	}
+	freeRaspberryPi := domain.Promotion{
+		ID:        "free-raspberrypi",
+		Condition: promotioncondition.BuyXProductGetFreeProductCondition{XProductID: "googlehome", FreeProductID: "raspberrypi"},
+	}
+
+	with := func(promotion domain.Promotion, set func(promotion *domain.Promotion)) domain.Promotion {
+		set(&promotion)
+		return promotion
+	}
+	applied := func(promotionID string, amount string) domain.AppliedPromotion {
+		return domain.AppliedPromotion{PromotionID: promotionID, Amount: domain.MustParseMoney(amount, "USD")}
+	}
+
+	tests := []struct {
+		name       string
+		promotions []domain.Promotion
+		want       []domain.AppliedPromotion
+	}{
+		{
+			name:       "should stack promotions on the same line by default",
+			promotions: []domain.Promotion{googleHome3For2, googleHome10Percent},
+			want: []domain.AppliedPromotion{
+				applied("googlehome-10-percent", "-15"),
+				applied("googlehome-3-for-2", "-49.99"),
+			},
+		},
+		{
+			name: "should apply promotions by priority then by id",
+			promotions: []domain.Promotion{
+				freeRaspberryPi,
+				alexa10Percent,
+				with(googleHome3For2, func(p *domain.Promotion) { p.Priority = 1 }),
+			},
+			want: []domain.AppliedPromotion{
+				applied("googlehome-3-for-2", "-49.99"),
+				applied("alexaspeaker-10-percent", "-32.85"),
+				applied("free-raspberrypi", "-30"),
+			},
+		},
+		{
+			name: "should apply only the promotion of highest priority of a stack group",
+			promotions: []domain.Promotion{
+				with(googleHome3For2, func(p *domain.Promotion) { p.StackGroup = "googlehome"; p.Priority = 1 }),
+				with(googleHome10Percent, func(p *domain.Promotion) { p.StackGroup = "googlehome"; p.Priority = 2 }),
+				alexa10Percent,
+			},
+			want: []domain.AppliedPromotion{
+				applied("googlehome-10-percent", "-15"),
+				applied("alexaspeaker-10-percent", "-32.85"),
+			},
+		},
+		{
+			name: "should skip a stack group member that gives no discount",
+			promotions: []domain.Promotion{
+				with(freeRaspberryPi, func(p *domain.Promotion) {
+					p.StackGroup = "googlehome"
+					p.Priority = 2
+					p.Condition = promotioncondition.BuyXProductGetFreeProductCondition{XProductID: "macbookpro", FreeProductID: "raspberrypi"}
+				}),
+				with(googleHome3For2, func(p *domain.Promotion) { p.StackGroup = "googlehome" }),
+			},
+			want: []domain.AppliedPromotion{
+				applied("googlehome-3-for-2", "-49.99"),
+			},
+		},
+		{
+			name: "should apply an exclusive promotion alone",
+			promotions: []domain.Promotion{
+				googleHome3For2,
+				with(alexa10Percent, func(p *domain.Promotion) { p.Exclusive = true; p.Priority = 1 }),
+			},
+			want: []domain.AppliedPromotion{
+				applied("alexaspeaker-10-percent", "-32.85"),
+			},
+		},
+		{
+			name: "should skip an exclusive promotion once another one applied",
+			promotions: []domain.Promotion{
+				with(googleHome3For2, func(p *domain.Promotion) { p.Priority = 1 }),
+				with(alexa10Percent, func(p *domain.Promotion) { p.Exclusive = true }),
+				freeRaspberryPi,
+			},
+			want: []domain.AppliedPromotion{
+				applied("googlehome-3-for-2", "-49.99"),
+				applied("free-raspberrypi", "-30"),
+			},
+		},
+		{
+			name: "should keep other promotions off the lines of a max one per line promotion",
+			promotions: []domain.Promotion{
+				with(googleHome3For2, func(p *domain.Promotion) { p.MaxOnePerLine = true; p.Priority = 1 }),
+				googleHome10Percent,
+				alexa10Percent,
+			},
+			want: []domain.AppliedPromotion{
+				applied("googlehome-3-for-2", "-49.99"),
+				applied("alexaspeaker-10-percent", "-32.85"),
+			},
+		},
+		{
+			name: "should keep a max one per line promotion off lines already discounted",
+			promotions: []domain.Promotion{
+				with(googleHome3For2, func(p *domain.Promotion) { p.MaxOnePerLine = true }),
+				with(googleHome10Percent, func(p *domain.Promotion) { p.Priority = 1 }),
+			},
+			want: []domain.AppliedPromotion{
+				applied("googlehome-10-percent", "-15"),
+			},
+		},
+		{
+			name: "should never discount a line below zero",
+			promotions: []domain.Promotion{
+				with(googleHome3For2, func(p *domain.Promotion) { p.Priority = 1 }),
+				googleHomeFree,
+				freeRaspberryPi,
+				with(freeRaspberryPi, func(p *domain.Promotion) { p.ID = "free-raspberrypi-again" }),
+			},
+			want: []domain.AppliedPromotion{
+				applied("googlehome-3-for-2", "-49.99"),
+				applied("free-raspberrypi", "-30"),
+				applied("googlehome-free", "-99.98"),
+			},
+		},
+		{
+			name: "should cap the discount of conditions that don't tell which lines they discount",
+			promotions: []domain.Promotion{
+				alexa10Percent,
+				{ID: "everything-free", Condition: fixedDiscount{amount: domain.MustParseMoney("-600", "USD")}},
+			},
+			want: []domain.AppliedPromotion{
+				applied("alexaspeaker-10-percent", "-32.85"),
+				applied("everything-free", "-475.62"),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := applyPromotions(order, test.promotions)
+			assert.Equal(t, test.want, withoutLines(got))
+		})
+	}
+}
+
+func TestBestDeal(t *testing.T) {
+	order := &domain.Order{
+		Lines: []*domain.OrderLine{
+			{ID: "l1", ProductID: "googlehome", Quantity: 3, UnitPrice: domain.MustParseMoney("49.99", "USD")},
+			{ID: "l2", ProductID: "alexaspeaker", Quantity: 3, UnitPrice: domain.MustParseMoney("109.50", "USD")},
+		},
+	}
+
+	googleHome3For2 := domain.Promotion{
+		ID:         "googlehome-3-for-2",
+		StackGroup: "googlehome",
+		Condition:  promotioncondition.ProductQuantityDiscount{ProductID: "googlehome", RequiredQuantity: 3, DiscountedQuantity: 1},
+	}
+	googleHome10Percent := domain.Promotion{
+		ID:         "googlehome-10-percent",
+		Priority:   1,
+		StackGroup: "googlehome",
+		Condition:  promotioncondition.ProductPercentageDiscount{ProductID: "googlehome", MinQuantity: 3, DiscountInPercent: 10},
+	}
+	alexa10Percent := domain.Promotion{
+		ID:        "alexaspeaker-10-percent",
+		Condition: promotioncondition.ProductPercentageDiscount{ProductID: "alexaspeaker", MinQuantity: 3, DiscountInPercent: 10},
+	}
+	exclusive := func(amount string) domain.Promotion {
+		return domain.Promotion{
+			ID:        "exclusive",
+			Exclusive: true,
+			Condition: fixedDiscount{amount: domain.MustParseMoney(amount, "USD")},
+		}
+	}
+	applied := func(promotionID string, amount string) domain.AppliedPromotion {
+		return domain.AppliedPromotion{PromotionID: promotionID, Amount: domain.MustParseMoney(amount, "USD")}
+	}
+
+	tests := []struct {
+		name            string
+		promotions      []domain.Promotion
+		maxCombinations int
+		want            []domain.AppliedPromotion
+	}{
+		{
+			name:            "should choose the promotion of a stack group that saves the most",
+			promotions:      []domain.Promotion{googleHome10Percent, googleHome3For2},
+			maxCombinations: DefaultBestDealCombinations,
+			want:            []domain.AppliedPromotion{applied("googlehome-3-for-2", "-49.99")},
+		},
+		{
+			name:            "should choose an exclusive promotion when it saves more than the others together",
+			promotions:      []domain.Promotion{googleHome3For2, alexa10Percent, exclusive("-100")},
+			maxCombinations: DefaultBestDealCombinations,
+			want:            []domain.AppliedPromotion{applied("exclusive", "-100")},
+		},
+		{
+			name:            "should leave out an exclusive promotion when the others together save more",
+			promotions:      []domain.Promotion{exclusive("-80"), googleHome3For2, alexa10Percent},
+			maxCombinations: DefaultBestDealCombinations,
+			want: []domain.AppliedPromotion{
+				applied("alexaspeaker-10-percent", "-32.85"),
+				applied("googlehome-3-for-2", "-49.99"),
+			},
+		},
+		{
+			name: "should leave out a max one per line promotion that blocks a better one",
+			promotions: []domain.Promotion{
+				{
+					ID:            "googlehome-10-percent",
+					Priority:      1,
+					MaxOnePerLine: true,
+					Condition:     googleHome10Percent.Condition,
+				},
+				{ID: "googlehome-3-for-2", Condition: googleHome3For2.Condition},
+			},
+			maxCombinations: DefaultBestDealCombinations,
+			want:            []domain.AppliedPromotion{applied("googlehome-3-for-2", "-49.99")},
+		},
+		{
+			name: "should prefer the promotion of highest priority on ties",
+			promotions: []domain.Promotion{
+				{ID: "a", StackGroup: "g", Condition: fixedDiscount{amount: domain.MustParseMoney("-10", "USD")}},
+				{ID: "b", StackGroup: "g", Priority: 1, Condition: fixedDiscount{amount: domain.MustParseMoney("-10", "USD")}},
+			},
+			maxCombinations: DefaultBestDealCombinations,
+			want:            []domain.AppliedPromotion{applied("b", "-10")},
+		},
+		{
+			name:            "should leave out promotions one at a time when there are too many combinations",
+			promotions:      []domain.Promotion{googleHome10Percent, googleHome3For2, alexa10Percent},
+			maxCombinations: 2,
+			want: []domain.AppliedPromotion{
+				applied("alexaspeaker-10-percent", "-32.85"),
+				applied("googlehome-3-for-2", "-49.99"),
+			},
+		},
+		{
+			name:            "should apply promotions by priority when the budget is spent",
+			promotions:      []domain.Promotion{googleHome10Percent, googleHome3For2, alexa10Percent},
+			maxCombinations: 1,
+			want: []domain.AppliedPromotion{
+				applied("googlehome-10-percent", "-15"),
+				applied("alexaspeaker-10-percent", "-32.85"),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := bestDeal(order, test.promotions, test.maxCombinations)
+			assert.Equal(t, test.want, withoutLines(got))
+		})
+	}
+}
+
+func TestApplyPromotions_Lines(t *testing.T) {
+	order := &domain.Order{
+		Lines: []*domain.OrderLine{
+			{ID: "l1", ProductID: "googlehome", Quantity: 3, UnitPrice: domain.MustParseMoney("49.99", "USD")},
+			{ID: "l2", ProductID: "raspberrypi", Quantity: 2, UnitPrice: domain.MustParseMoney("30", "USD")},
+		},
+	}
+	promotions := []domain.Promotion{
+		{
+			ID:        "googlehome-free",
+			Priority:  1,
+			Condition: promotioncondition.ProductQuantityDiscount{ProductID: "googlehome", RequiredQuantity: 3, DiscountedQuantity: 3},
+		},
+		{
+			ID:        "googlehome-3-for-2",
+			Condition: promotioncondition.ProductQuantityDiscount{ProductID: "googlehome", RequiredQuantity: 3, DiscountedQuantity: 1},
+		},
+		{
+			ID:        "raspberrypi-free",
+			Condition: promotioncondition.BuyXProductGetFreeProductCondition{XProductID: "googlehome", FreeProductID: "raspberrypi"},
+		},
+		{ID: "ten-off", Condition: fixedDiscount{amount: domain.MustParseMoney("-10", "USD")}},
+	}
+
+	got := applyPromotions(order, promotions)
+	assert.Equal(t, []domain.AppliedPromotion{
+		{
+			PromotionID: "googlehome-free",
+			Amount:      domain.MustParseMoney("-149.97", "USD"),
+			Lines: []domain.LineDiscount{
+				{OrderLineID: "l1", Quantity: 3, Amount: domain.MustParseMoney("-149.97", "USD")},
+			},
+		},
+		{
+			PromotionID: "raspberrypi-free",
+			Amount:      domain.MustParseMoney("-60", "USD"),
+			Lines: []domain.LineDiscount{
+				{OrderLineID: "l2", Quantity: 2, Amount: domain.MustParseMoney("-60", "USD")},
+			},
+		},
+	}, got)
+}
+
+func TestApplyPromotions_Cart(t *testing.T) {
+	order := &domain.Order{
+		Lines: []*domain.OrderLine{
+			{ID: "l1", ProductID: "googlehome", Quantity: 3, UnitPrice: domain.MustParseMoney("49.99", "USD")},
+			{ID: "l2", ProductID: "kindle", Quantity: 1, UnitPrice: domain.MustParseMoney("400", "USD")},
+		},
+	}
+
+	googleHome3For2 := domain.Promotion{
+		ID:        "googlehome-3-for-2",
+		Condition: promotioncondition.ProductQuantityDiscount{ProductID: "googlehome", RequiredQuantity: 3, DiscountedQuantity: 1},
+	}
+	spend500 := domain.Promotion{
+		ID:       "spend-500-get-50-off",
+		Priority: 10,
+		Condition: promotioncondition.CartThresholdDiscount{Tiers: []promotioncondition.CartThresholdTier{
+			{MinSubtotal: domain.MustParseMoney("500", "USD"), Amount: domain.MustParseMoney("50", "USD")},
+		}},
+	}
+	spend400 := promotioncondition.CartThresholdDiscount{Tiers: []promotioncondition.CartThresholdTier{
+		{MinSubtotal: domain.MustParseMoney("400", "USD"), DiscountInPercent: 10},
+	}}
+	spend400On := func(excludeDiscountedLines bool) domain.Promotion {
+		condition := spend400
+		condition.ExcludeDiscountedLines = excludeDiscountedLines
+		return domain.Promotion{ID: "spend-400-get-10-percent-off", Condition: condition}
+	}
+
+	with := func(promotion domain.Promotion, set func(promotion *domain.Promotion)) domain.Promotion {
+		set(&promotion)
+		return promotion
+	}
+
+	spend500Applied := domain.AppliedPromotion{
+		PromotionID: "spend-500-get-50-off",
+		Amount:      domain.MustParseMoney("-50", "USD"),
+		Lines: []domain.LineDiscount{
+			{OrderLineID: "l1", Quantity: 3, Amount: domain.MustParseMoney("-13.63", "USD")},
+			{OrderLineID: "l2", Quantity: 1, Amount: domain.MustParseMoney("-36.37", "USD")},
+		},
+	}
+	googleHome3For2Applied := domain.AppliedPromotion{
+		PromotionID: "googlehome-3-for-2",
+		Amount:      domain.MustParseMoney("-49.99", "USD"),
+		Lines: []domain.LineDiscount{
+			{OrderLineID: "l1", Quantity: 1, Amount: domain.MustParseMoney("-49.99", "USD")},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		promotions []domain.Promotion
+		want       []domain.AppliedPromotion
+	}{
+		{
+			name:       "should apply a cart promotion on an order no product promotion discounts",
+			promotions: []domain.Promotion{spend500},
+			want:       []domain.AppliedPromotion{spend500Applied},
+		},
+		{
+			name:       "should measure the threshold after product promotions whatever their priority",
+			promotions: []domain.Promotion{spend500, googleHome3For2},
+			want:       []domain.AppliedPromotion{googleHome3For2Applied},
+		},
+		{
+			name:       "should count discounted lines for what is left to pay",
+			promotions: []domain.Promotion{spend400On(false), googleHome3For2},
+			want: []domain.AppliedPromotion{
+				googleHome3For2Applied,
+				{
+					PromotionID: "spend-400-get-10-percent-off",
+					Amount:      domain.MustParseMoney("-50", "USD"),
+					Lines: []domain.LineDiscount{
+						{OrderLineID: "l1", Quantity: 3, Amount: domain.MustParseMoney("-10", "USD")},
+						{OrderLineID: "l2", Quantity: 1, Amount: domain.MustParseMoney("-40", "USD")},
+					},
+				},
+			},
+		},
+		{
+			name: "should apply an exclusive cart promotion alone when its priority is higher",
+			promotions: []domain.Promotion{
+				with(spend500, func(p *domain.Promotion) { p.Exclusive = true }),
+				googleHome3For2,
+			},
+			want: []domain.AppliedPromotion{spend500Applied},
+		},
+		{
+			name: "should skip an exclusive cart promotion when a product promotion has a higher priority",
+			promotions: []domain.Promotion{
+				with(spend500, func(p *domain.Promotion) { p.Exclusive = true; p.Priority = 0 }),
+				with(googleHome3For2, func(p *domain.Promotion) { p.Priority = 1 }),
+			},
+			want: []domain.AppliedPromotion{googleHome3For2Applied},
+		},
+		{
+			name: "should let a cart promotion of higher priority win its stack group",
+			promotions: []domain.Promotion{
+				with(spend500, func(p *domain.Promotion) { p.StackGroup = "spring" }),
+				with(googleHome3For2, func(p *domain.Promotion) { p.StackGroup = "spring" }),
+			},
+			want: []domain.AppliedPromotion{spend500Applied},
+		},
+		{
+			name:       "should leave discounted lines out when they don't count",
+			promotions: []domain.Promotion{spend400On(true), googleHome3For2},
+			want: []domain.AppliedPromotion{
+				googleHome3For2Applied,
+				{
+					PromotionID: "spend-400-get-10-percent-off",
+					Amount:      domain.MustParseMoney("-40", "USD"),
+					Lines: []domain.LineDiscount{
+						{OrderLineID: "l2", Quantity: 1, Amount: domain.MustParseMoney("-40", "USD")},
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := applyPromotions(order, test.promotions)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
@@ -1,158 +1,1818 @@
 package services
 
 import (
-	"math"
+	"errors"
+	"fmt"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/rs/xid"
+	"golang.org/x/crypto/bcrypt"
 
 	"github.com/donnpebe/shoppo/pkg/domain"
+	"github.com/donnpebe/shoppo/pkg/lib/promotioncondition"
 )
 
 type ShopService struct {
-	inventories map[string]*domain.Product
-	invMutex    sync.RWMutex
+	store domain.Store
 
 	promotions []domain.Promotion
+	// promotionRegistry records the promotions applied at checkout and
+	// builds them back for refunds.
+	promotionRegistry *promotioncondition.Registry
+	// bestDealCombinations is how many combinations of promotions are
+	// evaluated to find the best deal, promotions are applied by priority
+	// when it is zero.
+	bestDealCombinations int
 
-	orderStore map[string]*domain.Order
+	// shippingMethods are the ways physical products can be shipped, they
+	// are shipped for free when there is none.
+	shippingMethods []domain.ShippingMethod
+
+	paymentMethods []domain.PaymentMethod
+
+	// reservationTTL is how long adding an item to a cart holds its stock,
+	// stock is not reserved when it is zero.
+	reservationTTL time.Duration
+
+	orderLocks *orderLocks
+	// couponLocks serializes the checkouts using the same coupon code, so
+	// its usage limits can't be exceeded.
+	couponLocks *orderLocks
+	// ownerLocks serializes the creation and merging of the carts of the
+	// same customer or session.
+	ownerLocks *orderLocks
+
+	// passwordCost is the bcrypt cost of the password hashes of customers.
+	passwordCost int
+	// unknownCustomerHash is compared to the password of an unknown
+	// customer, so logging in takes as long whether the customer exists.
+	unknownCustomerHash     []byte
+	unknownCustomerHashOnce sync.Once
+
+	now func() time.Time
+}
+
+// Option configures a ShopService.
+type Option func(service *ShopService)
+
+// WithReservationTTL makes adding an item to a cart hold its stock for
+// ttl, so it can't be sold to another cart in the meantime.
+func WithReservationTTL(ttl time.Duration) Option {
+	return func(service *ShopService) {
+		service.reservationTTL = ttl
+	}
+}
+
+// DefaultBestDealCombinations evaluates every combination of up to 10
+// promotions that discount the same order.
+const DefaultBestDealCombinations = 1 << 10
+
+// WithBestDeal makes the promotions that can't apply together be chosen
+// to give the customer the lowest total instead of by priority, trying
+// at most maxCombinations of them per order.
+func WithBestDeal(maxCombinations int) Option {
+	return func(service *ShopService) {
+		service.bestDealCombinations = maxCombinations
+	}
+}
+
+// WithShippingMethods makes customers choose one of methods to ship
+// the physical products they order.
+func WithShippingMethods(methods ...domain.ShippingMethod) Option {
+	return func(service *ShopService) {
+		service.shippingMethods = methods
+	}
+}
+
+// WithPasswordCost hashes the passwords of customers with the given bcrypt
+// cost instead of bcrypt.DefaultCost.
+func WithPasswordCost(cost int) Option {
+	return func(service *ShopService) {
+		service.passwordCost = cost
+	}
+}
+
+// WithPromotionRegistry records the promotions applied at checkout with
+// the condition types of registry instead of
+// promotioncondition.DefaultRegistry.
+func WithPromotionRegistry(registry *promotioncondition.Registry) Option {
+	return func(service *ShopService) {
+		service.promotionRegistry = registry
+	}
+}
+
+// WithPaymentMethods lets customers pay for their orders with methods.
+func WithPaymentMethods(methods ...domain.PaymentMethod) Option {
+	return func(service *ShopService) {
+		service.paymentMethods = methods
+	}
+}
+
+func NewShopService(store domain.Store, promotions []domain.Promotion, opts ...Option) *ShopService {
+	service := &ShopService{
+		store:             store,
+		promotions:        promotions,
+		promotionRegistry: promotioncondition.DefaultRegistry,
+		orderLocks:        newOrderLocks(),
+		couponLocks:       newOrderLocks(),
+		ownerLocks:        newOrderLocks(),
+		passwordCost:      bcrypt.DefaultCost,
+		now:               time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(service)
+	}
+
+	return service
+}
+
+// CreateCart creates a cart that belongs to nobody.
+func (service *ShopService) CreateCart() (*domain.Order, error) {
+	return service.createCart(domain.OrderOwner{})
+}
+
+func (service *ShopService) createCart(owner domain.OrderOwner) (*domain.Order, error) {
+	order := &domain.Order{
+		ID:         xid.New().String(),
+		Status:     domain.OrderStatusCreated,
+		CustomerID: owner.CustomerID,
+		SessionID:  owner.SessionID,
+		CreatedAt:  service.now(),
+	}
+
+	if err := service.store.Orders().Save(order); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// GetActiveOrder returns the cart the owner is filling, or
+// ErrCartNotFound if they don't have one.
+func (service *ShopService) GetActiveOrder(owner domain.OrderOwner) (*domain.Order, error) {
+	return service.store.Orders().FindActive(owner)
+}
+
+// GetOrCreateActiveOrder returns the cart the owner is filling, creating
+// it if they don't have one.
+func (service *ShopService) GetOrCreateActiveOrder(owner domain.OrderOwner) (*domain.Order, error) {
+	if owner.CustomerID == "" && owner.SessionID == "" {
+		return nil, domain.ErrCartNotFound
+	}
+
+	unlock := service.ownerLocks.lock(ownerKey(owner))
+	defer unlock()
+
+	order, err := service.store.Orders().FindActive(owner)
+	if errors.Is(err, domain.ErrCartNotFound) {
+		return service.createCart(owner)
+	}
+
+	return order, err
+}
+
+// Passwords are at least minPasswordLength bytes long and at most the
+// maxPasswordLength bytes bcrypt hashes.
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72
+)
+
+// RegisterCustomer creates a customer with the given email address, who
+// logs in with password.
+func (service *ShopService) RegisterCustomer(emailAddress string, password string) (*domain.Customer, error) {
+	emailAddress, err := domain.NormalizeEmailAddress(emailAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return nil, fmt.Errorf("%w: must be %d to %d characters long", domain.ErrInvalidPassword, minPasswordLength, maxPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), service.passwordCost)
+	if err != nil {
+		return nil, err
+	}
+
+	customer := &domain.Customer{
+		ID:           xid.New().String(),
+		EmailAddress: emailAddress,
+		PasswordHash: string(hash),
+	}
+
+	if err := service.store.Customers().Save(customer); err != nil {
+		return nil, err
+	}
+
+	return customer, nil
+}
+
+// Authenticate returns the customer with the given email address if
+// password is theirs, and ErrInvalidCredentials otherwise, whether the
+// customer exists or not.
+func (service *ShopService) Authenticate(emailAddress string, password string) (*domain.Customer, error) {
+	customer, err := service.GetCustomerByEmailAddress(emailAddress)
+	if errors.Is(err, domain.ErrCustomerNotFound) || errors.Is(err, domain.ErrInvalidEmailAddress) {
+		service.unknownCustomerHashOnce.Do(func() {
+			service.unknownCustomerHash, _ = bcrypt.GenerateFromPassword([]byte("unknown customer"), service.passwordCost)
+		})
+		_ = bcrypt.CompareHashAndPassword(service.unknownCustomerHash, []byte(password))
+
+		return nil, domain.ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if customer.PasswordHash == "" ||
+		bcrypt.CompareHashAndPassword([]byte(customer.PasswordHash), []byte(password)) != nil {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	return customer, nil
+}
+
+func (service *ShopService) GetCustomer(customerID string) (*domain.Customer, error) {
+	return service.store.Customers().FindByID(customerID)
+}
+
+func (service *ShopService) GetCustomerByEmailAddress(emailAddress string) (*domain.Customer, error) {
+	emailAddress, err := domain.NormalizeEmailAddress(emailAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.store.Customers().FindByEmailAddress(emailAddress)
+}
+
+// MergeSessionCart is meant to be called when a customer logs in, it
+// moves the cart of the anonymous session to the customer and returns the
+// active order of the customer, or ErrCartNotFound if there is none.
+//
+// When the customer already has a cart the session cart is merged into
+// it and cancelled. A product in both carts keeps the larger of both
+// quantities, capped by the stock that can still be sold when stock is
+// reserved.
+func (service *ShopService) MergeSessionCart(sessionID string, customerID string) (*domain.Order, error) {
+	if _, err := service.store.Customers().FindByID(customerID); err != nil {
+		return nil, err
+	}
+
+	customerOwner := domain.OrderOwner{CustomerID: customerID}
+	sessionOwner := domain.OrderOwner{SessionID: sessionID}
+
+	// Owner locks are always taken customer first to avoid deadlocks.
+	unlockCustomer := service.ownerLocks.lock(ownerKey(customerOwner))
+	defer unlockCustomer()
+	unlockSession := service.ownerLocks.lock(ownerKey(sessionOwner))
+	defer unlockSession()
+
+	customerCart, err := service.store.Orders().FindActive(customerOwner)
+	if err != nil && !errors.Is(err, domain.ErrCartNotFound) {
+		return nil, err
+	}
+
+	sessionCart, err := service.store.Orders().FindActive(sessionOwner)
+	if errors.Is(err, domain.ErrCartNotFound) {
+		if customerCart == nil {
+			return nil, domain.ErrCartNotFound
+		}
+
+		return customerCart, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if customerCart == nil {
+		return service.claimCart(sessionCart.ID, customerID)
+	}
+
+	return service.mergeCarts(sessionCart.ID, customerCart.ID)
+}
+
+// claimCart gives the cart to the customer.
+func (service *ShopService) claimCart(orderID string, customerID string) (*domain.Order, error) {
+	unlock := service.orderLocks.lock(orderID)
+	defer unlock()
+
+	order, err := service.store.Orders().FindByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	order.CustomerID = customerID
+	if err := service.store.Orders().Save(order); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// mergeCarts moves the lines and coupon codes of the source cart to the
+// target cart and cancels the source cart.
+func (service *ShopService) mergeCarts(sourceID string, targetID string) (*domain.Order, error) {
+	// Orders are locked in a stable order to avoid deadlocks.
+	first, second := sourceID, targetID
+	if second < first {
+		first, second = second, first
+	}
+	unlockFirst := service.orderLocks.lock(first)
+	defer unlockFirst()
+	unlockSecond := service.orderLocks.lock(second)
+	defer unlockSecond()
+
+	source, err := service.store.Orders().FindByID(sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := service.store.Orders().FindByID(targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !source.IsModifiable() || !target.IsModifiable() {
+		return nil, domain.ErrOrderNotModifiable
+	}
+
+	// Lines in another currency than the cart of the customer can't be
+	// priced with it, they are dropped like the lines out of stock.
+	currency, err := target.Currency()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range source.Lines {
+		if currency != "" && line.UnitPrice.Currency != currency {
+			continue
+		}
+
+		foundLine, _ := findLineInOrder(target, line.ProductID)
+		if foundLine == nil {
+			target.Lines = append(target.Lines, line.Clone())
+		} else if line.Quantity > foundLine.Quantity {
+			foundLine.Quantity = line.Quantity
+		}
+	}
+	source.Lines = nil
+
+	for _, code := range source.CouponCodes {
+		if !target.HasCouponCode(code) {
+			target.CouponCodes = append(target.CouponCodes, code)
+		}
+	}
+	source.CouponCodes = nil
+
+	if err := source.TransitionTo(domain.OrderStatusCancelled); err != nil {
+		return nil, err
+	}
+
+	now := service.now()
+	err = service.store.Transaction(func(tx domain.Store) error {
+		if err := tx.Reservations().DeleteByOrder(source.ID); err != nil {
+			return err
+		}
+
+		if service.reservationTTL > 0 {
+			if err := service.reserveLines(tx, target, now); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Orders().Save(source); err != nil {
+			return err
+		}
+
+		return tx.Orders().Save(target)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return target, nil
+}
+
+// reserveLines holds the stock of every line of the order, lowering the
+// quantity of the lines for which there isn't enough stock left and
+// dropping those for which there is none.
+func (service *ShopService) reserveLines(tx domain.Store, order *domain.Order, now time.Time) error {
+	lines := order.Lines[:0]
+	for _, line := range order.Lines {
+		product, err := tx.Products().FindByID(line.ProductID)
+		if err != nil {
+			return err
+		}
+
+		available, err := service.availableToSell(tx, product, order.ID, now)
+		if err != nil {
+			return err
+		}
+
+		if line.Quantity > available {
+			line.Quantity = available
+		}
+
+		if line.Quantity <= 0 {
+			if err := tx.Reservations().Delete(order.ID, line.ProductID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		err = tx.Reservations().Save(&domain.Reservation{
+			OrderID:   order.ID,
+			ProductID: line.ProductID,
+			Quantity:  line.Quantity,
+			ExpiresAt: now.Add(service.reservationTTL),
+		})
+		if err != nil {
+			return err
+		}
+
+		lines = append(lines, line)
+	}
+	order.Lines = lines
+
+	return nil
+}
+
+// ListProducts returns the products with their variants, variants are not
+// listed on their own unless their product is gone.
+func (service *ShopService) ListProducts() ([]*domain.Product, error) {
+	products, err := service.store.Products().FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*domain.Product, len(products))
+	for _, product := range products {
+		byID[product.ID] = product
+	}
+
+	listed := make([]*domain.Product, 0, len(products))
+	for _, product := range products {
+		if parent, ok := byID[product.ParentID]; ok && product.ParentID != "" {
+			parent.Variants = append(parent.Variants, product)
+			continue
+		}
+		listed = append(listed, product)
+	}
+
+	for _, product := range listed {
+		sort.Slice(product.Variants, func(i, j int) bool {
+			return product.Variants[i].ID < product.Variants[j].ID
+		})
+	}
+
+	return listed, nil
+}
+
+func (service *ShopService) GetOrder(orderID string) (*domain.Order, error) {
+	return service.store.Orders().FindByID(orderID)
+}
+
+// GetProduct returns the product or variant with its variants.
+func (service *ShopService) GetProduct(productID string) (*domain.Product, error) {
+	product, err := service.store.Products().FindByID(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if product.Variants, err = service.store.Products().FindVariants(productID); err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+// GetStockLevel returns the stock of the product on hand and how much of
+// it is not held by carts. The stock of a product that has variants is
+// the stock of its variants.
+func (service *ShopService) GetStockLevel(productID string) (*domain.StockLevel, error) {
+	product, err := service.store.Products().FindByID(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	variants, err := service.store.Products().FindVariants(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(variants) > 0 {
+		level := &domain.StockLevel{ProductID: productID}
+		for _, variant := range variants {
+			variantLevel, err := service.GetStockLevel(variant.ID)
+			if err != nil {
+				return nil, err
+			}
+
+			level.OnHand += variantLevel.OnHand
+			level.Reserved += variantLevel.Reserved
+			level.Available += variantLevel.Available
+		}
+
+		return level, nil
+	}
+
+	reserved, err := service.store.Reservations().ReservedQuantity(productID, service.now())
+	if err != nil {
+		return nil, err
+	}
+
+	available := product.Quantity - reserved
+	if available < 0 {
+		available = 0
+	}
+
+	return &domain.StockLevel{
+		ProductID: productID,
+		OnHand:    product.Quantity,
+		Reserved:  reserved,
+		Available: available,
+	}, nil
+}
+
+// AddItemToCart adds quantity of the product or variant productID to the
+// order, a product that has variants is added through one of them.
+func (service *ShopService) AddItemToCart(orderID string, productID string, quantity int) (*domain.Order, error) {
+	if quantity <= 0 {
+		return nil, domain.ErrInvalidQuantity
+	}
+
+	unlock := service.orderLocks.lock(orderID)
+	defer unlock()
+
+	order, err := service.store.Orders().FindByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !order.IsModifiable() {
+		return nil, domain.ErrOrderNotModifiable
+	}
+
+	now := service.now()
+	err = service.store.Transaction(func(tx domain.Store) error {
+		product, err := tx.Products().FindByID(productID)
+		if err != nil {
+			return err
+		}
+
+		if product.ParentID == "" {
+			variants, err := tx.Products().FindVariants(productID)
+			if err != nil {
+				return err
+			}
+			if len(variants) > 0 {
+				return domain.ErrVariantRequired
+			}
+		}
+
+		currency, err := order.Currency()
+		if err != nil {
+			return err
+		}
+		if currency != "" && product.UnitPrice.Currency != currency {
+			return fmt.Errorf("%w: %s is priced in %s, the cart in %s",
+				domain.ErrCurrencyMismatch, productID, product.UnitPrice.Currency, currency)
+		}
+
+		available, err := service.availableToSell(tx, product, orderID, now)
+		if err != nil {
+			return err
+		}
+
+		foundLine, _ := findLineInOrder(order, productID)
+		if foundLine == nil {
+			foundLine = domain.NewOrderLine(xid.New().String(), product, 0)
+			order.Lines = append(order.Lines, foundLine)
+		}
+
+		if (available - (foundLine.Quantity + quantity)) < 0 {
+			return domain.ErrNotEnoughStock
+		}
+
+		foundLine.Quantity += quantity
+
+		if service.reservationTTL > 0 {
+			err := tx.Reservations().Save(&domain.Reservation{
+				OrderID:   orderID,
+				ProductID: productID,
+				Quantity:  foundLine.Quantity,
+				ExpiresAt: now.Add(service.reservationTTL),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Orders().Save(order)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+func (service *ShopService) RemoveItemFromCart(orderID string, productID string) (*domain.Order, error) {
+	unlock := service.orderLocks.lock(orderID)
+	defer unlock()
+
+	order, err := service.store.Orders().FindByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !order.IsModifiable() {
+		return nil, domain.ErrOrderNotModifiable
+	}
+
+	_, foundIdx := findLineInOrder(order, productID)
+	if foundIdx < 0 {
+		return nil, domain.ErrItemNotFoundInCart
+	}
+
+	order.Lines = append(order.Lines[:foundIdx], order.Lines[foundIdx+1:]...)
+
+	err = service.store.Transaction(func(tx domain.Store) error {
+		if err := tx.Reservations().Delete(orderID, productID); err != nil {
+			return err
+		}
+
+		return tx.Orders().Save(order)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// SetShippingAddress sets the address the order is shipped to.
+func (service *ShopService) SetShippingAddress(orderID string, address domain.Address) (*domain.Order, error) {
+	return service.setAddress(orderID, address, func(order *domain.Order, address *domain.Address) {
+		order.ShippingAddress = address
+	})
+}
+
+// SetBillingAddress sets the address the order is billed to.
+func (service *ShopService) SetBillingAddress(orderID string, address domain.Address) (*domain.Order, error) {
+	return service.setAddress(orderID, address, func(order *domain.Order, address *domain.Address) {
+		order.BillingAddress = address
+	})
+}
+
+func (service *ShopService) setAddress(orderID string, address domain.Address, set func(order *domain.Order, address *domain.Address)) (*domain.Order, error) {
+	address = address.Normalize()
+	if err := address.Validate(); err != nil {
+		return nil, err
+	}
+
+	unlock := service.orderLocks.lock(orderID)
+	defer unlock()
+
+	order, err := service.store.Orders().FindByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !order.IsModifiable() {
+		return nil, domain.ErrOrderNotModifiable
+	}
+
+	set(order, &address)
+
+	if err := service.store.Orders().Save(order); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// GetEligibleShippingMethods returns the shipping methods that can ship
+// the order as it is now and what they cost.
+func (service *ShopService) GetEligibleShippingMethods(orderID string) ([]domain.ShippingQuote, error) {
+	order, err := service.store.Orders().FindByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The value of the shipment doesn't include the cost of the shipping
+	// method chosen so far.
+	unshipped := *order
+	unshipped.ShippingMethodID = ""
+	pricing, err := service.price(&unshipped, service.now())
+	if err != nil {
+		return nil, err
+	}
+
+	shipment, err := service.shipment(order)
+	if err != nil {
+		return nil, err
+	}
+	shipment.Value = pricing.Total
+
+	quotes := []domain.ShippingQuote{}
+	if len(shipment.Items) == 0 {
+		return quotes, nil
+	}
+
+	for _, method := range service.shippingMethods {
+		cost, err := shippingCost(method, shipment)
+		if errors.Is(err, domain.ErrShippingMethodNotEligible) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		quotes = append(quotes, domain.ShippingQuote{Method: method, Cost: cost})
+	}
+
+	return quotes, nil
+}
+
+func (service *ShopService) GetShippingMethod(methodID string) (*domain.ShippingMethod, error) {
+	method, err := service.findShippingMethod(methodID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &method, nil
+}
+
+// SetShippingMethod chooses how the order is shipped.
+func (service *ShopService) SetShippingMethod(orderID string, methodID string) (*domain.Order, error) {
+	method, err := service.findShippingMethod(methodID)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock := service.orderLocks.lock(orderID)
+	defer unlock()
+
+	order, err := service.store.Orders().FindByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !order.IsModifiable() {
+		return nil, domain.ErrOrderNotModifiable
+	}
+
+	order.ShippingMethodID = method.ID
+
+	// Pricing the order makes sure the method can ship it.
+	if _, err := service.price(order, service.now()); err != nil {
+		return nil, err
+	}
+
+	if err := service.store.Orders().Save(order); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// ApplyCoupon applies the coupon code to the cart, unlocking its
+// promotion. It fails when the promotion doesn't run now, its usage limit
+// is reached or it gives no discount on the cart.
+func (service *ShopService) ApplyCoupon(orderID string, code string) (*domain.Order, error) {
+	code = domain.NormalizeCouponCode(code)
+
+	unlock := service.orderLocks.lock(orderID)
+	defer unlock()
+
+	order, err := service.store.Orders().FindByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !order.IsModifiable() {
+		return nil, domain.ErrOrderNotModifiable
+	}
+
+	if order.HasCouponCode(code) {
+		return order, nil
+	}
+
+	promotion, err := service.checkCouponCode(order, code, service.now())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(candidates(order, []domain.Promotion{promotion})) == 0 {
+		return nil, fmt.Errorf("%w: %s", domain.ErrCouponCodeNotEligible, code)
+	}
+
+	order.CouponCodes = append(order.CouponCodes, code)
+	if err := service.store.Orders().Save(order); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// RemoveCoupon removes the coupon code from the cart.
+func (service *ShopService) RemoveCoupon(orderID string, code string) (*domain.Order, error) {
+	code = domain.NormalizeCouponCode(code)
+
+	unlock := service.orderLocks.lock(orderID)
+	defer unlock()
+
+	order, err := service.store.Orders().FindByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !order.IsModifiable() {
+		return nil, domain.ErrOrderNotModifiable
+	}
+
+	if !order.HasCouponCode(code) {
+		return nil, fmt.Errorf("%w: %s", domain.ErrCouponCodeNotApplied, code)
+	}
+
+	codes := order.CouponCodes[:0]
+	for _, applied := range order.CouponCodes {
+		if applied != code {
+			codes = append(codes, applied)
+		}
+	}
+	order.CouponCodes = codes
+
+	if err := service.store.Orders().Save(order); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// checkCouponCode returns the promotion of the normalized coupon code if
+// it runs at now and the order can still use it.
+func (service *ShopService) checkCouponCode(order *domain.Order, code string, now time.Time) (domain.Promotion, error) {
+	var promotion domain.Promotion
+	found := false
+	for _, p := range service.promotions {
+		if p.CouponCode != "" && domain.NormalizeCouponCode(p.CouponCode) == code {
+			promotion, found = p, true
+			break
+		}
+	}
+
+	// Codes of promotions that didn't start yet are not given away.
+	if !found || (!promotion.StartDate.IsZero() && promotion.StartDate.After(now)) {
+		return domain.Promotion{}, fmt.Errorf("%w: %s", domain.ErrCouponCodeNotFound, code)
+	}
+
+	if !promotion.IsActive(now) {
+		return domain.Promotion{}, fmt.Errorf("%w: %s", domain.ErrCouponCodeExpired, code)
+	}
+
+	if promotion.UsageLimit > 0 {
+		uses, err := service.store.Orders().CountCouponCodeUses(code, "")
+		if err != nil {
+			return domain.Promotion{}, err
+		}
+
+		if uses >= promotion.UsageLimit {
+			return domain.Promotion{}, fmt.Errorf("%w: %s", domain.ErrCouponCodeUsageLimitReached, code)
+		}
+	}
+
+	if promotion.UsageLimitPerCustomer > 0 {
+		// Anonymous sessions are too easy to renew to count their uses.
+		if order.CustomerID == "" {
+			return domain.Promotion{}, fmt.Errorf("%w: %s requires a customer account", domain.ErrCouponCodeNotEligible, code)
+		}
+
+		uses, err := service.store.Orders().CountCouponCodeUses(code, order.CustomerID)
+		if err != nil {
+			return domain.Promotion{}, err
+		}
+
+		if uses >= promotion.UsageLimitPerCustomer {
+			return domain.Promotion{}, fmt.Errorf("%w: %s", domain.ErrCouponCodeUsageLimitReached, code)
+		}
+	}
+
+	return promotion, nil
+}
+
+// GetActivePromotions returns the promotions running now, except the
+// ones unlocked by a coupon code.
+func (service *ShopService) GetActivePromotions() []domain.Promotion {
+	active := []domain.Promotion{}
+	for _, promotion := range service.activePromotions(service.now()) {
+		if promotion.CouponCode == "" {
+			active = append(active, promotion)
+		}
+	}
+
+	return active
+}
+
+// QuoteOrder prices the order as checkout would right now, without
+// touching the stock or the order.
+func (service *ShopService) QuoteOrder(orderID string) (*domain.Pricing, error) {
+	order, err := service.store.Orders().FindByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.price(order, service.now())
+}
+
+// Checkout places the order, taking its items out of stock, and returns
+// its pricing which is also stored on the order.
+func (service *ShopService) Checkout(orderID string) (*domain.Pricing, error) {
+	unlock := service.orderLocks.lock(orderID)
+	defer unlock()
+
+	order, err := service.store.Orders().FindByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Status == domain.OrderStatusCancelled {
+		return nil, domain.ErrOrderCancelled
+	}
+
+	if order.Status != domain.OrderStatusCreated {
+		return nil, domain.ErrOrderAlreadyCheckedOut
+	}
+
+	shipment, err := service.shipment(order)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(shipment.Items) > 0 {
+		if order.ShippingAddress == nil {
+			return nil, domain.ErrShippingAddressRequired
+		}
+
+		if len(service.shippingMethods) > 0 && order.ShippingMethodID == "" {
+			return nil, domain.ErrShippingMethodRequired
+		}
+	}
+
+	now := service.now()
+	if len(order.CouponCodes) > 0 {
+		unlockCoupons := service.lockCouponCodes(order.CouponCodes)
+		defer unlockCoupons()
+
+		for _, code := range order.CouponCodes {
+			if _, err := service.checkCouponCode(order, code, now); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := order.TransitionTo(domain.OrderStatusArrangingPayment); err != nil {
+		return nil, err
+	}
+
+	order.PlacedAt = now
+	if order.Pricing, err = service.price(order, now); err != nil {
+		return nil, err
+	}
+	if err := service.snapshotPromotions(order.Pricing); err != nil {
+		return nil, err
+	}
+	quantities := lineQuantities(order)
+
+	err = service.store.Transaction(func(tx domain.Store) error {
+		// The stock held by other carts must not be sold, whether or not
+		// the reservations of this cart expired.
+		if service.reservationTTL > 0 {
+			if err := service.checkAvailability(tx, orderID, quantities, now); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Products().DecreaseStock(quantities); err != nil {
+			return err
+		}
+
+		if err := tx.Reservations().DeleteByOrder(orderID); err != nil {
+			return err
+		}
+
+		return tx.Orders().Save(order)
+	})
+	switch {
+	case errors.Is(err, domain.ErrProductNotFound):
+		return nil, domain.ErrSomeProductInCartNotFound
+	case errors.Is(err, domain.ErrNotEnoughStock):
+		return nil, domain.ErrSomeProductInCartNotEnoughInStock
+	case err != nil:
+		return nil, err
+	}
+
+	return order.Pricing.Clone(), nil
 }
 
-func NewShopService(inventories map[string]*domain.Product, promotions []domain.Promotion, orderStore map[string]*domain.Order) *ShopService {
-	return &ShopService{
-		inventories: inventories,
-		promotions:  promotions,
-		orderStore:  orderStore,
+// price computes the pricing of the order with the promotions active at
+// now and the cost of its shipping method. Promotions unlocked by a
+// coupon code only apply when the code is applied to the order.
+func (service *ShopService) price(order *domain.Order, now time.Time) (*domain.Pricing, error) {
+	var promotions []domain.Promotion
+	for _, promotion := range service.activePromotions(now) {
+		if promotion.CouponCode == "" || order.HasCouponCode(domain.NormalizeCouponCode(promotion.CouponCode)) {
+			promotions = append(promotions, promotion)
+		}
 	}
+
+	return service.priceWith(order, promotions)
 }
 
-func (service *ShopService) CreateCart() *domain.Order {
-	order := &domain.Order{
-		ID: xid.New().String(),
+// snapshotPromotions records on the discounts of pricing how their
+// promotions are set up, so refunds price what the customer keeps the
+// same way whatever the promotions of the shop become. Promotions whose
+// condition isn't Serializable or can't be rebuilt from its parameters,
+// e.g. one set up in code with values the registry refuses, are not
+// recorded and refunds fall back to the promotion of the shop. It returns
+// an error when the type of a Serializable condition is not registered.
+func (service *ShopService) snapshotPromotions(pricing *domain.Pricing) error {
+	for i, discount := range pricing.Discounts {
+		for _, promotion := range service.promotions {
+			if promotion.ID != discount.PromotionID {
+				continue
+			}
+			if _, ok := promotion.Condition.(promotioncondition.Serializable); !ok {
+				break
+			}
+
+			typeName, params, err := service.promotionRegistry.Encode(promotion.Condition)
+			if err != nil {
+				return fmt.Errorf("promotion %s: %w", promotion.ID, err)
+			}
+			if _, err := service.promotionRegistry.New(typeName, params); err != nil {
+				break
+			}
+
+			pricing.Discounts[i].Promotion = &domain.PromotionSnapshot{
+				ConditionType: typeName,
+				Parameters:    params,
+				Priority:      promotion.Priority,
+				Exclusive:     promotion.Exclusive,
+				StackGroup:    promotion.StackGroup,
+				MaxOnePerLine: promotion.MaxOnePerLine,
+			}
+			break
+		}
 	}
 
-	service.orderStore[order.ID] = order
-	return order
+	return nil
 }
 
-func (service *ShopService) ListProducts() []*domain.Product {
-	service.invMutex.RLock()
+// checkedOutPromotions returns the promotions that discounted the order
+// as they were set up at checkout. The ones that were not recorded are
+// taken from the promotions of the shop that were active at checkout.
+func (service *ShopService) checkedOutPromotions(order *domain.Order) ([]domain.Promotion, error) {
+	var promotions []domain.Promotion
+	for _, discount := range order.Pricing.Discounts {
+		snapshot := discount.Promotion
+		if snapshot == nil {
+			for _, promotion := range service.activePromotions(order.PlacedAt) {
+				if promotion.ID == discount.PromotionID {
+					promotions = append(promotions, promotion)
+				}
+			}
+			continue
+		}
+
+		condition, err := service.promotionRegistry.New(snapshot.ConditionType, snapshot.Parameters)
+		if err != nil {
+			return nil, fmt.Errorf("promotion %s of order %s: %w", discount.PromotionID, order.ID, err)
+		}
 
-	var products []*domain.Product
-	for _, product := range service.inventories {
-		products = append(products, product)
+		promotions = append(promotions, domain.Promotion{
+			ID:            discount.PromotionID,
+			Name:          discount.Name,
+			Condition:     condition,
+			Priority:      snapshot.Priority,
+			Exclusive:     snapshot.Exclusive,
+			StackGroup:    snapshot.StackGroup,
+			MaxOnePerLine: snapshot.MaxOnePerLine,
+		})
 	}
 
-	service.invMutex.RUnlock()
+	return promotions, nil
+}
+
+// lockCouponCodes blocks until the locks of codes are acquired and
+// returns the function releasing them.
+func (service *ShopService) lockCouponCodes(codes []string) (unlock func()) {
+	// Codes are locked in a stable order to avoid deadlocks.
+	sorted := append([]string(nil), codes...)
+	sort.Strings(sorted)
+
+	unlocks := make([]func(), 0, len(sorted))
+	for _, code := range sorted {
+		unlocks = append(unlocks, service.couponLocks.lock(code))
+	}
 
-	return products
+	return func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
 }
 
-func (service *ShopService) AddItemToCart(orderID string, productID string, quantity int) (*domain.Order, error) {
-	order, ok := service.orderStore[orderID]
-	if !ok {
-		return nil, domain.ErrCartNotFound
+func (service *ShopService) activePromotions(now time.Time) []domain.Promotion {
+	active := []domain.Promotion{}
+	for _, promotion := range service.promotions {
+		if promotion.IsActive(now) {
+			active = append(active, promotion)
+		}
+	}
+
+	return active
+}
+
+// priceWith computes the pricing of the order with promotions and the
+// cost of its shipping method.
+func (service *ShopService) priceWith(order *domain.Order, promotions []domain.Promotion) (*domain.Pricing, error) {
+	if _, err := order.Currency(); err != nil {
+		return nil, err
 	}
 
-	service.invMutex.RLock()
-	defer service.invMutex.RUnlock()
-	product, ok := service.inventories[productID]
-	if !ok {
-		return nil, domain.ErrProductNotFound
+	pricing := &domain.Pricing{}
+	for _, line := range order.Lines {
+		subtotal := line.Subtotal()
+		pricing.Lines = append(pricing.Lines, domain.LinePricing{
+			OrderLineID: line.ID,
+			ProductID:   line.ProductID,
+			Quantity:    line.Quantity,
+			UnitPrice:   line.UnitPrice,
+			Subtotal:    subtotal,
+		})
+		pricing.Subtotal = pricing.Subtotal.Add(subtotal)
 	}
 
-	foundLine, _ := findLineInOrder(order, productID)
-	if foundLine == nil {
-		if (product.Quantity - quantity) < 0 {
-			return nil, domain.ErrNotEnoughStock
+	if service.bestDealCombinations > 0 {
+		pricing.Discounts = bestDeal(order, promotions, service.bestDealCombinations)
+	} else {
+		pricing.Discounts = applyPromotions(order, promotions)
+	}
+	lineDiscounts := make(map[string]domain.Money, len(order.Lines))
+	for _, discount := range pricing.Discounts {
+		pricing.DiscountTotal = pricing.DiscountTotal.Add(discount.Amount)
+		for _, line := range discount.Lines {
+			lineDiscounts[line.OrderLineID] = lineDiscounts[line.OrderLineID].Add(line.Amount)
 		}
+	}
+	for i := range pricing.Lines {
+		pricing.Lines[i].Discount = lineDiscounts[pricing.Lines[i].OrderLineID]
+	}
+
+	pricing.Total = pricing.Subtotal.Add(pricing.DiscountTotal)
+
+	if order.ShippingMethodID == "" {
+		return pricing, nil
+	}
+
+	shipment, err := service.shipment(order)
+	if err != nil {
+		return nil, err
+	}
+
+	// Orders of digital products only are not shipped.
+	if len(shipment.Items) == 0 {
+		return pricing, nil
+	}
+
+	method, err := service.findShippingMethod(order.ShippingMethodID)
+	if err != nil {
+		return nil, err
+	}
+
+	shipment.Value = pricing.Total
+	cost, err := shippingCost(method, shipment)
+	if err != nil {
+		return nil, err
+	}
+
+	pricing.ShippingMethodID = method.ID
+	pricing.Shipping = cost
+	pricing.Total = pricing.Total.Add(cost)
+
+	return pricing, nil
+}
+
+// shippingCost returns the cost of shipping the shipment with method. A
+// method working in another currency than the shipment is not eligible.
+func shippingCost(method domain.ShippingMethod, shipment *domain.Shipment) (domain.Money, error) {
+	cost, err := method.Calculator.CalculateShippingCost(shipment)
+	if err == nil {
+		err = shipment.Value.MatchCurrency(cost)
+	}
+	if errors.Is(err, domain.ErrCurrencyMismatch) {
+		return domain.Money{}, fmt.Errorf("%w: %s: %v", domain.ErrShippingMethodNotEligible, method.ID, err)
+	}
+	if err != nil {
+		return domain.Money{}, err
+	}
+
+	return cost, nil
+}
+
+// GetPaymentMethods returns the ways customers can pay for their orders.
+func (service *ShopService) GetPaymentMethods() []domain.PaymentMethod {
+	return service.paymentMethods
+}
+
+// AddPayment pays what is left to pay for the order with the payment
+// method, the order is Paid once its total is settled. Declined and
+// failed payments are kept on the order, which stays ArrangingPayment
+// so the customer can try again.
+func (service *ShopService) AddPayment(orderID string, methodCode string, token string) (*domain.Order, error) {
+	method, err := service.findPaymentMethod(methodCode)
+	if err != nil {
+		return nil, err
+	}
 
-		order.Lines = append(order.Lines, &domain.OrderLine{
+	unlock := service.orderLocks.lock(orderID)
+	defer unlock()
+
+	order, err := service.store.Orders().FindByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Status != domain.OrderStatusArrangingPayment {
+		return nil, domain.ErrOrderNotAwaitingPayment
+	}
+
+	// A payment the provider didn't answer may have gone through, it is
+	// sent again with the same idempotency key rather than paying twice.
+	payment := pendingPayment(order)
+	if payment != nil {
+		if method, err = service.findPaymentMethod(payment.Method); err != nil {
+			return nil, err
+		}
+	} else {
+		payment = &domain.Payment{
 			ID:        xid.New().String(),
-			ProductID: productID,
-			Quantity:  quantity,
-			UnitPrice: product.UnitPrice,
+			Method:    method.Code,
+			Amount:    order.Pricing.Total.Sub(order.AmountPaid()),
+			State:     domain.PaymentStatePending,
+			CreatedAt: service.now(),
+		}
+		order.Payments = append(order.Payments, payment)
+
+		if err := service.store.Orders().Save(order); err != nil {
+			return nil, err
+		}
+	}
+
+	paymentErr := service.pay(method.Provider, order.ID, payment, token)
+
+	if paymentErr == nil && order.AmountPaid().Cmp(order.Pricing.Total) >= 0 {
+		if err := order.TransitionTo(domain.OrderStatusPaid); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := service.store.Orders().Save(order); err != nil {
+		return nil, err
+	}
+
+	if paymentErr != nil {
+		return nil, paymentErr
+	}
+
+	return order, nil
+}
+
+// pendingPayment returns the payment of the order still waiting for an
+// answer of the payment provider, or nil if there is none.
+func pendingPayment(order *domain.Order) *domain.Payment {
+	for _, payment := range order.Payments {
+		if payment.State == domain.PaymentStatePending {
+			return payment
+		}
+	}
+
+	return nil
+}
+
+// pay authorizes and captures the payment with provider, recording the
+// outcome in the state of the payment. The id of the payment is the
+// idempotency key of both, so a payment left Pending by a timeout can be
+// paid again, picking up where the provider stopped.
+func (service *ShopService) pay(provider domain.PaymentProvider, orderID string, payment *domain.Payment, token string) error {
+	if payment.TransactionID == "" {
+		transactionID, err := provider.Authorize(domain.PaymentRequest{
+			OrderID:        orderID,
+			Amount:         payment.Amount,
+			Token:          token,
+			IdempotencyKey: payment.ID,
 		})
+		if errors.Is(err, domain.ErrPaymentDeclined) {
+			payment.State = domain.PaymentStateDeclined
+			payment.ErrorMessage = err.Error()
+			return err
+		}
+		if errors.Is(err, domain.ErrPaymentTimeout) {
+			payment.ErrorMessage = err.Error()
+			return fmt.Errorf("%w: %v", domain.ErrPaymentPending, err)
+		}
+		if err != nil {
+			payment.State = domain.PaymentStateError
+			payment.ErrorMessage = err.Error()
+			return fmt.Errorf("%w: %v", domain.ErrPaymentFailed, err)
+		}
 
-		return order, nil
+		payment.TransactionID = transactionID
+	}
+
+	payment.State = domain.PaymentStateAuthorized
+	payment.ErrorMessage = ""
+
+	err := provider.Capture(payment.TransactionID, payment.Amount, payment.ID)
+	if errors.Is(err, domain.ErrPaymentTimeout) {
+		payment.State = domain.PaymentStatePending
+		payment.ErrorMessage = err.Error()
+		return fmt.Errorf("%w: %v", domain.ErrPaymentPending, err)
+	}
+	if err != nil {
+		payment.ErrorMessage = err.Error()
+
+		// The customer must not have money held for an order that is not
+		// paid, the payment stays Authorized if it can't be released.
+		if voidErr := provider.Void(payment.TransactionID); voidErr != nil {
+			payment.ErrorMessage += ", void failed: " + voidErr.Error()
+		} else {
+			payment.State = domain.PaymentStateCancelled
+		}
+
+		return fmt.Errorf("%w: %v", domain.ErrPaymentFailed, err)
+	}
+
+	payment.State = domain.PaymentStateSettled
+	return nil
+}
+
+func (service *ShopService) MarkOrderAsPaid(orderID string) (*domain.Order, error) {
+	return service.transitionOrder(orderID, domain.OrderStatusPaid)
+}
+
+func (service *ShopService) ShipOrder(orderID string) (*domain.Order, error) {
+	return service.transitionOrder(orderID, domain.OrderStatusShipped)
+}
+
+func (service *ShopService) DeliverOrder(orderID string) (*domain.Order, error) {
+	return service.transitionOrder(orderID, domain.OrderStatusDelivered)
+}
+
+// CancelOrder cancels the order, putting the items that were not
+// refunded yet back in stock if it was already checked out and refunding
+// them if it was paid.
+func (service *ShopService) CancelOrder(orderID string) (*domain.Order, error) {
+	unlock := service.orderLocks.lock(orderID)
+	defer unlock()
+
+	order, err := service.store.Orders().FindByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The stock is only taken at checkout.
+	restock := order.Status != domain.OrderStatusCreated
+	paid := order.IsPaid()
+	lines := unrefundedLines(order)
+
+	if err := order.TransitionTo(domain.OrderStatusCancelled); err != nil {
+		return nil, err
 	}
 
-	if (product.Quantity - (foundLine.Quantity + quantity)) < 0 {
-		return nil, domain.ErrNotEnoughStock
+	var refund *domain.Refund
+	if paid && len(lines) > 0 {
+		if refund, err = service.newRefund(order, lines, "order cancelled"); err != nil {
+			return nil, err
+		}
+		order.Refunds = append(order.Refunds, refund)
+	}
+
+	err = service.store.Transaction(func(tx domain.Store) error {
+		if restock {
+			if err := tx.Products().IncreaseStock(refundQuantities(order, lines)); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Reservations().DeleteByOrder(orderID); err != nil {
+			return err
+		}
+
+		return tx.Orders().Save(order)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	foundLine.Quantity += quantity
+	if refund != nil {
+		if err := service.sendRefund(order, refund); err != nil {
+			return nil, err
+		}
+	}
 
 	return order, nil
 }
 
-func (service *ShopService) RemoveItemFromCart(orderID string, productID string) (*domain.Order, error) {
-	order, ok := service.orderStore[orderID]
-	if !ok {
-		return nil, domain.ErrCartNotFound
+// RefundOrderLines refunds quantities of the lines of a paid order and
+// puts their items back in stock. The discounts of the promotions that no
+// longer apply to what the customer keeps are taken back from the
+// refund, e.g. refunding the product that made another one free. The
+// order is cancelled once all of its lines are refunded, unless it was
+// shipped already. The refund is saved before it is sent to the payment
+// provider, when the provider fails it is kept as failed and
+// ErrRefundFailed is returned.
+func (service *ShopService) RefundOrderLines(orderID string, lines []domain.RefundLine, reason string) (*domain.Refund, error) {
+	unlock := service.orderLocks.lock(orderID)
+	defer unlock()
+
+	order, err := service.store.Orders().FindByID(orderID)
+	if err != nil {
+		return nil, err
 	}
 
-	_, foundIdx := findLineInOrder(order, productID)
-	if foundIdx >= 0 {
-		order.Lines = append(order.Lines[:foundIdx], order.Lines[foundIdx+1:]...)
-		return order, nil
+	if !order.IsPaid() {
+		return nil, domain.ErrOrderNotRefundable
+	}
+
+	lines, err = mergeRefundLines(order, lines)
+	if err != nil {
+		return nil, err
+	}
+
+	refund, err := service.newRefund(order, lines, reason)
+	if err != nil {
+		return nil, err
+	}
+	order.Refunds = append(order.Refunds, refund)
+
+	if len(unrefundedLines(order)) == 0 && order.Status.CanTransitionTo(domain.OrderStatusCancelled) {
+		if err := order.TransitionTo(domain.OrderStatusCancelled); err != nil {
+			return nil, err
+		}
+	}
+
+	err = service.store.Transaction(func(tx domain.Store) error {
+		if err := tx.Products().IncreaseStock(refundQuantities(order, lines)); err != nil {
+			return err
+		}
+
+		return tx.Orders().Save(order)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.sendRefund(order, refund); err != nil {
+		return nil, err
+	}
+
+	return refund, nil
+}
+
+// newRefund computes what to give back for lines of the order. The
+// refund is pending until it is sent back to the payment of the order,
+// it is settled right away when there is nothing to send.
+func (service *ShopService) newRefund(order *domain.Order, lines []domain.RefundLine, reason string) (*domain.Refund, error) {
+	amount, err := service.refundAmount(order, lines)
+	if err != nil {
+		return nil, err
+	}
+
+	refund := &domain.Refund{
+		ID:        xid.New().String(),
+		Lines:     lines,
+		Amount:    amount,
+		Reason:    reason,
+		State:     domain.RefundStateSettled,
+		CreatedAt: service.now(),
+	}
+
+	if payment := settledPayment(order); payment != nil && !amount.IsZero() {
+		refund.State = domain.RefundStatePending
+		refund.PaymentID = payment.ID
+	}
+
+	return refund, nil
+}
+
+// sendRefund sends a pending refund back to its payment and saves the
+// order with the outcome. The id of the refund is the idempotency key, so
+// a refund sent again is not given back twice.
+func (service *ShopService) sendRefund(order *domain.Order, refund *domain.Refund) error {
+	if refund.State != domain.RefundStatePending {
+		return nil
+	}
+
+	refundErr := service.refundPayment(order, refund)
+	if err := service.store.Orders().Save(order); err != nil {
+		return err
+	}
+
+	return refundErr
+}
+
+// refundPayment asks the payment provider to make the refund, recording
+// the outcome in the state of the refund.
+func (service *ShopService) refundPayment(order *domain.Order, refund *domain.Refund) error {
+	var payment *domain.Payment
+	for _, p := range order.Payments {
+		if p.ID == refund.PaymentID {
+			payment = p
+		}
+	}
+
+	method, err := service.findPaymentMethod(payment.Method)
+	if err == nil {
+		refund.TransactionID, err = method.Provider.Refund(payment.TransactionID, refund.Amount, refund.ID)
+	}
+	if err != nil {
+		refund.State = domain.RefundStateFailed
+		refund.ErrorMessage = err.Error()
+		return fmt.Errorf("%w: %v", domain.ErrRefundFailed, err)
+	}
+
+	refund.State = domain.RefundStateSettled
+	return nil
+}
+
+// refundAmount returns what is left of the total of the order once the
+// items the customer keeps are paid for. They are priced with the
+// promotions applied at checkout as they were then, so only the discounts
+// that no longer apply are taken back. Shipping is only refunded with the
+// last items.
+func (service *ShopService) refundAmount(order *domain.Order, lines []domain.RefundLine) (domain.Money, error) {
+	refunding := make(map[string]int, len(lines))
+	for _, line := range lines {
+		refunding[line.OrderLineID] += line.Quantity
+	}
+
+	kept := order.Clone()
+	kept.ShippingMethodID = ""
+	kept.Lines = nil
+	for _, line := range order.Lines {
+		quantity := line.Quantity - order.RefundedQuantity(line.ID) - refunding[line.ID]
+		if quantity > 0 {
+			l := line.Clone()
+			l.Quantity = quantity
+			kept.Lines = append(kept.Lines, l)
+		}
+	}
+
+	applied, err := service.checkedOutPromotions(order)
+	if err != nil {
+		return domain.Money{}, err
+	}
+
+	pricing, err := service.priceWith(kept, applied)
+	if err != nil {
+		return domain.Money{}, err
+	}
+
+	keptTotal := pricing.Total
+	if len(kept.Lines) > 0 {
+		keptTotal = keptTotal.Add(order.Pricing.Shipping)
+	}
+
+	amount := order.Pricing.Total.Sub(order.AmountRefunded()).Sub(keptTotal)
+	if amount.IsNegative() {
+		return domain.NewMoney(0, amount.Currency), nil
 	}
 
-	return nil, domain.ErrItemNotFoundInCart
+	return amount, nil
+}
+
+// ReleaseExpiredReservations deletes the reservations of abandoned carts
+// and returns how many there were. Expired reservations already don't
+// hold any stock, releasing them only frees their storage.
+func (service *ShopService) ReleaseExpiredReservations() (int, error) {
+	return service.store.Reservations().DeleteExpired(service.now())
 }
 
-func (service *ShopService) Checkout(orderID string) (totalAmount float64, err error) {
-	order, ok := service.orderStore[orderID]
-	if !ok {
-		return 0, domain.ErrCartNotFound
+func (service *ShopService) transitionOrder(orderID string, status domain.OrderStatus) (*domain.Order, error) {
+	unlock := service.orderLocks.lock(orderID)
+	defer unlock()
+
+	order, err := service.store.Orders().FindByID(orderID)
+	if err != nil {
+		return nil, err
 	}
 
-	service.invMutex.Lock()
-	defer service.invMutex.Unlock()
+	if err := order.TransitionTo(status); err != nil {
+		return nil, err
+	}
+
+	if err := service.store.Orders().Save(order); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
 
+// shipment returns the physical products of the order and where they
+// are shipped to, its value is left to the caller.
+func (service *ShopService) shipment(order *domain.Order) (*domain.Shipment, error) {
+	shipment := &domain.Shipment{Address: order.ShippingAddress}
 	for _, line := range order.Lines {
-		product, ok := service.inventories[line.ProductID]
-		if !ok {
-			return 0, domain.ErrSomeProductInCartNotFound
+		product, err := service.store.Products().FindByID(line.ProductID)
+		if errors.Is(err, domain.ErrProductNotFound) {
+			return nil, domain.ErrSomeProductInCartNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if !product.Digital {
+			shipment.Items = append(shipment.Items, domain.ShipmentItem{Product: product, Quantity: line.Quantity})
+		}
+	}
+
+	return shipment, nil
+}
+
+func (service *ShopService) findPaymentMethod(code string) (domain.PaymentMethod, error) {
+	for _, method := range service.paymentMethods {
+		if method.Code == code {
+			return method, nil
 		}
+	}
+
+	return domain.PaymentMethod{}, domain.ErrPaymentMethodNotFound
+}
 
-		if (product.Quantity - line.Quantity) < 0 {
-			return 0, domain.ErrSomeProductInCartNotEnoughInStock
+func (service *ShopService) findShippingMethod(methodID string) (domain.ShippingMethod, error) {
+	for _, method := range service.shippingMethods {
+		if method.ID == methodID {
+			return method, nil
 		}
+	}
 
-		product.Quantity -= line.Quantity
+	return domain.ShippingMethod{}, domain.ErrShippingMethodNotFound
+}
 
-		totalAmount += line.UnitPrice * float64(line.Quantity)
+// availableToSell returns the stock of the product that is not held by
+// the carts of other orders.
+func (service *ShopService) availableToSell(tx domain.Store, product *domain.Product, orderID string, now time.Time) (int, error) {
+	if service.reservationTTL <= 0 {
+		return product.Quantity, nil
 	}
 
-	if len(service.promotions) == 0 {
-		return totalAmount, nil
+	reserved, err := tx.Reservations().ReservedQuantity(product.ID, now)
+	if err != nil {
+		return 0, err
 	}
 
-	now := time.Now()
-	for _, promotion := range service.promotions {
-		if !promotion.StartDate.IsZero() && promotion.StartDate.After(now) {
-			continue
+	own, err := tx.Reservations().FindByOrder(orderID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, reservation := range own {
+		if reservation.ProductID == product.ID && reservation.IsActive(now) {
+			reserved -= reservation.Quantity
+		}
+	}
+
+	return product.Quantity - reserved, nil
+}
+
+// checkAvailability returns ErrNotEnoughStock if one of the quantities
+// is more than what can be sold to the order.
+func (service *ShopService) checkAvailability(tx domain.Store, orderID string, quantities map[string]int, now time.Time) error {
+	for productID, quantity := range quantities {
+		product, err := tx.Products().FindByID(productID)
+		if err != nil {
+			return fmt.Errorf("%w: %s", err, productID)
 		}
 
-		if !promotion.EndDate.IsZero() && promotion.EndDate.Before(now) {
-			continue
+		available, err := service.availableToSell(tx, product, orderID, now)
+		if err != nil {
+			return err
+		}
+
+		if available < quantity {
+			return fmt.Errorf("%w: %s", domain.ErrNotEnoughStock, productID)
+		}
+	}
+
+	return nil
+}
+
+func ownerKey(owner domain.OrderOwner) string {
+	if owner.CustomerID != "" {
+		return "customer:" + owner.CustomerID
+	}
+
+	return "session:" + owner.SessionID
+}
+
+// mergeRefundLines returns the lines to refund with one entry per order
+// line, making sure their quantities were not refunded yet.
+func mergeRefundLines(order *domain.Order, lines []domain.RefundLine) ([]domain.RefundLine, error) {
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%w: nothing to refund", domain.ErrInvalidRefundQuantity)
+	}
+
+	var merged []domain.RefundLine
+	indexes := make(map[string]int, len(lines))
+	for _, line := range lines {
+		if line.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: %d", domain.ErrInvalidRefundQuantity, line.Quantity)
 		}
 
-		if promotion.Condition == nil {
+		if index, ok := indexes[line.OrderLineID]; ok {
+			merged[index].Quantity += line.Quantity
 			continue
 		}
 
-		totalAmount += promotion.Condition.CalculateDiscount(order)
+		indexes[line.OrderLineID] = len(merged)
+		merged = append(merged, line)
+	}
+
+	for _, line := range merged {
+		orderLine := findLineByID(order, line.OrderLineID)
+		if orderLine == nil {
+			return nil, domain.ErrItemNotFoundInCart
+		}
+
+		if refundable := orderLine.Quantity - order.RefundedQuantity(line.OrderLineID); line.Quantity > refundable {
+			return nil, fmt.Errorf("%w: only %d left to refund on line %s", domain.ErrInvalidRefundQuantity, refundable, line.OrderLineID)
+		}
+	}
+
+	return merged, nil
+}
+
+// unrefundedLines returns what was not refunded yet of every line of the
+// order.
+func unrefundedLines(order *domain.Order) []domain.RefundLine {
+	var lines []domain.RefundLine
+	for _, line := range order.Lines {
+		if quantity := line.Quantity - order.RefundedQuantity(line.ID); quantity > 0 {
+			lines = append(lines, domain.RefundLine{OrderLineID: line.ID, Quantity: quantity})
+		}
+	}
+
+	return lines
+}
+
+// refundQuantities returns the quantity of each product of the lines.
+func refundQuantities(order *domain.Order, lines []domain.RefundLine) map[string]int {
+	quantities := make(map[string]int, len(lines))
+	for _, line := range lines {
+		if orderLine := findLineByID(order, line.OrderLineID); orderLine != nil {
+			quantities[orderLine.ProductID] += line.Quantity
+		}
+	}
+
+	return quantities
+}
+
+// settledPayment returns the payment that paid for the order, if any.
+func settledPayment(order *domain.Order) *domain.Payment {
+	for i := len(order.Payments) - 1; i >= 0; i-- {
+		if order.Payments[i].State == domain.PaymentStateSettled {
+			return order.Payments[i]
+		}
+	}
+
+	return nil
+}
+
+func findLineByID(order *domain.Order, lineID string) *domain.OrderLine {
+	for _, line := range order.Lines {
+		if line.ID == lineID {
+			return line
+		}
 	}
 
-	return round(totalAmount), nil
+	return nil
 }
 
-// Round to nearest 2 digit after deciaml point
-func round(k float64) float64 {
-	return math.Round(k*100) / 100
+// lineQuantities returns the quantity ordered of each product.
+func lineQuantities(order *domain.Order) map[string]int {
+	quantities := make(map[string]int, len(order.Lines))
+	for _, line := range order.Lines {
+		quantities[line.ProductID] += line.Quantity
+	}
+
+	return quantities
 }
 
 func findLineInOrder(order *domain.Order, productID string) (foundLine *domain.OrderLine, index int) {
@@ -1,15 +1,33 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 
 	"github.com/donnpebe/shoppo/pkg/domain"
+	"github.com/donnpebe/shoppo/pkg/lib/paymentprovider"
+	"github.com/donnpebe/shoppo/pkg/lib/promotioncondition"
 	"github.com/donnpebe/shoppo/pkg/lib/promotioncondition/mock"
+	"github.com/donnpebe/shoppo/pkg/lib/shippingcalculator"
+	"github.com/donnpebe/shoppo/pkg/storage/memory"
 )
 
+var testShippingAddress = domain.Address{
+	StreetLine: "Jl. Jend. Sudirman Kav. 52-53",
+	City:       "Jakarta Selatan",
+	Province:   "DKI Jakarta",
+	Country:    "ID",
+}
+
 func TestShopService_CreateCart(t *testing.T) {
 	tests := []struct {
 		name string
@@ -21,19 +39,96 @@ func TestShopService_CreateCart(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			orderStore := make(map[string]*domain.Order)
-			sut := NewShopService(nil, nil, orderStore)
+			store := memory.NewStore(nil, nil)
+			sut := NewShopService(store, nil)
 
-			got := sut.CreateCart()
+			got, err := sut.CreateCart()
+			assert.NoError(t, err)
 			assert.NotNil(t, got)
 			assert.NotEmpty(t, got.ID)
-			gotInStore, ok := orderStore[got.ID]
-			assert.True(t, ok)
+			gotInStore, err := store.Orders().FindByID(got.ID)
+			assert.NoError(t, err)
 			assert.Equal(t, got.ID, gotInStore.ID)
 		})
 	}
 }
 
+func TestShopService_GetOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		orderID string
+		wantErr error
+	}{
+		{
+			name:    "should return order in store",
+			orderID: "order1",
+		},
+		{
+			name:    "should return error when order is not in store",
+			orderID: "order2",
+			wantErr: domain.ErrCartNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			orderStore := map[string]*domain.Order{
+				"order1": {ID: "order1"},
+			}
+			sut := NewShopService(memory.NewStore(nil, orderStore), nil)
+
+			got, err := sut.GetOrder(test.orderID)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, orderStore[test.orderID], got)
+			}
+		})
+	}
+}
+
+func TestShopService_GetProduct(t *testing.T) {
+	tests := []struct {
+		name      string
+		productID string
+		wantErr   error
+	}{
+		{
+			name:      "should return product in inventories",
+			productID: "p01",
+		},
+		{
+			name:      "should return error when product is not in inventories",
+			productID: "p02",
+			wantErr:   domain.ErrProductNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inventories := map[string]*domain.Product{
+				"p01": {
+					ID:        "p01",
+					SKU:       "120P90",
+					Name:      "Google Home",
+					UnitPrice: domain.MustParseMoney("49.99", "USD"),
+					Quantity:  10,
+				},
+			}
+			sut := NewShopService(memory.NewStore(inventories, nil), nil)
+
+			got, err := sut.GetProduct(test.productID)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, inventories[test.productID], got)
+			}
+		})
+	}
+}
+
 func TestShopService_ListProducts(t *testing.T) {
 	tests := []struct {
 		name string
@@ -50,20 +145,21 @@ func TestShopService_ListProducts(t *testing.T) {
 					ID:        "p01",
 					SKU:       "120P90",
 					Name:      "Google Home",
-					UnitPrice: 49.99,
+					UnitPrice: domain.MustParseMoney("49.99", "USD"),
 					Quantity:  10,
 				},
 				"p02": {
 					ID:        "p02",
 					SKU:       "43N23P",
 					Name:      "MacBook Pro",
-					UnitPrice: 5399.99,
+					UnitPrice: domain.MustParseMoney("5399.99", "USD"),
 					Quantity:  5,
 				},
 			}
 
-			sut := NewShopService(inventories, nil, nil)
-			got := sut.ListProducts()
+			sut := NewShopService(memory.NewStore(inventories, nil), nil)
+			got, err := sut.ListProducts()
+			assert.NoError(t, err)
 			assert.Len(t, got, 2)
 
 			for _, g := range got {
@@ -73,20 +169,135 @@ func TestShopService_ListProducts(t *testing.T) {
 	}
 }
 
+func TestShopService_Variants(t *testing.T) {
+	inventories := map[string]*domain.Product{
+		"p01": {
+			ID:        "p01",
+			SKU:       "120P90",
+			Name:      "Google Home",
+			UnitPrice: domain.MustParseMoney("49.99", "USD"),
+			Quantity:  10,
+		},
+		"p02": {
+			ID:        "p02",
+			Name:      "MacBook Pro",
+			UnitPrice: domain.MustParseMoney("5399.99", "USD"),
+		},
+		"p02-16gb": {
+			ID:        "p02-16gb",
+			SKU:       "43N23P-16",
+			Name:      "MacBook Pro 16GB",
+			UnitPrice: domain.MustParseMoney("5399.99", "USD"),
+			Quantity:  5,
+			ParentID:  "p02",
+			Options:   []domain.ProductOption{{Name: "Memory", Value: "16GB"}},
+		},
+		"p02-32gb": {
+			ID:        "p02-32gb",
+			SKU:       "43N23P-32",
+			Name:      "MacBook Pro 32GB",
+			UnitPrice: domain.MustParseMoney("5999.99", "USD"),
+			Quantity:  2,
+			ParentID:  "p02",
+			Options:   []domain.ProductOption{{Name: "Memory", Value: "32GB"}},
+		},
+	}
+
+	sut := NewShopService(memory.NewStore(inventories, nil), []domain.Promotion{
+		{
+			ID:        "promo0",
+			Name:      "10% off every MacBook Pro",
+			Condition: promotioncondition.ProductPercentageDiscount{ProductID: "p02", MinQuantity: 1, DiscountInPercent: 10},
+		},
+		{
+			ID:        "promo1",
+			Name:      "5% off the 32GB MacBook Pro",
+			Condition: promotioncondition.ProductPercentageDiscount{ProductID: "p02-32gb", MinQuantity: 1, DiscountInPercent: 5},
+		},
+	})
+
+	t.Run("should list variants under their product", func(t *testing.T) {
+		products, err := sut.ListProducts()
+		require.NoError(t, err)
+		sort.Slice(products, func(i, j int) bool { return products[i].ID < products[j].ID })
+
+		require.Len(t, products, 2)
+		assert.Empty(t, products[0].Variants)
+		assert.Equal(t, []*domain.Product{inventories["p02-16gb"], inventories["p02-32gb"]}, products[1].Variants)
+	})
+
+	t.Run("should return a product with its variants", func(t *testing.T) {
+		product, err := sut.GetProduct("p02")
+		require.NoError(t, err)
+		assert.Equal(t, []*domain.Product{inventories["p02-16gb"], inventories["p02-32gb"]}, product.Variants)
+
+		variant, err := sut.GetProduct("p02-32gb")
+		require.NoError(t, err)
+		assert.Equal(t, inventories["p02-32gb"], variant)
+	})
+
+	t.Run("should sum the stock of the variants", func(t *testing.T) {
+		level, err := sut.GetStockLevel("p02")
+		require.NoError(t, err)
+		assert.Equal(t, &domain.StockLevel{ProductID: "p02", OnHand: 7, Available: 7}, level)
+	})
+
+	t.Run("should only sell a product that has variants through them", func(t *testing.T) {
+		order, err := sut.CreateCart()
+		require.NoError(t, err)
+
+		_, err = sut.AddItemToCart(order.ID, "p02", 1)
+		assert.ErrorIs(t, err, domain.ErrVariantRequired)
+
+		_, err = sut.AddItemToCart(order.ID, "p02-32gb", 3)
+		assert.ErrorIs(t, err, domain.ErrNotEnoughStock)
+
+		order, err = sut.AddItemToCart(order.ID, "p02-32gb", 1)
+		require.NoError(t, err)
+		require.Len(t, order.Lines, 1)
+		assert.Equal(t, "p02-32gb", order.Lines[0].ProductID)
+		assert.Equal(t, "p02", order.Lines[0].ParentProductID)
+		assert.Equal(t, "43N23P-32", order.Lines[0].SKU)
+		assert.Equal(t, domain.MustParseMoney("5999.99", "USD"), order.Lines[0].UnitPrice)
+	})
+
+	t.Run("should discount the variants a promotion targets", func(t *testing.T) {
+		order, err := sut.CreateCart()
+		require.NoError(t, err)
+		_, err = sut.AddItemToCart(order.ID, "p02-16gb", 1)
+		require.NoError(t, err)
+		_, err = sut.AddItemToCart(order.ID, "p02-32gb", 1)
+		require.NoError(t, err)
+
+		quote, err := sut.QuoteOrder(order.ID)
+		require.NoError(t, err)
+		require.Len(t, quote.Lines, 2)
+		assert.Equal(t, domain.MustParseMoney("-540", "USD"), quote.Lines[0].Discount)
+		assert.Equal(t, domain.MustParseMoney("-900", "USD"), quote.Lines[1].Discount)
+	})
+}
+
 func TestShopService_AddItemToCart(t *testing.T) {
 	inventories := map[string]*domain.Product{
 		"p01": {
 			ID:        "p01",
 			SKU:       "120P90",
 			Name:      "Google Home",
-			UnitPrice: 49.99,
+			UnitPrice: domain.MustParseMoney("49.99", "USD"),
 			Quantity:  5,
 		},
 		"p02": {
 			ID:        "p02",
 			SKU:       "43N23P",
 			Name:      "Macbook Pro",
-			UnitPrice: 5399.99,
+			UnitPrice: domain.MustParseMoney("5399.99", "USD"),
+			Quantity:  4,
+		},
+		"p03": {
+			ID:        "p03",
+			SKU:       "RPI4B",
+			Name:      "Raspberry Pi",
+			UnitPrice: domain.MustParseMoney("450000", "IDR"),
 			Quantity:  4,
 		},
 	}
@@ -182,6 +393,30 @@ func TestShopService_AddItemToCart(t *testing.T) {
 			},
 			wantErr: domain.ErrProductNotFound,
 		},
+		{
+			name: "should return error when adding no item",
+			input: []args{
+				{
+					productID: "p01",
+					quantity:  0,
+				},
+			},
+			wantErr: domain.ErrInvalidQuantity,
+		},
+		{
+			name: "should return error when adding a negative quantity",
+			input: []args{
+				{
+					productID: "p01",
+					quantity:  2,
+				},
+				{
+					productID: "p01",
+					quantity:  -1,
+				},
+			},
+			wantErr: domain.ErrInvalidQuantity,
+		},
 		{
 			name: "should return error when adding a product with quantity more than what's in inventories",
 			input: []args{
@@ -192,24 +427,38 @@ func TestShopService_AddItemToCart(t *testing.T) {
 			},
 			wantErr: domain.ErrNotEnoughStock,
 		},
+		{
+			name: "should return error when adding a product in another currency than the cart",
+			input: []args{
+				{
+					productID: "p01",
+					quantity:  1,
+				},
+				{
+					productID: "p03",
+					quantity:  1,
+				},
+			},
+			wantErr: domain.ErrCurrencyMismatch,
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			orderStore := make(map[string]*domain.Order)
+			sut := NewShopService(memory.NewStore(inventories, nil), nil)
+			order, err := sut.CreateCart()
+			assert.NoError(t, err)
 
-			sut := NewShopService(inventories, nil, orderStore)
-			order := sut.CreateCart()
-			var (
-				got *domain.Order
-				err error
-			)
+			var got *domain.Order
 
 			if test.wantErr != nil {
-				for _, arg := range test.input {
+				last := len(test.input) - 1
+				for _, arg := range test.input[:last] {
 					_, err = sut.AddItemToCart(order.ID, arg.productID, arg.quantity)
-					assert.ErrorIs(t, err, test.wantErr)
+					require.NoError(t, err)
 				}
+				_, err = sut.AddItemToCart(order.ID, test.input[last].productID, test.input[last].quantity)
+				assert.ErrorIs(t, err, test.wantErr)
 			} else {
 
 				for _, arg := range test.input {
@@ -260,24 +509,26 @@ func TestShopService_RemoveItemFromCart(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			orderStore := map[string]*domain.Order{
 				"order1": {
+					ID:     "order1",
+					Status: domain.OrderStatusCreated,
 					Lines: []*domain.OrderLine{
 						{
 							ID:        "line1",
 							ProductID: "p01",
 							Quantity:  2,
-							UnitPrice: 10,
+							UnitPrice: domain.MustParseMoney("10", "USD"),
 						},
 						{
 							ID:        "line1",
 							ProductID: "p02",
 							Quantity:  5,
-							UnitPrice: 40,
+							UnitPrice: domain.MustParseMoney("40", "USD"),
 						},
 					},
 				},
 			}
 
-			sut := NewShopService(nil, nil, orderStore)
+			sut := NewShopService(memory.NewStore(nil, orderStore), nil)
 
 			order, err := sut.RemoveItemFromCart(test.input.orderID, test.input.productID)
 
@@ -306,11 +557,12 @@ func TestShopService_Checkout(t *testing.T) {
 	type mockBehavior func(ms ...*mock.MockPromotionCondition)
 
 	tests := []struct {
-		name    string
-		mock    mockBehavior
-		input   args
-		want    float64
-		wantErr error
+		name          string
+		mock          mockBehavior
+		input         args
+		want          domain.Money
+		wantDiscounts []domain.AppliedPromotion
+		wantErr       error
 	}{
 		{
 			name: "should return full total amount if promotions not provided",
@@ -324,7 +576,7 @@ func TestShopService_Checkout(t *testing.T) {
 					quantity:  1,
 				},
 			}},
-			want: 59.99,
+			want: domain.MustParseMoney("59.99", "USD"),
 		},
 		{
 			name: "should return discounted total amount if promotion provided",
@@ -339,9 +591,12 @@ func TestShopService_Checkout(t *testing.T) {
 				},
 			}},
 			mock: func(ms ...*mock.MockPromotionCondition) {
-				ms[0].EXPECT().CalculateDiscount(gomock.Any()).Return(-10.0)
+				ms[0].EXPECT().CalculateDiscount(gomock.Any()).Return(domain.MustParseMoney("-10", "USD"))
+			},
+			want: domain.MustParseMoney("49.99", "USD"),
+			wantDiscounts: []domain.AppliedPromotion{
+				{PromotionID: "promo0", Name: "Promotion 0", Amount: domain.MustParseMoney("-10", "USD")},
 			},
-			want: 49.99,
 		},
 		{
 			name: "should return discounted total amount if multiple promotion provided",
@@ -357,10 +612,54 @@ func TestShopService_Checkout(t *testing.T) {
 			}},
 			mock: func(ms ...*mock.MockPromotionCondition) {
 				for _, m := range ms {
-					m.EXPECT().CalculateDiscount(gomock.Any()).Return(-10.0)
+					m.EXPECT().CalculateDiscount(gomock.Any()).Return(domain.MustParseMoney("-10", "USD"))
+				}
+			},
+			want: domain.MustParseMoney("39.99", "USD"),
+			wantDiscounts: []domain.AppliedPromotion{
+				{PromotionID: "promo0", Name: "Promotion 0", Amount: domain.MustParseMoney("-10", "USD")},
+				{PromotionID: "promo1", Name: "Promotion 1", Amount: domain.MustParseMoney("-10", "USD")},
+			},
+		},
+		{
+			name: "should not list promotions that give no discount",
+			input: args{promotionsCount: 2, items: []item{
+				{
+					productID: "p01",
+					quantity:  1,
+				},
+			}},
+			mock: func(ms ...*mock.MockPromotionCondition) {
+				ms[0].EXPECT().CalculateDiscount(gomock.Any()).Return(domain.MustParseMoney("0", "USD"))
+				ms[1].EXPECT().CalculateDiscount(gomock.Any()).Return(domain.MustParseMoney("-5", "USD"))
+			},
+			want: domain.MustParseMoney("44.99", "USD"),
+			wantDiscounts: []domain.AppliedPromotion{
+				{PromotionID: "promo1", Name: "Promotion 1", Amount: domain.MustParseMoney("-5", "USD")},
+			},
+		},
+		{
+			name: "should never discount the order below zero",
+			input: args{promotionsCount: 2, items: []item{
+				{
+					productID: "p01",
+					quantity:  1,
+				},
+				{
+					productID: "p02",
+					quantity:  1,
+				},
+			}},
+			mock: func(ms ...*mock.MockPromotionCondition) {
+				for _, m := range ms {
+					m.EXPECT().CalculateDiscount(gomock.Any()).Return(domain.MustParseMoney("-40", "USD"))
 				}
 			},
-			want: 39.99,
+			want: domain.MustParseMoney("0", "USD"),
+			wantDiscounts: []domain.AppliedPromotion{
+				{PromotionID: "promo0", Name: "Promotion 0", Amount: domain.MustParseMoney("-40", "USD")},
+				{PromotionID: "promo1", Name: "Promotion 1", Amount: domain.MustParseMoney("-19.99", "USD")},
+			},
 		},
 		{
 			name:    "should return error if provided with invalid order id",
@@ -376,14 +675,14 @@ func TestShopService_Checkout(t *testing.T) {
 					ID:        "p01",
 					SKU:       "120P90",
 					Name:      "Google Home",
-					UnitPrice: 49.99,
+					UnitPrice: domain.MustParseMoney("49.99", "USD"),
 					Quantity:  5,
 				},
 				"p02": {
 					ID:        "p02",
 					SKU:       "43N23P",
 					Name:      "Pen",
-					UnitPrice: 10,
+					UnitPrice: domain.MustParseMoney("10", "USD"),
 					Quantity:  4,
 				},
 			}
@@ -398,33 +697,2100 @@ func TestShopService_Checkout(t *testing.T) {
 				for i := 0; i < test.input.promotionsCount; i++ {
 					cond := mock.NewMockPromotionCondition(c)
 					conds = append(conds, cond)
-					promotions = append(promotions, domain.Promotion{Condition: cond})
+					promotions = append(promotions, domain.Promotion{
+						ID:        fmt.Sprintf("promo%d", i),
+						Name:      fmt.Sprintf("Promotion %d", i),
+						Condition: cond,
+					})
 				}
 
 				test.mock(conds...)
 			}
 
-			orderStore := make(map[string]*domain.Order)
-			sut := NewShopService(inventories, promotions, orderStore)
-			order := sut.CreateCart()
+			sut := NewShopService(memory.NewStore(inventories, nil), promotions)
+			order, err := sut.CreateCart()
+			assert.NoError(t, err)
 
 			for _, item := range test.input.items {
 				_, _ = sut.AddItemToCart(order.ID, item.productID, item.quantity)
 			}
 
+			_, err = sut.SetShippingAddress(order.ID, testShippingAddress)
+			assert.NoError(t, err)
+
 			orderID := order.ID
 			if test.input.useInvalidOrderID {
 				orderID = "invalid"
 			}
-			totalAmount, err := sut.Checkout(orderID)
+			pricing, err := sut.Checkout(orderID)
 
 			if test.wantErr != nil {
 				assert.ErrorIs(t, err, test.wantErr)
 			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.want, pricing.Total)
+				assert.Equal(t, test.wantDiscounts, withoutLines(pricing.Discounts))
+				assert.Len(t, pricing.Lines, len(test.input.items))
+
+				var lineDiscounts domain.Money
+				for _, line := range pricing.Lines {
+					lineDiscounts = lineDiscounts.Add(line.Discount)
+					assert.False(t, line.Total().IsNegative())
+				}
+				assert.Equal(t, pricing.DiscountTotal.Amount, lineDiscounts.Amount)
 
+				got, err := sut.GetOrder(orderID)
 				assert.NoError(t, err)
-				assert.Equal(t, test.want, totalAmount)
+				assert.Equal(t, pricing, got.Pricing)
 			}
 		})
 	}
 }
+
+func TestShopService_QuoteOrder(t *testing.T) {
+	inventories := map[string]*domain.Product{
+		"p01": {
+			ID:        "p01",
+			SKU:       "120P90",
+			Name:      "Google Home",
+			UnitPrice: domain.MustParseMoney("49.99", "USD"),
+			Quantity:  5,
+		},
+	}
+
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	cond := mock.NewMockPromotionCondition(c)
+	cond.EXPECT().CalculateDiscount(gomock.Any()).Return(domain.MustParseMoney("-10", "USD")).Times(2)
+
+	sut := NewShopService(memory.NewStore(inventories, nil), []domain.Promotion{
+		{ID: "promo0", Name: "Promotion 0", Condition: cond},
+	})
+
+	order, err := sut.CreateCart()
+	assert.NoError(t, err)
+	_, err = sut.AddItemToCart(order.ID, "p01", 2)
+	assert.NoError(t, err)
+
+	quote, err := sut.QuoteOrder(order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, &domain.Pricing{
+		Lines: []domain.LinePricing{
+			{
+				OrderLineID: quote.Lines[0].OrderLineID,
+				ProductID:   "p01",
+				Quantity:    2,
+				UnitPrice:   domain.MustParseMoney("49.99", "USD"),
+				Subtotal:    domain.MustParseMoney("99.98", "USD"),
+				Discount:    domain.MustParseMoney("-10", "USD"),
+			},
+		},
+		Subtotal: domain.MustParseMoney("99.98", "USD"),
+		Discounts: []domain.AppliedPromotion{
+			{
+				PromotionID: "promo0",
+				Name:        "Promotion 0",
+				Amount:      domain.MustParseMoney("-10", "USD"),
+				Lines: []domain.LineDiscount{
+					{OrderLineID: quote.Lines[0].OrderLineID, Quantity: 2, Amount: domain.MustParseMoney("-10", "USD")},
+				},
+			},
+		},
+		DiscountTotal: domain.MustParseMoney("-10", "USD"),
+		Total:         domain.MustParseMoney("89.98", "USD"),
+	}, quote)
+
+	got, err := sut.GetOrder(order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.OrderStatusCreated, got.Status)
+	assert.Nil(t, got.Pricing)
+
+	product, err := sut.GetProduct("p01")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, product.Quantity)
+
+	_, err = sut.SetShippingAddress(order.ID, testShippingAddress)
+	assert.NoError(t, err)
+
+	pricing, err := sut.Checkout(order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, quote, pricing)
+
+	_, err = sut.QuoteOrder("invalid")
+	assert.ErrorIs(t, err, domain.ErrCartNotFound)
+}
+
+func TestShopService_QuoteOrderCurrencyMismatch(t *testing.T) {
+	inventories := map[string]*domain.Product{
+		"p01": {ID: "p01", Name: "Google Home", UnitPrice: domain.MustParseMoney("49.99", "USD"), Quantity: 5, Weight: 500},
+		"p02": {ID: "p02", Name: "Raspberry Pi", UnitPrice: domain.MustParseMoney("450000", "IDR"), Quantity: 5, Weight: 50},
+	}
+	orders := map[string]*domain.Order{
+		// Stored before carts were kept to a single currency.
+		"mixed": {
+			ID:     "mixed",
+			Status: domain.OrderStatusCreated,
+			Lines: []*domain.OrderLine{
+				{ID: "l1", ProductID: "p01", Quantity: 1, UnitPrice: domain.MustParseMoney("49.99", "USD")},
+				{ID: "l2", ProductID: "p02", Quantity: 1, UnitPrice: domain.MustParseMoney("450000", "IDR")},
+			},
+		},
+	}
+
+	sut := NewShopService(memory.NewStore(inventories, orders), nil, WithShippingMethods(
+		domain.ShippingMethod{
+			ID:   "usd",
+			Code: "usd",
+			Calculator: shippingcalculator.FreeOverThreshold{
+				Threshold:  domain.MustParseMoney("100", "USD"),
+				Calculator: shippingcalculator.FlatRate{Cost: domain.MustParseMoney("10", "USD")},
+			},
+		},
+		domain.ShippingMethod{ID: "idr", Code: "idr", Calculator: shippingcalculator.FlatRate{Cost: domain.MustParseMoney("20000", "IDR")}},
+	))
+
+	_, err := sut.QuoteOrder("mixed")
+	assert.ErrorIs(t, err, domain.ErrCurrencyMismatch)
+
+	_, err = sut.SetShippingAddress("mixed", testShippingAddress)
+	require.NoError(t, err)
+	_, err = sut.SetShippingMethod("mixed", "idr")
+	assert.ErrorIs(t, err, domain.ErrCurrencyMismatch)
+
+	order, err := sut.CreateCart()
+	require.NoError(t, err)
+	_, err = sut.AddItemToCart(order.ID, "p02", 1)
+	require.NoError(t, err)
+	_, err = sut.SetShippingAddress(order.ID, testShippingAddress)
+	require.NoError(t, err)
+
+	quotes, err := sut.GetEligibleShippingMethods(order.ID)
+	require.NoError(t, err)
+	require.Len(t, quotes, 1)
+	assert.Equal(t, "idr", quotes[0].Method.ID)
+
+	_, err = sut.SetShippingMethod(order.ID, "usd")
+	assert.ErrorIs(t, err, domain.ErrShippingMethodNotEligible)
+}
+
+func TestShopService_QuoteOrderWithProductSelector(t *testing.T) {
+	inventories := map[string]*domain.Product{
+		"p01": {
+			ID:         "p01",
+			SKU:        "120P90",
+			Name:       "Google Home",
+			UnitPrice:  domain.MustParseMoney("49.99", "USD"),
+			Quantity:   5,
+			Categories: []string{"smart-home"},
+			Tags:       []string{"sale"},
+		},
+		"p02": {
+			ID:         "p02",
+			SKU:        "43N23P",
+			Name:       "MacBook Pro",
+			UnitPrice:  domain.MustParseMoney("5399.99", "USD"),
+			Quantity:   5,
+			Categories: []string{"computers"},
+		},
+	}
+
+	store := memory.NewStore(inventories, nil)
+	sut := NewShopService(store, []domain.Promotion{
+		{
+			ID:   "promo0",
+			Name: "Smart home sale",
+			Condition: promotioncondition.ProductPercentageDiscount{
+				Products:          domain.ProductSelector{Categories: []string{"smart-home"}, Tags: []string{"sale"}},
+				MinQuantity:       1,
+				DiscountInPercent: 10,
+			},
+		},
+	})
+
+	order, err := sut.CreateCart()
+	require.NoError(t, err)
+	_, err = sut.AddItemToCart(order.ID, "p01", 2)
+	require.NoError(t, err)
+	order, err = sut.AddItemToCart(order.ID, "p02", 1)
+	require.NoError(t, err)
+
+	// Lines keep the classification of the product when it was added, a
+	// later change of the product doesn't change what they are discounted.
+	assert.Equal(t, "120P90", order.Lines[0].SKU)
+	assert.Equal(t, []string{"smart-home"}, order.Lines[0].Categories)
+	assert.Equal(t, []string{"sale"}, order.Lines[0].Tags)
+
+	product, err := sut.GetProduct("p01")
+	require.NoError(t, err)
+	product.Tags = nil
+	product.Categories[0] = "audio"
+	require.NoError(t, store.Products().Save(product))
+
+	quote, err := sut.QuoteOrder(order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.MustParseMoney("-10", "USD"), quote.DiscountTotal)
+	assert.Equal(t, domain.MustParseMoney("5489.97", "USD"), quote.Total)
+}
+
+func TestShopService_QuoteOrderWithBestDeal(t *testing.T) {
+	inventories := map[string]*domain.Product{
+		"p01": {
+			ID:        "p01",
+			SKU:       "120P90",
+			Name:      "Google Home",
+			UnitPrice: domain.MustParseMoney("49.99", "USD"),
+			Quantity:  5,
+		},
+	}
+	promotions := []domain.Promotion{
+		{
+			ID:         "p01-3-for-2",
+			Name:       "3 for 2",
+			StackGroup: "p01",
+			Condition:  promotioncondition.ProductQuantityDiscount{ProductID: "p01", RequiredQuantity: 3, DiscountedQuantity: 1},
+		},
+		{
+			ID:         "p01-10-percent",
+			Name:       "10% off 3 or more",
+			Priority:   1,
+			StackGroup: "p01",
+			Condition:  promotioncondition.ProductPercentageDiscount{ProductID: "p01", MinQuantity: 3, DiscountInPercent: 10},
+		},
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+		want []domain.AppliedPromotion
+	}{
+		{
+			name: "should apply the promotion of highest priority",
+			want: []domain.AppliedPromotion{
+				{PromotionID: "p01-10-percent", Name: "10% off 3 or more", Amount: domain.MustParseMoney("-15", "USD")},
+			},
+		},
+		{
+			name: "should apply the promotion that saves the most with best deal",
+			opts: []Option{WithBestDeal(DefaultBestDealCombinations)},
+			want: []domain.AppliedPromotion{
+				{PromotionID: "p01-3-for-2", Name: "3 for 2", Amount: domain.MustParseMoney("-49.99", "USD")},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sut := NewShopService(memory.NewStore(inventories, nil), promotions, test.opts...)
+			order, err := sut.CreateCart()
+			assert.NoError(t, err)
+			_, err = sut.AddItemToCart(order.ID, "p01", 3)
+			assert.NoError(t, err)
+
+			quote, err := sut.QuoteOrder(order.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, withoutLines(quote.Discounts))
+		})
+	}
+}
+
+func TestShopService_ConcurrentCarts(t *testing.T) {
+	inventories := map[string]*domain.Product{
+		"p01": {
+			ID:        "p01",
+			SKU:       "120P90",
+			Name:      "Google Home",
+			UnitPrice: domain.MustParseMoney("49.99", "USD"),
+			Quantity:  100,
+		},
+	}
+
+	sut := NewShopService(memory.NewStore(inventories, nil), nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			order, err := sut.CreateCart()
+			assert.NoError(t, err)
+			_, err = sut.AddItemToCart(order.ID, "p01", 2)
+			assert.NoError(t, err)
+
+			_, err = sut.ListProducts()
+			assert.NoError(t, err)
+
+			got, err := sut.GetOrder(order.ID)
+			assert.NoError(t, err)
+			assert.Len(t, got.Lines, 1)
+
+			_, err = sut.SetShippingAddress(order.ID, testShippingAddress)
+			assert.NoError(t, err)
+
+			pricing, err := sut.Checkout(order.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, domain.MustParseMoney("99.98", "USD"), pricing.Total)
+		}()
+	}
+	wg.Wait()
+
+	product, err := sut.GetProduct("p01")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, product.Quantity)
+}
+
+func TestShopService_ConcurrentEditsToSameCart(t *testing.T) {
+	inventories := map[string]*domain.Product{
+		"p01": {
+			ID:        "p01",
+			SKU:       "120P90",
+			Name:      "Google Home",
+			UnitPrice: domain.MustParseMoney("49.99", "USD"),
+			Quantity:  100,
+		},
+		"p02": {
+			ID:        "p02",
+			SKU:       "43N23P",
+			Name:      "Pen",
+			UnitPrice: domain.MustParseMoney("10", "USD"),
+			Quantity:  100,
+		},
+	}
+
+	sut := NewShopService(memory.NewStore(inventories, nil), nil)
+	order, err := sut.CreateCart()
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+
+			got, err := sut.AddItemToCart(order.ID, "p01", 1)
+			assert.NoError(t, err)
+			for _, line := range got.Lines {
+				_ = line.Quantity
+			}
+		}()
+		go func() {
+			defer wg.Done()
+
+			_, _ = sut.AddItemToCart(order.ID, "p02", 1)
+			_, _ = sut.RemoveItemFromCart(order.ID, "p02")
+		}()
+	}
+	wg.Wait()
+
+	got, err := sut.GetOrder(order.ID)
+	assert.NoError(t, err)
+	line, _ := findLineInOrder(got, "p01")
+	if assert.NotNil(t, line) {
+		assert.Equal(t, 100, line.Quantity)
+	}
+}
+
+func TestShopService_ConcurrentCheckoutDoesNotOversell(t *testing.T) {
+	inventories := map[string]*domain.Product{
+		"p01": {
+			ID:        "p01",
+			SKU:       "234234",
+			Name:      "Raspberry Pi B",
+			UnitPrice: domain.MustParseMoney("30", "USD"),
+			Quantity:  5,
+		},
+	}
+
+	sut := NewShopService(memory.NewStore(inventories, nil), nil)
+
+	var orderIDs []string
+	for i := 0; i < 20; i++ {
+		order, err := sut.CreateCart()
+		assert.NoError(t, err)
+		_, err = sut.AddItemToCart(order.ID, "p01", 1)
+		assert.NoError(t, err)
+		_, err = sut.SetShippingAddress(order.ID, testShippingAddress)
+		assert.NoError(t, err)
+		orderIDs = append(orderIDs, order.ID)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	for _, orderID := range orderIDs {
+		wg.Add(1)
+		go func(orderID string) {
+			defer wg.Done()
+
+			_, err := sut.Checkout(orderID)
+			if err != nil {
+				assert.ErrorIs(t, err, domain.ErrSomeProductInCartNotEnoughInStock)
+				return
+			}
+
+			mu.Lock()
+			succeeded++
+			mu.Unlock()
+		}(orderID)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 5, succeeded)
+
+	product, err := sut.GetProduct("p01")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, product.Quantity)
+}
+
+func TestShopService_OrderLifecycle(t *testing.T) {
+	inventories := map[string]*domain.Product{
+		"p01": {
+			ID:        "p01",
+			SKU:       "120P90",
+			Name:      "Google Home",
+			UnitPrice: domain.MustParseMoney("49.99", "USD"),
+			Quantity:  5,
+		},
+	}
+
+	store := memory.NewStore(inventories, nil)
+	sut := NewShopService(store, nil)
+
+	order, err := sut.CreateCart()
+	assert.NoError(t, err)
+	assert.Equal(t, domain.OrderStatusCreated, order.Status)
+
+	_, err = sut.AddItemToCart(order.ID, "p01", 2)
+	assert.NoError(t, err)
+	_, err = sut.SetShippingAddress(order.ID, testShippingAddress)
+	assert.NoError(t, err)
+
+	_, err = sut.Checkout(order.ID)
+	assert.NoError(t, err)
+
+	got, err := sut.GetOrder(order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.OrderStatusArrangingPayment, got.Status)
+
+	_, err = sut.AddItemToCart(order.ID, "p01", 1)
+	assert.ErrorIs(t, err, domain.ErrOrderNotModifiable)
+
+	_, err = sut.RemoveItemFromCart(order.ID, "p01")
+	assert.ErrorIs(t, err, domain.ErrOrderNotModifiable)
+
+	_, err = sut.Checkout(order.ID)
+	assert.ErrorIs(t, err, domain.ErrOrderAlreadyCheckedOut)
+
+	product, err := sut.GetProduct("p01")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, product.Quantity, "stock must only be taken once")
+
+	_, err = sut.ShipOrder(order.ID)
+	assert.ErrorIs(t, err, domain.ErrInvalidOrderStatusTransition)
+
+	got, err = sut.MarkOrderAsPaid(order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.OrderStatusPaid, got.Status)
+
+	got, err = sut.ShipOrder(order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.OrderStatusShipped, got.Status)
+
+	got, err = sut.DeliverOrder(order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.OrderStatusDelivered, got.Status)
+
+	_, err = sut.CancelOrder(order.ID)
+	assert.ErrorIs(t, err, domain.ErrInvalidOrderStatusTransition)
+
+	got, err = store.Orders().FindByID(order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.OrderStatusDelivered, got.Status)
+}
+
+func TestShopService_CancelOrder(t *testing.T) {
+	tests := []struct {
+		name         string
+		checkout     bool
+		ship         bool
+		wantQuantity int
+		wantErr      error
+	}{
+		{
+			name:         "should cancel cart without touching the stock",
+			wantQuantity: 5,
+		},
+		{
+			name:         "should put items back in stock when order was checked out",
+			checkout:     true,
+			wantQuantity: 5,
+		},
+		{
+			name:         "should return error when order was shipped",
+			checkout:     true,
+			ship:         true,
+			wantQuantity: 3,
+			wantErr:      domain.ErrInvalidOrderStatusTransition,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inventories := map[string]*domain.Product{
+				"p01": {
+					ID:        "p01",
+					SKU:       "120P90",
+					Name:      "Google Home",
+					UnitPrice: domain.MustParseMoney("49.99", "USD"),
+					Quantity:  5,
+				},
+			}
+
+			sut := NewShopService(memory.NewStore(inventories, nil), nil)
+			order, err := sut.CreateCart()
+			assert.NoError(t, err)
+			_, err = sut.AddItemToCart(order.ID, "p01", 2)
+			assert.NoError(t, err)
+
+			if test.checkout {
+				_, err = sut.SetShippingAddress(order.ID, testShippingAddress)
+				assert.NoError(t, err)
+				_, err = sut.Checkout(order.ID)
+				assert.NoError(t, err)
+			}
+
+			if test.ship {
+				_, err = sut.MarkOrderAsPaid(order.ID)
+				assert.NoError(t, err)
+				_, err = sut.ShipOrder(order.ID)
+				assert.NoError(t, err)
+			}
+
+			got, err := sut.CancelOrder(order.ID)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, domain.OrderStatusCancelled, got.Status)
+
+				_, err = sut.Checkout(order.ID)
+				assert.ErrorIs(t, err, domain.ErrOrderCancelled)
+			}
+
+			product, err := sut.GetProduct("p01")
+			assert.NoError(t, err)
+			assert.Equal(t, test.wantQuantity, product.Quantity)
+		})
+	}
+}
+
+func TestShopService_Reservations(t *testing.T) {
+	type step struct {
+		cart      string
+		add       int
+		remove    bool
+		checkout  bool
+		cancel    bool
+		advance   time.Duration
+		wantErr   error
+		wantStock domain.StockLevel
+	}
+
+	tests := []struct {
+		name  string
+		opts  []Option
+		steps []step
+	}{
+		{
+			name: "should not hold stock without reservation ttl",
+			steps: []step{
+				{cart: "a", add: 2, wantStock: domain.StockLevel{OnHand: 2, Available: 2}},
+				{cart: "b", add: 2, wantStock: domain.StockLevel{OnHand: 2, Available: 2}},
+				{cart: "a", checkout: true, wantStock: domain.StockLevel{OnHand: 0, Available: 0}},
+				{cart: "b", checkout: true, wantErr: domain.ErrSomeProductInCartNotEnoughInStock},
+			},
+		},
+		{
+			name: "should hold stock until the item is removed",
+			opts: []Option{WithReservationTTL(15 * time.Minute)},
+			steps: []step{
+				{cart: "a", add: 1, wantStock: domain.StockLevel{OnHand: 2, Reserved: 1, Available: 1}},
+				{cart: "a", add: 1, wantStock: domain.StockLevel{OnHand: 2, Reserved: 2, Available: 0}},
+				{cart: "b", add: 1, wantErr: domain.ErrNotEnoughStock},
+				{cart: "a", remove: true, wantStock: domain.StockLevel{OnHand: 2, Reserved: 0, Available: 2}},
+				{cart: "b", add: 1, wantStock: domain.StockLevel{OnHand: 2, Reserved: 1, Available: 1}},
+			},
+		},
+		{
+			name: "should release stock when the reservation expires",
+			opts: []Option{WithReservationTTL(15 * time.Minute)},
+			steps: []step{
+				{cart: "a", add: 2, wantStock: domain.StockLevel{OnHand: 2, Reserved: 2, Available: 0}},
+				{advance: 15 * time.Minute, wantStock: domain.StockLevel{OnHand: 2, Reserved: 0, Available: 2}},
+				{cart: "b", add: 2, wantStock: domain.StockLevel{OnHand: 2, Reserved: 2, Available: 0}},
+				{cart: "a", checkout: true, wantErr: domain.ErrSomeProductInCartNotEnoughInStock},
+				{cart: "b", checkout: true, wantStock: domain.StockLevel{OnHand: 0, Reserved: 0, Available: 0}},
+			},
+		},
+		{
+			name: "should release stock on checkout and cancel",
+			opts: []Option{WithReservationTTL(15 * time.Minute)},
+			steps: []step{
+				{cart: "a", add: 1, wantStock: domain.StockLevel{OnHand: 2, Reserved: 1, Available: 1}},
+				{cart: "b", add: 1, wantStock: domain.StockLevel{OnHand: 2, Reserved: 2, Available: 0}},
+				{cart: "a", checkout: true, wantStock: domain.StockLevel{OnHand: 1, Reserved: 1, Available: 0}},
+				{cart: "b", cancel: true, wantStock: domain.StockLevel{OnHand: 1, Reserved: 0, Available: 1}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inventories := map[string]*domain.Product{
+				"p01": {
+					ID:        "p01",
+					SKU:       "234234",
+					Name:      "Raspberry Pi B",
+					UnitPrice: domain.MustParseMoney("30", "USD"),
+					Quantity:  2,
+				},
+			}
+
+			now := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+			sut := NewShopService(memory.NewStore(inventories, nil), nil, test.opts...)
+			sut.now = func() time.Time { return now }
+
+			carts := map[string]string{}
+			for _, name := range []string{"a", "b"} {
+				order, err := sut.CreateCart()
+				require.NoError(t, err)
+				carts[name] = order.ID
+			}
+
+			for i, step := range test.steps {
+				var err error
+				switch {
+				case step.add > 0:
+					_, err = sut.AddItemToCart(carts[step.cart], "p01", step.add)
+				case step.remove:
+					_, err = sut.RemoveItemFromCart(carts[step.cart], "p01")
+				case step.checkout:
+					_, err = sut.SetShippingAddress(carts[step.cart], testShippingAddress)
+					require.NoError(t, err)
+					_, err = sut.Checkout(carts[step.cart])
+				case step.cancel:
+					_, err = sut.CancelOrder(carts[step.cart])
+				}
+				now = now.Add(step.advance)
+
+				if step.wantErr != nil {
+					assert.ErrorIs(t, err, step.wantErr, "step %d", i)
+					continue
+				}
+				require.NoError(t, err, "step %d", i)
+
+				step.wantStock.ProductID = "p01"
+				got, err := sut.GetStockLevel("p01")
+				assert.NoError(t, err)
+				assert.Equal(t, &step.wantStock, got, "step %d", i)
+			}
+		})
+	}
+}
+
+func TestShopService_ReleaseExpiredReservations(t *testing.T) {
+	inventories := map[string]*domain.Product{
+		"p01": {
+			ID:        "p01",
+			SKU:       "234234",
+			Name:      "Raspberry Pi B",
+			UnitPrice: domain.MustParseMoney("30", "USD"),
+			Quantity:  2,
+		},
+	}
+
+	store := memory.NewStore(inventories, nil)
+	now := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+	sut := NewShopService(store, nil, WithReservationTTL(15*time.Minute))
+	sut.now = func() time.Time { return now }
+
+	abandoned, err := sut.CreateCart()
+	require.NoError(t, err)
+	_, err = sut.AddItemToCart(abandoned.ID, "p01", 1)
+	require.NoError(t, err)
+
+	now = now.Add(10 * time.Minute)
+	active, err := sut.CreateCart()
+	require.NoError(t, err)
+	_, err = sut.AddItemToCart(active.ID, "p01", 1)
+	require.NoError(t, err)
+
+	now = now.Add(10 * time.Minute)
+	released, err := sut.ReleaseExpiredReservations()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, released)
+
+	reservations, err := store.Reservations().FindByOrder(abandoned.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, reservations)
+
+	reservations, err = store.Reservations().FindByOrder(active.ID)
+	assert.NoError(t, err)
+	assert.Len(t, reservations, 1)
+}
+
+func TestShopService_GetOrCreateActiveOrder(t *testing.T) {
+	sut := NewShopService(memory.NewStore(nil, nil), nil)
+	owner := domain.OrderOwner{SessionID: "s1"}
+
+	_, err := sut.GetActiveOrder(owner)
+	assert.ErrorIs(t, err, domain.ErrCartNotFound)
+
+	created, err := sut.GetOrCreateActiveOrder(owner)
+	assert.NoError(t, err)
+	assert.Equal(t, "s1", created.SessionID)
+	assert.Equal(t, domain.OrderStatusCreated, created.Status)
+
+	got, err := sut.GetOrCreateActiveOrder(owner)
+	assert.NoError(t, err)
+	assert.Equal(t, created.ID, got.ID)
+
+	got, err = sut.GetActiveOrder(owner)
+	assert.NoError(t, err)
+	assert.Equal(t, created.ID, got.ID)
+
+	_, err = sut.GetOrCreateActiveOrder(domain.OrderOwner{})
+	assert.ErrorIs(t, err, domain.ErrCartNotFound)
+}
+
+func TestShopService_RegisterCustomer(t *testing.T) {
+	sut := NewShopService(memory.NewStore(nil, nil), nil, WithPasswordCost(bcrypt.MinCost))
+
+	customer, err := sut.RegisterCustomer("Jane@Example.com", "correct horse")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, customer.ID)
+	assert.Equal(t, "jane@example.com", customer.EmailAddress)
+	assert.NotContains(t, customer.PasswordHash, "correct horse")
+
+	got, err := sut.GetCustomerByEmailAddress("JANE@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, customer, got)
+
+	_, err = sut.RegisterCustomer("jane@example.com", "correct horse")
+	assert.ErrorIs(t, err, domain.ErrCustomerAlreadyExists)
+
+	_, err = sut.RegisterCustomer("jane", "correct horse")
+	assert.ErrorIs(t, err, domain.ErrInvalidEmailAddress)
+
+	_, err = sut.RegisterCustomer("john@example.com", "short")
+	assert.ErrorIs(t, err, domain.ErrInvalidPassword)
+}
+
+func TestShopService_Authenticate(t *testing.T) {
+	store := memory.NewStore(nil, nil)
+	require.NoError(t, store.Customers().Save(&domain.Customer{ID: "c02", EmailAddress: "john@example.com"}))
+	sut := NewShopService(store, nil, WithPasswordCost(bcrypt.MinCost))
+
+	customer, err := sut.RegisterCustomer("jane@example.com", "correct horse")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		emailAddress string
+		password     string
+		want         *domain.Customer
+		wantErr      error
+	}{
+		{
+			name:         "should return the customer of the email address and password",
+			emailAddress: "Jane@Example.com",
+			password:     "correct horse",
+			want:         customer,
+		},
+		{
+			name:         "should reject a wrong password",
+			emailAddress: "jane@example.com",
+			password:     "battery staple",
+			wantErr:      domain.ErrInvalidCredentials,
+		},
+		{
+			name:         "should not tell an unknown customer apart from a wrong password",
+			emailAddress: "nobody@example.com",
+			password:     "correct horse",
+			wantErr:      domain.ErrInvalidCredentials,
+		},
+		{
+			name:         "should reject a customer without password",
+			emailAddress: "john@example.com",
+			password:     "",
+			wantErr:      domain.ErrInvalidCredentials,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := sut.Authenticate(test.emailAddress, test.password)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestShopService_MergeSessionCart(t *testing.T) {
+	type line struct {
+		productID string
+		quantity  int
+	}
+
+	tests := []struct {
+		name         string
+		opts         []Option
+		sessionLines []line
+		customerCart bool
+		customerLine []line
+		want         []line
+		wantErr      error
+	}{
+		{
+			name:    "should return error when there is no cart",
+			wantErr: domain.ErrCartNotFound,
+		},
+		{
+			name:         "should keep cart of the customer when session has none",
+			customerCart: true,
+			customerLine: []line{{"p01", 1}},
+			want:         []line{{"p01", 1}},
+		},
+		{
+			name:         "should give cart of the session to the customer",
+			sessionLines: []line{{"p01", 2}},
+			want:         []line{{"p01", 2}},
+		},
+		{
+			name:         "should merge lines keeping the largest quantity",
+			sessionLines: []line{{"p01", 1}, {"p02", 1}},
+			customerCart: true,
+			customerLine: []line{{"p01", 2}},
+			want:         []line{{"p01", 2}, {"p02", 1}},
+		},
+		{
+			name:         "should hold stock of the merged lines for the customer cart",
+			opts:         []Option{WithReservationTTL(15 * time.Minute)},
+			sessionLines: []line{{"p01", 3}},
+			customerCart: true,
+			customerLine: []line{{"p01", 2}, {"p02", 1}},
+			want:         []line{{"p01", 3}, {"p02", 1}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inventories := map[string]*domain.Product{
+				"p01": {
+					ID:        "p01",
+					SKU:       "120P90",
+					Name:      "Google Home",
+					UnitPrice: domain.MustParseMoney("49.99", "USD"),
+					Quantity:  5,
+				},
+				"p02": {
+					ID:        "p02",
+					SKU:       "43N23P",
+					Name:      "Pen",
+					UnitPrice: domain.MustParseMoney("10", "USD"),
+					Quantity:  1,
+				},
+			}
+
+			store := memory.NewStore(inventories, nil)
+			sut := NewShopService(store, nil, append(test.opts, WithPasswordCost(bcrypt.MinCost))...)
+
+			customer, err := sut.RegisterCustomer("jane@example.com", "correct horse")
+			require.NoError(t, err)
+			customerOwner := domain.OrderOwner{CustomerID: customer.ID}
+			sessionOwner := domain.OrderOwner{SessionID: "s1"}
+
+			// The customer filled their cart from another device.
+			if test.customerCart {
+				order, err := sut.GetOrCreateActiveOrder(customerOwner)
+				require.NoError(t, err)
+				for _, l := range test.customerLine {
+					_, err = sut.AddItemToCart(order.ID, l.productID, l.quantity)
+					require.NoError(t, err)
+				}
+			}
+
+			var sessionCart *domain.Order
+			if len(test.sessionLines) > 0 {
+				sessionCart, err = sut.GetOrCreateActiveOrder(sessionOwner)
+				require.NoError(t, err)
+				for _, l := range test.sessionLines {
+					_, err = sut.AddItemToCart(sessionCart.ID, l.productID, l.quantity)
+					require.NoError(t, err)
+				}
+			}
+
+			got, err := sut.MergeSessionCart("s1", customer.ID)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			var gotLines []line
+			for _, l := range got.Lines {
+				gotLines = append(gotLines, line{l.ProductID, l.Quantity})
+			}
+			assert.Equal(t, test.want, gotLines)
+			assert.Equal(t, customer.ID, got.CustomerID)
+
+			active, err := sut.GetActiveOrder(customerOwner)
+			assert.NoError(t, err)
+			assert.Equal(t, got, active)
+
+			_, err = sut.GetActiveOrder(sessionOwner)
+			assert.ErrorIs(t, err, domain.ErrCartNotFound)
+
+			if sessionCart != nil && sessionCart.ID != got.ID {
+				merged, err := sut.GetOrder(sessionCart.ID)
+				assert.NoError(t, err)
+				assert.Equal(t, domain.OrderStatusCancelled, merged.Status)
+
+				reservations, err := store.Reservations().FindByOrder(sessionCart.ID)
+				assert.NoError(t, err)
+				assert.Empty(t, reservations)
+			}
+
+			for _, l := range got.Lines {
+				stock, err := sut.GetStockLevel(l.ProductID)
+				assert.NoError(t, err)
+				if len(test.opts) > 0 {
+					assert.Equal(t, l.Quantity, stock.Reserved)
+				}
+			}
+		})
+	}
+}
+
+func TestShopService_SetShippingAddress(t *testing.T) {
+	tests := []struct {
+		name       string
+		address    domain.Address
+		checkout   bool
+		want       *domain.Address
+		wantErr    error
+		wantFields []domain.FieldError
+	}{
+		{
+			name: "should set trimmed address",
+			address: domain.Address{
+				FullName:   " Jane Doe ",
+				StreetLine: "Jl. Jend. Sudirman Kav. 52-53",
+				City:       "Jakarta Selatan",
+				Province:   "DKI Jakarta",
+				Country:    "ID",
+			},
+			want: &domain.Address{
+				FullName:   "Jane Doe",
+				StreetLine: "Jl. Jend. Sudirman Kav. 52-53",
+				City:       "Jakarta Selatan",
+				Province:   "DKI Jakarta",
+				Country:    "ID",
+			},
+		},
+		{
+			name:    "should report every missing required field",
+			address: domain.Address{City: "Jakarta Selatan", Province: " "},
+			wantErr: domain.ErrInvalidAddress,
+			wantFields: []domain.FieldError{
+				{Field: "streetLine", Message: "is required"},
+				{Field: "province", Message: "is required"},
+				{Field: "country", Message: "is required"},
+			},
+		},
+		{
+			name:     "should return error when order is checked out",
+			address:  testShippingAddress,
+			checkout: true,
+			wantErr:  domain.ErrOrderNotModifiable,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inventories := map[string]*domain.Product{
+				"p01": {
+					ID:        "p01",
+					SKU:       "120P90",
+					Name:      "Google Home",
+					UnitPrice: domain.MustParseMoney("49.99", "USD"),
+					Quantity:  5,
+				},
+			}
+
+			sut := NewShopService(memory.NewStore(inventories, nil), nil)
+			order, err := sut.CreateCart()
+			require.NoError(t, err)
+			_, err = sut.AddItemToCart(order.ID, "p01", 1)
+			require.NoError(t, err)
+
+			if test.checkout {
+				_, err = sut.SetShippingAddress(order.ID, testShippingAddress)
+				require.NoError(t, err)
+				_, err = sut.Checkout(order.ID)
+				require.NoError(t, err)
+			}
+
+			got, err := sut.SetShippingAddress(order.ID, test.address)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+
+				var validationErr *domain.ValidationError
+				if errors.As(err, &validationErr) {
+					assert.Equal(t, test.wantFields, validationErr.Fields)
+				} else {
+					assert.Nil(t, test.wantFields)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got.ShippingAddress)
+
+			stored, err := sut.GetOrder(order.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, stored.ShippingAddress)
+		})
+	}
+}
+
+func TestShopService_CheckoutRequiresShippingAddress(t *testing.T) {
+	tests := []struct {
+		name       string
+		productIDs []string
+		wantErr    error
+	}{
+		{
+			name:       "should require shipping address for physical products",
+			productIDs: []string{"ebook", "speaker"},
+			wantErr:    domain.ErrShippingAddressRequired,
+		},
+		{
+			name:       "should not require shipping address for digital products only",
+			productIDs: []string{"ebook"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inventories := map[string]*domain.Product{
+				"ebook": {
+					ID:        "ebook",
+					Name:      "Go Programming E-Book",
+					UnitPrice: domain.MustParseMoney("15", "USD"),
+					Quantity:  100,
+					Digital:   true,
+				},
+				"speaker": {
+					ID:        "speaker",
+					Name:      "Alexa Speaker",
+					UnitPrice: domain.MustParseMoney("109.50", "USD"),
+					Quantity:  10,
+				},
+			}
+
+			sut := NewShopService(memory.NewStore(inventories, nil), nil)
+			order, err := sut.CreateCart()
+			require.NoError(t, err)
+			for _, productID := range test.productIDs {
+				_, err = sut.AddItemToCart(order.ID, productID, 1)
+				require.NoError(t, err)
+			}
+
+			_, err = sut.Checkout(order.ID)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+
+				got, err := sut.GetOrder(order.ID)
+				assert.NoError(t, err)
+				assert.Equal(t, domain.OrderStatusCreated, got.Status)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestShopService_ShippingMethods(t *testing.T) {
+	inventories := map[string]*domain.Product{
+		"ebook": {
+			ID:        "ebook",
+			Name:      "Go Programming E-Book",
+			UnitPrice: domain.MustParseMoney("15", "USD"),
+			Quantity:  100,
+			Digital:   true,
+		},
+		"speaker": {
+			ID:        "speaker",
+			Name:      "Alexa Speaker",
+			UnitPrice: domain.MustParseMoney("109.50", "USD"),
+			Quantity:  10,
+			Weight:    1500,
+		},
+	}
+	methods := WithShippingMethods(
+		domain.ShippingMethod{
+			ID:   "standard",
+			Code: "standard",
+			Name: "Standard",
+			Calculator: shippingcalculator.PerRegion{
+				Regions: []shippingcalculator.Region{
+					{
+						Country: "ID",
+						Calculator: shippingcalculator.FreeOverThreshold{
+							Threshold: domain.MustParseMoney("200", "USD"),
+							Calculator: shippingcalculator.WeightBased{
+								BaseCost:  domain.MustParseMoney("3", "USD"),
+								CostPerKg: domain.MustParseMoney("1", "USD"),
+							},
+						},
+					},
+				},
+			},
+		},
+		domain.ShippingMethod{
+			ID:         "express",
+			Code:       "express",
+			Name:       "Express",
+			Calculator: shippingcalculator.FlatRate{Cost: domain.MustParseMoney("20", "USD")},
+		},
+	)
+
+	tests := []struct {
+		name       string
+		productIDs []string
+		address    *domain.Address
+		methodID   string
+		// wantQuotes are the eligible methods and their cost.
+		wantQuotes map[string]domain.Money
+		wantErr    error
+		wantTotal  domain.Money
+	}{
+		{
+			name:       "should quote every method serving the shipping address",
+			productIDs: []string{"speaker"},
+			address:    &testShippingAddress,
+			methodID:   "standard",
+			wantQuotes: map[string]domain.Money{
+				"standard": domain.MustParseMoney("5", "USD"),
+				"express":  domain.MustParseMoney("20", "USD"),
+			},
+			wantTotal: domain.MustParseMoney("114.50", "USD"),
+		},
+		{
+			name:       "should skip methods that can't ship without an address",
+			productIDs: []string{"speaker"},
+			methodID:   "standard",
+			wantQuotes: map[string]domain.Money{
+				"express": domain.MustParseMoney("20", "USD"),
+			},
+			wantErr: domain.ErrShippingMethodNotEligible,
+		},
+		{
+			name:       "should skip methods that don't serve the shipping address",
+			productIDs: []string{"speaker"},
+			address:    &domain.Address{StreetLine: "1 Raffles Place", City: "Singapore", Province: "Singapore", Country: "SG"},
+			methodID:   "express",
+			wantQuotes: map[string]domain.Money{
+				"express": domain.MustParseMoney("20", "USD"),
+			},
+			wantTotal: domain.MustParseMoney("129.50", "USD"),
+		},
+		{
+			name:       "should quote on the value of the order",
+			productIDs: []string{"speaker", "speaker"},
+			address:    &testShippingAddress,
+			methodID:   "standard",
+			wantQuotes: map[string]domain.Money{
+				"standard": domain.NewMoney(0, "USD"),
+				"express":  domain.MustParseMoney("20", "USD"),
+			},
+			wantTotal: domain.MustParseMoney("219", "USD"),
+		},
+		{
+			name:       "should return error when shipping method doesn't exist",
+			productIDs: []string{"speaker"},
+			address:    &testShippingAddress,
+			methodID:   "pigeon",
+			wantQuotes: map[string]domain.Money{
+				"standard": domain.MustParseMoney("5", "USD"),
+				"express":  domain.MustParseMoney("20", "USD"),
+			},
+			wantErr: domain.ErrShippingMethodNotFound,
+		},
+		{
+			name:       "should require shipping method for physical products",
+			productIDs: []string{"ebook", "speaker"},
+			address:    &testShippingAddress,
+			wantQuotes: map[string]domain.Money{
+				"standard": domain.MustParseMoney("5", "USD"),
+				"express":  domain.MustParseMoney("20", "USD"),
+			},
+			wantErr: domain.ErrShippingMethodRequired,
+		},
+		{
+			name:       "should not quote nor require shipping method for digital products only",
+			productIDs: []string{"ebook"},
+			address:    &testShippingAddress,
+			wantQuotes: map[string]domain.Money{},
+			wantTotal:  domain.MustParseMoney("15", "USD"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sut := NewShopService(memory.NewStore(inventories, nil), nil, methods)
+			order, err := sut.CreateCart()
+			require.NoError(t, err)
+			for _, productID := range test.productIDs {
+				_, err = sut.AddItemToCart(order.ID, productID, 1)
+				require.NoError(t, err)
+			}
+			if test.address != nil {
+				_, err = sut.SetShippingAddress(order.ID, *test.address)
+				require.NoError(t, err)
+			}
+
+			quotes, err := sut.GetEligibleShippingMethods(order.ID)
+			assert.NoError(t, err)
+			got := map[string]domain.Money{}
+			for _, quote := range quotes {
+				got[quote.Method.ID] = quote.Cost
+			}
+			assert.Equal(t, test.wantQuotes, got)
+
+			if test.methodID != "" {
+				_, err = sut.SetShippingMethod(order.ID, test.methodID)
+			}
+			var pricing *domain.Pricing
+			if err == nil {
+				pricing, err = sut.Checkout(order.ID)
+			}
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+
+				got, err := sut.GetOrder(order.ID)
+				assert.NoError(t, err)
+				assert.Equal(t, domain.OrderStatusCreated, got.Status)
+				assert.Empty(t, got.ShippingMethodID)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.methodID, pricing.ShippingMethodID)
+			assert.Equal(t, test.wantTotal, pricing.Total)
+		})
+	}
+}
+
+func TestShopService_AddPayment(t *testing.T) {
+	tests := []struct {
+		name      string
+		checkout  bool
+		method    string
+		tokens    []string
+		wantErr   error
+		wantState []domain.PaymentState
+		want      domain.OrderStatus
+	}{
+		{
+			name:      "should settle the payment and mark the order as paid",
+			checkout:  true,
+			method:    "card",
+			tokens:    []string{"tok_visa"},
+			wantState: []domain.PaymentState{domain.PaymentStateSettled},
+			want:      domain.OrderStatusPaid,
+		},
+		{
+			name:      "should keep declined payments and let the customer try again",
+			checkout:  true,
+			method:    "card",
+			tokens:    []string{paymentprovider.FakeTokenDecline, "tok_visa"},
+			wantState: []domain.PaymentState{domain.PaymentStateDeclined, domain.PaymentStateSettled},
+			want:      domain.OrderStatusPaid,
+		},
+		{
+			name:      "should return error when the payment is declined",
+			checkout:  true,
+			method:    "card",
+			tokens:    []string{paymentprovider.FakeTokenDecline},
+			wantErr:   domain.ErrPaymentDeclined,
+			wantState: []domain.PaymentState{domain.PaymentStateDeclined},
+			want:      domain.OrderStatusArrangingPayment,
+		},
+		{
+			name:      "should keep the payment pending when the payment provider times out",
+			checkout:  true,
+			method:    "card",
+			tokens:    []string{paymentprovider.FakeTokenTimeout},
+			wantErr:   domain.ErrPaymentPending,
+			wantState: []domain.PaymentState{domain.PaymentStatePending},
+			want:      domain.OrderStatusArrangingPayment,
+		},
+		{
+			name:      "should send the pending payment again instead of paying twice",
+			checkout:  true,
+			method:    "card",
+			tokens:    []string{paymentprovider.FakeTokenTimeout, "tok_visa"},
+			wantState: []domain.PaymentState{domain.PaymentStateSettled},
+			want:      domain.OrderStatusPaid,
+		},
+		{
+			name:      "should void the authorization when capture fails",
+			checkout:  true,
+			method:    "card",
+			tokens:    []string{paymentprovider.FakeTokenCaptureFailure},
+			wantErr:   domain.ErrPaymentFailed,
+			wantState: []domain.PaymentState{domain.PaymentStateCancelled},
+			want:      domain.OrderStatusArrangingPayment,
+		},
+		{
+			name:     "should return error when payment method doesn't exist",
+			checkout: true,
+			method:   "cash",
+			tokens:   []string{"tok_visa"},
+			wantErr:  domain.ErrPaymentMethodNotFound,
+			want:     domain.OrderStatusArrangingPayment,
+		},
+		{
+			name:    "should return error when order is not checked out",
+			method:  "card",
+			tokens:  []string{"tok_visa"},
+			wantErr: domain.ErrOrderNotAwaitingPayment,
+			want:    domain.OrderStatusCreated,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inventories := map[string]*domain.Product{
+				"p01": {
+					ID:        "p01",
+					Name:      "Google Home",
+					UnitPrice: domain.MustParseMoney("49.99", "USD"),
+					Quantity:  10,
+				},
+			}
+			provider := paymentprovider.NewFake()
+			sut := NewShopService(memory.NewStore(inventories, nil), nil, WithPaymentMethods(
+				domain.PaymentMethod{Code: "card", Name: "Credit Card", Provider: provider},
+			))
+
+			order, err := sut.CreateCart()
+			require.NoError(t, err)
+			_, err = sut.AddItemToCart(order.ID, "p01", 2)
+			require.NoError(t, err)
+			_, err = sut.SetShippingAddress(order.ID, testShippingAddress)
+			require.NoError(t, err)
+			if test.checkout {
+				_, err = sut.Checkout(order.ID)
+				require.NoError(t, err)
+			}
+
+			for _, token := range test.tokens {
+				_, err = sut.AddPayment(order.ID, test.method, token)
+			}
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			got, err := sut.GetOrder(order.ID)
+			require.NoError(t, err)
+			assert.Equal(t, test.want, got.Status)
+
+			var states []domain.PaymentState
+			for _, payment := range got.Payments {
+				states = append(states, payment.State)
+				assert.Equal(t, domain.MustParseMoney("99.98", "USD"), payment.Amount)
+			}
+			assert.Equal(t, test.wantState, states)
+
+			if test.want == domain.OrderStatusPaid {
+				settled := got.Payments[len(got.Payments)-1]
+				transaction, ok := provider.Transaction(settled.TransactionID)
+				assert.True(t, ok)
+				assert.Equal(t, domain.MustParseMoney("99.98", "USD"), transaction.Captured)
+			}
+		})
+	}
+}
+
+// authorizeHook is a payment provider calling hook before authorizing.
+type authorizeHook struct {
+	*paymentprovider.Fake
+	hook func(request domain.PaymentRequest)
+}
+
+func (provider authorizeHook) Authorize(request domain.PaymentRequest) (string, error) {
+	provider.hook(request)
+	return provider.Fake.Authorize(request)
+}
+
+func TestShopService_AddPaymentSavesPendingPayment(t *testing.T) {
+	inventories := map[string]*domain.Product{
+		"p01": {ID: "p01", Name: "Google Home", UnitPrice: domain.MustParseMoney("49.99", "USD"), Quantity: 10},
+	}
+	var (
+		sut     *ShopService
+		saved   *domain.Order
+		request domain.PaymentRequest
+	)
+	provider := authorizeHook{Fake: paymentprovider.NewFake(), hook: func(r domain.PaymentRequest) {
+		var err error
+		saved, err = sut.GetOrder(r.OrderID)
+		require.NoError(t, err)
+		request = r
+	}}
+	sut = NewShopService(memory.NewStore(inventories, nil), nil, WithPaymentMethods(
+		domain.PaymentMethod{Code: "card", Name: "Credit Card", Provider: provider},
+	))
+
+	order, err := sut.CreateCart()
+	require.NoError(t, err)
+	_, err = sut.AddItemToCart(order.ID, "p01", 1)
+	require.NoError(t, err)
+	_, err = sut.SetShippingAddress(order.ID, testShippingAddress)
+	require.NoError(t, err)
+	_, err = sut.Checkout(order.ID)
+	require.NoError(t, err)
+
+	got, err := sut.AddPayment(order.ID, "card", "tok_visa")
+	require.NoError(t, err)
+
+	require.Len(t, saved.Payments, 1, "should save the payment before sending it")
+	assert.Equal(t, domain.PaymentStatePending, saved.Payments[0].State)
+	assert.Equal(t, got.Payments[0].ID, saved.Payments[0].ID)
+	assert.Equal(t, got.Payments[0].ID, request.IdempotencyKey)
+}
+
+// flatDiscount takes Amount off the order, it is a condition type of
+// another package.
+type flatDiscount struct {
+	Amount domain.Money
+}
+
+func (cond flatDiscount) CalculateDiscount(*domain.Order) domain.Money {
+	return cond.Amount.Neg()
+}
+
+func (cond flatDiscount) ConditionType() string {
+	return "flat_discount"
+}
+
+func (cond flatDiscount) Parameters() map[string]interface{} {
+	return map[string]interface{}{"amount": cond.Amount.Decimal()}
+}
+
+func TestShopService_PromotionRegistry(t *testing.T) {
+	withFlatDiscount := promotioncondition.NewRegistry()
+	require.NoError(t, withFlatDiscount.Register(promotioncondition.ConditionType{
+		Name: "flat_discount",
+		New: func(params *promotioncondition.Parameters) domain.PromotionCondition {
+			return flatDiscount{Amount: params.Money("amount", "USD")}
+		},
+	}))
+
+	tests := []struct {
+		name     string
+		registry *promotioncondition.Registry
+		wantErr  error
+	}{
+		{
+			name:     "should refund with the promotions rebuilt by the registry",
+			registry: withFlatDiscount,
+		},
+		{
+			name:     "should return error when the condition type is not registered",
+			registry: promotioncondition.DefaultRegistry,
+			wantErr:  promotioncondition.ErrConditionNotSerializable,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inventories := map[string]*domain.Product{
+				"p01": {ID: "p01", Name: "Google Home", UnitPrice: domain.MustParseMoney("49.99", "USD"), Quantity: 10},
+			}
+			promotions := []domain.Promotion{
+				{ID: "10-off", Name: "10 USD off", Condition: flatDiscount{Amount: domain.MustParseMoney("10", "USD")}},
+			}
+			sut := NewShopService(memory.NewStore(inventories, nil), promotions,
+				WithPromotionRegistry(test.registry),
+				WithPaymentMethods(domain.PaymentMethod{Code: "card", Name: "Credit Card", Provider: paymentprovider.NewFake()}),
+			)
+
+			order, err := sut.CreateCart()
+			require.NoError(t, err)
+			_, err = sut.AddItemToCart(order.ID, "p01", 2)
+			require.NoError(t, err)
+			_, err = sut.SetShippingAddress(order.ID, testShippingAddress)
+			require.NoError(t, err)
+
+			_, err = sut.Checkout(order.ID)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+
+				got, err := sut.GetOrder(order.ID)
+				require.NoError(t, err)
+				assert.Equal(t, domain.OrderStatusCreated, got.Status)
+				return
+			}
+			require.NoError(t, err)
+			order, err = sut.AddPayment(order.ID, "card", "tok_visa")
+			require.NoError(t, err)
+			assert.Equal(t, domain.MustParseMoney("89.98", "USD"), order.Pricing.Total)
+
+			// The customer keeps a Google Home for 39.99 USD.
+			sut.promotions = nil
+			refund, err := sut.RefundOrderLines(order.ID, refundLines(order, map[string]int{"p01": 1}), "changed my mind")
+			require.NoError(t, err)
+			assert.Equal(t, domain.MustParseMoney("49.99", "USD"), refund.Amount)
+		})
+	}
+}
+
+// refundLines returns the refund lines of the quantities of products of
+// the order.
+func refundLines(order *domain.Order, quantities map[string]int) []domain.RefundLine {
+	var lines []domain.RefundLine
+	for _, line := range order.Lines {
+		if quantity, ok := quantities[line.ProductID]; ok {
+			lines = append(lines, domain.RefundLine{OrderLineID: line.ID, Quantity: quantity})
+		}
+	}
+
+	return lines
+}
+
+// failingStore is a store whose transactions fail with err.
+type failingStore struct {
+	domain.Store
+	err error
+}
+
+func (store failingStore) Transaction(func(tx domain.Store) error) error {
+	return store.err
+}
+
+func TestShopService_RefundOrderLines(t *testing.T) {
+	inventories := map[string]*domain.Product{
+		"macbookpro": {
+			ID:        "macbookpro",
+			Name:      "MacBook Pro",
+			UnitPrice: domain.MustParseMoney("5399.99", "USD"),
+			Quantity:  5,
+		},
+		"raspberrypi": {
+			ID:        "raspberrypi",
+			Name:      "Raspberry Pi B",
+			UnitPrice: domain.MustParseMoney("30", "USD"),
+			Quantity:  5,
+		},
+		"googlehome": {
+			ID:        "googlehome",
+			Name:      "Google Home",
+			UnitPrice: domain.MustParseMoney("49.99", "USD"),
+			Quantity:  5,
+		},
+	}
+	promotions := []domain.Promotion{
+		{
+			ID:   "macbookpro-free-raspberrypi",
+			Name: "Free Raspberry Pi with every MacBook Pro",
+			Condition: promotioncondition.BuyXProductGetFreeProductCondition{
+				XProductID:    "macbookpro",
+				FreeProductID: "raspberrypi",
+			},
+		},
+	}
+
+	// paidOrder returns a paid order of a MacBook Pro coming with a free
+	// Raspberry Pi and two Google Homes, for 5499.97 USD unless extra
+	// promotions discount it.
+	paidOrder := func(t *testing.T, extra ...domain.Promotion) (*ShopService, *paymentprovider.Fake, *domain.Order) {
+		provider := paymentprovider.NewFake()
+		running := append(append([]domain.Promotion(nil), promotions...), extra...)
+		sut := NewShopService(memory.NewStore(inventories, nil), running, WithPaymentMethods(
+			domain.PaymentMethod{Code: "card", Name: "Credit Card", Provider: provider},
+		))
+
+		order, err := sut.CreateCart()
+		require.NoError(t, err)
+		for productID, quantity := range map[string]int{"macbookpro": 1, "raspberrypi": 1, "googlehome": 2} {
+			_, err = sut.AddItemToCart(order.ID, productID, quantity)
+			require.NoError(t, err)
+		}
+		_, err = sut.SetShippingAddress(order.ID, testShippingAddress)
+		require.NoError(t, err)
+		pricing, err := sut.Checkout(order.ID)
+		require.NoError(t, err)
+		if len(extra) == 0 {
+			require.Equal(t, domain.MustParseMoney("5499.97", "USD"), pricing.Total)
+		}
+		order, err = sut.AddPayment(order.ID, "card", "tok_visa")
+		require.NoError(t, err)
+
+		return sut, provider, order
+	}
+
+	storeErr := errors.New("database is locked")
+
+	tests := []struct {
+		name string
+		// promotions run at checkout on top of the free Raspberry Pi.
+		promotions []domain.Promotion
+		// setup changes the service once the order is paid.
+		setup   func(sut *ShopService)
+		refunds []map[string]int
+		// cancel cancels the order once refunds are made.
+		cancel      bool
+		wantAmounts []domain.Money
+		wantErr     error
+		wantStatus  domain.OrderStatus
+		wantStates  []domain.RefundState
+		wantStock   map[string]int
+	}{
+		{
+			name:        "should claw back the discount the refunded product came with",
+			refunds:     []map[string]int{{"macbookpro": 1}},
+			wantAmounts: []domain.Money{domain.MustParseMoney("5369.99", "USD")},
+			wantStatus:  domain.OrderStatusPaid,
+			wantStates:  []domain.RefundState{domain.RefundStateSettled},
+			wantStock:   map[string]int{"macbookpro": 5, "raspberrypi": 4, "googlehome": 3},
+		},
+		{
+			name:        "should refund nothing for a free product",
+			refunds:     []map[string]int{{"raspberrypi": 1}},
+			wantAmounts: []domain.Money{domain.NewMoney(0, "USD")},
+			wantStatus:  domain.OrderStatusPaid,
+			wantStates:  []domain.RefundState{domain.RefundStateSettled},
+			wantStock:   map[string]int{"macbookpro": 4, "raspberrypi": 5, "googlehome": 3},
+		},
+		{
+			name:        "should refund part of a line",
+			refunds:     []map[string]int{{"googlehome": 1}},
+			wantAmounts: []domain.Money{domain.MustParseMoney("49.99", "USD")},
+			wantStatus:  domain.OrderStatusPaid,
+			wantStates:  []domain.RefundState{domain.RefundStateSettled},
+			wantStock:   map[string]int{"macbookpro": 4, "raspberrypi": 4, "googlehome": 4},
+		},
+		{
+			name:    "should refund what was paid once every line is refunded",
+			refunds: []map[string]int{{"macbookpro": 1}, {"raspberrypi": 1}, {"googlehome": 2}},
+			wantAmounts: []domain.Money{
+				domain.MustParseMoney("5369.99", "USD"),
+				domain.MustParseMoney("30", "USD"),
+				domain.MustParseMoney("99.98", "USD"),
+			},
+			wantStatus: domain.OrderStatusCancelled,
+			wantStates: []domain.RefundState{domain.RefundStateSettled, domain.RefundStateSettled, domain.RefundStateSettled},
+			wantStock:  map[string]int{"macbookpro": 5, "raspberrypi": 5, "googlehome": 5},
+		},
+		{
+			name:    "should refund what is left when a paid order is cancelled",
+			refunds: []map[string]int{{"googlehome": 1}},
+			cancel:  true,
+			wantAmounts: []domain.Money{
+				domain.MustParseMoney("49.99", "USD"),
+				domain.MustParseMoney("5449.98", "USD"),
+			},
+			wantStatus: domain.OrderStatusCancelled,
+			wantStates: []domain.RefundState{domain.RefundStateSettled, domain.RefundStateSettled},
+			wantStock:  map[string]int{"macbookpro": 5, "raspberrypi": 5, "googlehome": 5},
+		},
+		{
+			name:       "should return error when refunding more than what is left",
+			refunds:    []map[string]int{{"googlehome": 1}, {"googlehome": 2}},
+			wantErr:    domain.ErrInvalidRefundQuantity,
+			wantStatus: domain.OrderStatusPaid,
+			wantStates: []domain.RefundState{domain.RefundStateSettled},
+			wantStock:  map[string]int{"macbookpro": 4, "raspberrypi": 4, "googlehome": 4},
+		},
+		{
+			name:       "should return error when refunding nothing",
+			refunds:    []map[string]int{{"googlehome": 0}},
+			wantErr:    domain.ErrInvalidRefundQuantity,
+			wantStatus: domain.OrderStatusPaid,
+			wantStock:  map[string]int{"macbookpro": 4, "raspberrypi": 4, "googlehome": 3},
+		},
+		{
+			name: "should keep the discount of a promotion removed since checkout",
+			setup: func(sut *ShopService) {
+				sut.promotions = nil
+			},
+			// The Raspberry Pi the customer keeps is still free.
+			refunds:     []map[string]int{{"googlehome": 1}},
+			wantAmounts: []domain.Money{domain.MustParseMoney("49.99", "USD")},
+			wantStatus:  domain.OrderStatusPaid,
+			wantStates:  []domain.RefundState{domain.RefundStateSettled},
+			wantStock:   map[string]int{"macbookpro": 4, "raspberrypi": 4, "googlehome": 4},
+		},
+		{
+			name: "should keep the discount of a promotion changed since checkout",
+			setup: func(sut *ShopService) {
+				changed := promotions[0]
+				changed.Condition = promotioncondition.BuyXProductGetFreeProductCondition{
+					XProductID:    "alexaspeaker",
+					FreeProductID: "raspberrypi",
+				}
+				sut.promotions = []domain.Promotion{changed}
+			},
+			refunds:     []map[string]int{{"googlehome": 1}},
+			wantAmounts: []domain.Money{domain.MustParseMoney("49.99", "USD")},
+			wantStatus:  domain.OrderStatusPaid,
+			wantStates:  []domain.RefundState{domain.RefundStateSettled},
+			wantStock:   map[string]int{"macbookpro": 4, "raspberrypi": 4, "googlehome": 4},
+		},
+		{
+			name: "should keep the discount of a promotion that ended since checkout",
+			setup: func(sut *ShopService) {
+				ended := promotions[0]
+				ended.EndDate = time.Now()
+				sut.promotions = []domain.Promotion{ended}
+				sut.now = func() time.Time { return time.Now().Add(24 * time.Hour) }
+			},
+			refunds:     []map[string]int{{"googlehome": 1}},
+			wantAmounts: []domain.Money{domain.MustParseMoney("49.99", "USD")},
+			wantStatus:  domain.OrderStatusPaid,
+			wantStates:  []domain.RefundState{domain.RefundStateSettled},
+			wantStock:   map[string]int{"macbookpro": 4, "raspberrypi": 4, "googlehome": 4},
+		},
+		{
+			name: "should refund an order discounted by a promotion that can't be recorded",
+			promotions: []domain.Promotion{{
+				ID:   "googlehome-10-percent",
+				Name: "10% off Google Home",
+				// A minimum quantity of 0 can be set in code but not
+				// rebuilt from its parameters.
+				Condition: promotioncondition.ProductPercentageDiscount{
+					ProductID:         "googlehome",
+					MinQuantity:       0,
+					DiscountInPercent: 10,
+				},
+			}},
+			refunds:     []map[string]int{{"googlehome": 1}},
+			wantAmounts: []domain.Money{domain.MustParseMoney("44.99", "USD")},
+			wantStatus:  domain.OrderStatusPaid,
+			wantStates:  []domain.RefundState{domain.RefundStateSettled},
+			wantStock:   map[string]int{"macbookpro": 4, "raspberrypi": 4, "googlehome": 4},
+		},
+		{
+			name: "should not refund the payment when the refund can't be saved",
+			setup: func(sut *ShopService) {
+				sut.store = failingStore{Store: sut.store, err: storeErr}
+			},
+			refunds:    []map[string]int{{"googlehome": 1}},
+			wantErr:    storeErr,
+			wantStatus: domain.OrderStatusPaid,
+			wantStock:  map[string]int{"macbookpro": 4, "raspberrypi": 4, "googlehome": 3},
+		},
+		{
+			name: "should not refund the payment when the cancellation can't be saved",
+			setup: func(sut *ShopService) {
+				sut.store = failingStore{Store: sut.store, err: storeErr}
+			},
+			cancel:     true,
+			wantErr:    storeErr,
+			wantStatus: domain.OrderStatusPaid,
+			wantStock:  map[string]int{"macbookpro": 4, "raspberrypi": 4, "googlehome": 3},
+		},
+		{
+			name: "should keep the refund as failed when the payment provider fails",
+			setup: func(sut *ShopService) {
+				// A provider that doesn't know the transaction of the payment.
+				sut.paymentMethods = []domain.PaymentMethod{{Code: "card", Name: "Credit Card", Provider: paymentprovider.NewFake()}}
+			},
+			refunds:    []map[string]int{{"googlehome": 1}},
+			wantErr:    domain.ErrRefundFailed,
+			wantStatus: domain.OrderStatusPaid,
+			wantStates: []domain.RefundState{domain.RefundStateFailed},
+			wantStock:  map[string]int{"macbookpro": 4, "raspberrypi": 4, "googlehome": 4},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sut, provider, order := paidOrder(t, test.promotions...)
+			if test.setup != nil {
+				test.setup(sut)
+			}
+
+			var (
+				amounts []domain.Money
+				err     error
+			)
+			for _, quantities := range test.refunds {
+				var refund *domain.Refund
+				refund, err = sut.RefundOrderLines(order.ID, refundLines(order, quantities), "changed my mind")
+				if err != nil {
+					break
+				}
+				amounts = append(amounts, refund.Amount)
+			}
+			if test.cancel && err == nil {
+				var cancelled *domain.Order
+				if cancelled, err = sut.CancelOrder(order.ID); err == nil {
+					refund := cancelled.Refunds[len(cancelled.Refunds)-1]
+					assert.Equal(t, "order cancelled", refund.Reason)
+					amounts = append(amounts, refund.Amount)
+				}
+			}
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.wantAmounts, amounts)
+			}
+
+			got, err := sut.GetOrder(order.ID)
+			require.NoError(t, err)
+			assert.Equal(t, test.wantStatus, got.Status)
+
+			var (
+				states  []domain.RefundState
+				settled domain.Money
+			)
+			for _, refund := range got.Refunds {
+				states = append(states, refund.State)
+				if refund.State == domain.RefundStateSettled {
+					settled = settled.Add(refund.Amount)
+				}
+			}
+			assert.Equal(t, test.wantStates, states)
+
+			transaction, _ := provider.Transaction(got.Payments[0].TransactionID)
+			assert.Equal(t, settled.Amount, transaction.Refunded.Amount)
+
+			for productID, quantity := range test.wantStock {
+				product, err := sut.GetProduct(productID)
+				require.NoError(t, err)
+				assert.Equal(t, quantity, product.Quantity, productID)
+			}
+		})
+	}
+
+	t.Run("should return error when order line doesn't exist", func(t *testing.T) {
+		sut, _, order := paidOrder(t)
+
+		_, err := sut.RefundOrderLines(order.ID, []domain.RefundLine{{OrderLineID: "unknown", Quantity: 1}}, "")
+		assert.ErrorIs(t, err, domain.ErrItemNotFoundInCart)
+	})
+
+	t.Run("should return error when order is not paid", func(t *testing.T) {
+		sut, _, _ := paidOrder(t)
+		order, err := sut.CreateCart()
+		require.NoError(t, err)
+		order, err = sut.AddItemToCart(order.ID, "googlehome", 1)
+		require.NoError(t, err)
+
+		_, err = sut.RefundOrderLines(order.ID, refundLines(order, map[string]int{"googlehome": 1}), "")
+		assert.ErrorIs(t, err, domain.ErrOrderNotRefundable)
+	})
+}
+
+func TestShopService_Coupons(t *testing.T) {
+	inventories := map[string]*domain.Product{
+		"p01": {
+			ID:        "p01",
+			SKU:       "120P90",
+			Name:      "Google Home",
+			UnitPrice: domain.MustParseMoney("49.99", "USD"),
+			Quantity:  20,
+		},
+	}
+	tenPercent := promotioncondition.ProductPercentageDiscount{ProductID: "p01", MinQuantity: 1, DiscountInPercent: 10}
+	promotions := []domain.Promotion{
+		{ID: "save10", Name: "10% off", CouponCode: "save10", Condition: tenPercent},
+		{
+			ID:         "three-for-two",
+			Name:       "3 for 2",
+			CouponCode: "THREE",
+			Condition:  promotioncondition.ProductQuantityDiscount{ProductID: "p01", RequiredQuantity: 3, DiscountedQuantity: 1},
+		},
+		{ID: "expired", Name: "Expired", CouponCode: "OLD", EndDate: time.Date(2022, 2, 28, 0, 0, 0, 0, time.UTC), Condition: tenPercent},
+		{ID: "future", Name: "Future", CouponCode: "SOON", StartDate: time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC), Condition: tenPercent},
+		{ID: "once", Name: "Once", CouponCode: "ONCE", UsageLimit: 1, Condition: tenPercent},
+		{ID: "member", Name: "Members", CouponCode: "MEMBER", UsageLimitPerCustomer: 1, Condition: tenPercent},
+	}
+	now := func() time.Time { return time.Date(2022, 3, 15, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name          string
+		code          string
+		quantity      int
+		wantCodes     []string
+		wantDiscounts []string
+		wantErr       error
+	}{
+		{
+			name:          "should unlock the promotion of the code whatever its case",
+			code:          " Save10 ",
+			quantity:      1,
+			wantCodes:     []string{"SAVE10"},
+			wantDiscounts: []string{"save10"},
+		},
+		{
+			name:     "should reject unknown codes",
+			code:     "NOPE",
+			quantity: 1,
+			wantErr:  domain.ErrCouponCodeNotFound,
+		},
+		{
+			name:     "should reject codes of promotions that didn't start",
+			code:     "SOON",
+			quantity: 1,
+			wantErr:  domain.ErrCouponCodeNotFound,
+		},
+		{
+			name:     "should reject codes of promotions that ended",
+			code:     "OLD",
+			quantity: 1,
+			wantErr:  domain.ErrCouponCodeExpired,
+		},
+		{
+			name:     "should reject codes giving no discount on the cart",
+			code:     "THREE",
+			quantity: 2,
+			wantErr:  domain.ErrCouponCodeNotEligible,
+		},
+		{
+			name:     "should reject codes limited per customer on anonymous carts",
+			code:     "MEMBER",
+			quantity: 1,
+			wantErr:  domain.ErrCouponCodeNotEligible,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sut := NewShopService(memory.NewStore(inventories, nil), promotions)
+			sut.now = now
+			order, err := sut.CreateCart()
+			require.NoError(t, err)
+			_, err = sut.AddItemToCart(order.ID, "p01", test.quantity)
+			require.NoError(t, err)
+
+			got, err := sut.ApplyCoupon(order.ID, test.code)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.wantCodes, got.CouponCodes)
+
+			pricing, err := sut.QuoteOrder(order.ID)
+			require.NoError(t, err)
+			var discounts []string
+			for _, discount := range pricing.Discounts {
+				discounts = append(discounts, discount.PromotionID)
+			}
+			assert.Equal(t, test.wantDiscounts, discounts)
+		})
+	}
+
+	t.Run("should remove applied codes whatever their case", func(t *testing.T) {
+		sut := NewShopService(memory.NewStore(inventories, nil), promotions)
+		sut.now = now
+		order, err := sut.CreateCart()
+		require.NoError(t, err)
+		_, err = sut.AddItemToCart(order.ID, "p01", 3)
+		require.NoError(t, err)
+
+		_, err = sut.ApplyCoupon(order.ID, "SAVE10")
+		require.NoError(t, err)
+		_, err = sut.ApplyCoupon(order.ID, "three")
+		require.NoError(t, err)
+		order, err = sut.ApplyCoupon(order.ID, "three")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"SAVE10", "THREE"}, order.CouponCodes)
+
+		order, err = sut.RemoveCoupon(order.ID, "save10")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"THREE"}, order.CouponCodes)
+
+		pricing, err := sut.QuoteOrder(order.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.MustParseMoney("99.98", "USD"), pricing.Total)
+
+		_, err = sut.RemoveCoupon(order.ID, "SAVE10")
+		assert.ErrorIs(t, err, domain.ErrCouponCodeNotApplied)
+	})
+
+	t.Run("should enforce usage limits at checkout", func(t *testing.T) {
+		sut := NewShopService(memory.NewStore(inventories, nil), promotions, WithPasswordCost(bcrypt.MinCost))
+		sut.now = now
+		customer, err := sut.RegisterCustomer("jane@example.com", "correct horse")
+		require.NoError(t, err)
+
+		// checkout fills a cart of owner with the coupon code and checks it out.
+		checkout := func(owner domain.OrderOwner, code string) (*domain.Order, error) {
+			order, err := sut.createCart(owner)
+			require.NoError(t, err)
+			_, err = sut.AddItemToCart(order.ID, "p01", 1)
+			require.NoError(t, err)
+			_, err = sut.SetShippingAddress(order.ID, testShippingAddress)
+			require.NoError(t, err)
+
+			if _, err := sut.ApplyCoupon(order.ID, code); err != nil {
+				return order, err
+			}
+
+			_, err = sut.Checkout(order.ID)
+			return order, err
+		}
+
+		janeOrder, err := checkout(domain.OrderOwner{CustomerID: customer.ID}, "ONCE")
+		require.NoError(t, err)
+
+		_, err = checkout(domain.OrderOwner{SessionID: "s1"}, "ONCE")
+		assert.ErrorIs(t, err, domain.ErrCouponCodeUsageLimitReached)
+
+		// A cancelled order gives its use back. The limit is checked again at
+		// checkout, the code may have been used since it was applied.
+		_, err = sut.CancelOrder(janeOrder.ID)
+		require.NoError(t, err)
+
+		var carts []*domain.Order
+		for _, sessionID := range []string{"s2", "s3"} {
+			order, err := sut.createCart(domain.OrderOwner{SessionID: sessionID})
+			require.NoError(t, err)
+			_, err = sut.AddItemToCart(order.ID, "p01", 1)
+			require.NoError(t, err)
+			_, err = sut.SetShippingAddress(order.ID, testShippingAddress)
+			require.NoError(t, err)
+			_, err = sut.ApplyCoupon(order.ID, "ONCE")
+			require.NoError(t, err)
+			carts = append(carts, order)
+		}
+
+		_, err = sut.Checkout(carts[0].ID)
+		assert.NoError(t, err)
+		_, err = sut.Checkout(carts[1].ID)
+		assert.ErrorIs(t, err, domain.ErrCouponCodeUsageLimitReached)
+
+		_, err = checkout(domain.OrderOwner{CustomerID: customer.ID}, "MEMBER")
+		require.NoError(t, err)
+		_, err = checkout(domain.OrderOwner{CustomerID: customer.ID}, "MEMBER")
+		assert.ErrorIs(t, err, domain.ErrCouponCodeUsageLimitReached)
+	})
+}
@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+type CustomerRepository struct {
+	mu        sync.RWMutex
+	customers map[string]*domain.Customer
+}
+
+func NewCustomerRepository() *CustomerRepository {
+	return &CustomerRepository{
+		customers: make(map[string]*domain.Customer),
+	}
+}
+
+func (repo *CustomerRepository) FindByID(customerID string) (*domain.Customer, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	customer, ok := repo.customers[customerID]
+	if !ok {
+		return nil, domain.ErrCustomerNotFound
+	}
+
+	c := *customer
+	return &c, nil
+}
+
+func (repo *CustomerRepository) FindByEmailAddress(emailAddress string) (*domain.Customer, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	for _, customer := range repo.customers {
+		if customer.EmailAddress == emailAddress {
+			c := *customer
+			return &c, nil
+		}
+	}
+
+	return nil, domain.ErrCustomerNotFound
+}
+
+func (repo *CustomerRepository) Save(customer *domain.Customer) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, other := range repo.customers {
+		if other.ID != customer.ID && other.EmailAddress == customer.EmailAddress {
+			return domain.ErrCustomerAlreadyExists
+		}
+	}
+
+	c := *customer
+	repo.customers[c.ID] = &c
+
+	return nil
+}
+
+// snapshot returns a function restoring the customer with the given id
+// to its current state.
+func (repo *CustomerRepository) snapshot(customerID string) (restore func()) {
+	repo.mu.RLock()
+	var previous *domain.Customer
+	if customer, ok := repo.customers[customerID]; ok {
+		c := *customer
+		previous = &c
+	}
+	repo.mu.RUnlock()
+
+	return func() {
+		repo.mu.Lock()
+		defer repo.mu.Unlock()
+
+		if previous == nil {
+			delete(repo.customers, customerID)
+		} else {
+			repo.customers[customerID] = previous
+		}
+	}
+}
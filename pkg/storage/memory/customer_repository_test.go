@@ -0,0 +1,38 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+func TestCustomerRepository(t *testing.T) {
+	sut := NewCustomerRepository()
+
+	customer := &domain.Customer{ID: "c1", EmailAddress: "jane@example.com"}
+	assert.NoError(t, sut.Save(customer))
+
+	got, err := sut.FindByID("c1")
+	assert.NoError(t, err)
+	assert.Equal(t, customer, got)
+
+	got, err = sut.FindByEmailAddress("jane@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, customer, got)
+
+	_, err = sut.FindByID("c2")
+	assert.ErrorIs(t, err, domain.ErrCustomerNotFound)
+
+	_, err = sut.FindByEmailAddress("john@example.com")
+	assert.ErrorIs(t, err, domain.ErrCustomerNotFound)
+
+	err = sut.Save(&domain.Customer{ID: "c2", EmailAddress: "jane@example.com"})
+	assert.ErrorIs(t, err, domain.ErrCustomerAlreadyExists)
+
+	assert.NoError(t, sut.Save(&domain.Customer{ID: "c1", EmailAddress: "jane.doe@example.com"}))
+	got, err = sut.FindByID("c1")
+	assert.NoError(t, err)
+	assert.Equal(t, "jane.doe@example.com", got.EmailAddress)
+}
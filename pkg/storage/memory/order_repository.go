@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+type OrderRepository struct {
+	mu     sync.RWMutex
+	orders map[string]*domain.Order
+}
+
+// NewOrderRepository returns a repository holding a copy of orders,
+// orders may be nil.
+func NewOrderRepository(orders map[string]*domain.Order) *OrderRepository {
+	repo := &OrderRepository{
+		orders: make(map[string]*domain.Order, len(orders)),
+	}
+
+	for id, order := range orders {
+		repo.orders[id] = order.Clone()
+	}
+
+	return repo
+}
+
+func (repo *OrderRepository) FindByID(orderID string) (*domain.Order, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	order, ok := repo.orders[orderID]
+	if !ok {
+		return nil, domain.ErrCartNotFound
+	}
+
+	return order.Clone(), nil
+}
+
+func (repo *OrderRepository) FindActive(owner domain.OrderOwner) (*domain.Order, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	var active *domain.Order
+	for _, order := range repo.orders {
+		if !order.IsModifiable() || !order.IsOwnedBy(owner) {
+			continue
+		}
+
+		if active == nil || order.CreatedAt.After(active.CreatedAt) ||
+			(order.CreatedAt.Equal(active.CreatedAt) && order.ID > active.ID) {
+			active = order
+		}
+	}
+
+	if active == nil {
+		return nil, domain.ErrCartNotFound
+	}
+
+	return active.Clone(), nil
+}
+
+func (repo *OrderRepository) CountCouponCodeUses(code string, customerID string) (int, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	uses := 0
+	for _, order := range repo.orders {
+		if !order.IsPlaced() || !order.HasCouponCode(code) {
+			continue
+		}
+
+		if customerID == "" || order.CustomerID == customerID {
+			uses++
+		}
+	}
+
+	return uses, nil
+}
+
+func (repo *OrderRepository) Save(order *domain.Order) error {
+	clone := order.Clone()
+
+	repo.mu.Lock()
+	repo.orders[clone.ID] = clone
+	repo.mu.Unlock()
+
+	return nil
+}
+
+// snapshot returns a function restoring the order with the given id to
+// its current state.
+func (repo *OrderRepository) snapshot(orderID string) (restore func()) {
+	repo.mu.RLock()
+	var previous *domain.Order
+	if order, ok := repo.orders[orderID]; ok {
+		previous = order.Clone()
+	}
+	repo.mu.RUnlock()
+
+	return func() {
+		repo.mu.Lock()
+		defer repo.mu.Unlock()
+
+		if previous == nil {
+			delete(repo.orders, orderID)
+		} else {
+			repo.orders[orderID] = previous
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+func TestOrderRepository_FindByID(t *testing.T) {
+	tests := []struct {
+		name    string
+		orderID string
+		wantErr error
+	}{
+		{
+			name:    "should return copy of stored order",
+			orderID: "order1",
+		},
+		{
+			name:    "should return error when order is not stored",
+			orderID: "order2",
+			wantErr: domain.ErrCartNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			orders := map[string]*domain.Order{
+				"order1": {
+					ID: "order1",
+					Lines: []*domain.OrderLine{
+						{
+							ID:        "line1",
+							ProductID: "p01",
+							Quantity:  2,
+							UnitPrice: domain.MustParseMoney("10", "USD"),
+						},
+					},
+				},
+			}
+			sut := NewOrderRepository(orders)
+
+			got, err := sut.FindByID(test.orderID)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, orders[test.orderID], got)
+
+			got.Lines[0].Quantity = 10
+			again, err := sut.FindByID(test.orderID)
+			assert.NoError(t, err)
+			assert.Equal(t, 2, again.Lines[0].Quantity)
+		})
+	}
+}
+
+func TestOrderRepository_Save(t *testing.T) {
+	sut := NewOrderRepository(nil)
+
+	order := &domain.Order{ID: "order1"}
+	assert.NoError(t, sut.Save(order))
+
+	order.Lines = append(order.Lines, &domain.OrderLine{ID: "line1", ProductID: "p01", Quantity: 1})
+	got, err := sut.FindByID("order1")
+	assert.NoError(t, err)
+	assert.Empty(t, got.Lines)
+
+	assert.NoError(t, sut.Save(order))
+	got, err = sut.FindByID("order1")
+	assert.NoError(t, err)
+	assert.Equal(t, order, got)
+}
+
+func TestOrderRepository_FindActive(t *testing.T) {
+	createdAt := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	sut := NewOrderRepository(nil)
+	for _, order := range []*domain.Order{
+		{ID: "order1", Status: domain.OrderStatusCreated, SessionID: "s1", CreatedAt: createdAt},
+		{ID: "order2", Status: domain.OrderStatusCreated, SessionID: "s1", CreatedAt: createdAt.Add(time.Second)},
+		{ID: "order3", Status: domain.OrderStatusArrangingPayment, SessionID: "s1", CreatedAt: createdAt.Add(2 * time.Second)},
+		{ID: "order4", Status: domain.OrderStatusCreated, SessionID: "s2", CustomerID: "c1", CreatedAt: createdAt},
+		{ID: "order5", Status: domain.OrderStatusCancelled, CustomerID: "c1", CreatedAt: createdAt.Add(time.Second)},
+	} {
+		assert.NoError(t, sut.Save(order))
+	}
+
+	tests := []struct {
+		name    string
+		owner   domain.OrderOwner
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "should return latest cart of the session",
+			owner: domain.OrderOwner{SessionID: "s1"},
+			want:  "order2",
+		},
+		{
+			name:  "should return cart of the customer whatever the session",
+			owner: domain.OrderOwner{CustomerID: "c1", SessionID: "s1"},
+			want:  "order4",
+		},
+		{
+			name:    "should not return cart of a customer to the session",
+			owner:   domain.OrderOwner{SessionID: "s2"},
+			wantErr: domain.ErrCartNotFound,
+		},
+		{
+			name:    "should return error when customer has no cart",
+			owner:   domain.OrderOwner{CustomerID: "c2"},
+			wantErr: domain.ErrCartNotFound,
+		},
+		{
+			name:    "should return error when there is no owner",
+			wantErr: domain.ErrCartNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := sut.FindActive(test.owner)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got.ID)
+		})
+	}
+}
+
+func TestOrderRepository_CountCouponCodeUses(t *testing.T) {
+	sut := NewOrderRepository(nil)
+	for _, order := range []*domain.Order{
+		{ID: "order1", Status: domain.OrderStatusCreated, CustomerID: "c1", CouponCodes: []string{"SAVE10"}},
+		{ID: "order2", Status: domain.OrderStatusArrangingPayment, CustomerID: "c1", CouponCodes: []string{"SAVE10"}},
+		{ID: "order3", Status: domain.OrderStatusDelivered, CustomerID: "c2", CouponCodes: []string{"WELCOME", "SAVE10"}},
+		{ID: "order4", Status: domain.OrderStatusCancelled, CustomerID: "c1", CouponCodes: []string{"SAVE10"}},
+		{ID: "order5", Status: domain.OrderStatusPaid, SessionID: "s1", CouponCodes: []string{"SAVE10"}},
+	} {
+		assert.NoError(t, sut.Save(order))
+	}
+
+	tests := []struct {
+		name       string
+		code       string
+		customerID string
+		want       int
+	}{
+		{
+			name: "should count placed orders using the code",
+			code: "SAVE10",
+			want: 3,
+		},
+		{
+			name:       "should count placed orders of the customer using the code",
+			code:       "SAVE10",
+			customerID: "c1",
+			want:       1,
+		},
+		{
+			name: "should return zero for an unused code",
+			code: "UNUSED",
+			want: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := sut.CountCouponCodeUses(test.code, test.customerID)
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
@@ -0,0 +1,157 @@
+// Package memory implements the domain repositories in process memory,
+// everything stored is lost when the process exits.
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+type ProductRepository struct {
+	mu       sync.RWMutex
+	products map[string]*domain.Product
+}
+
+// NewProductRepository returns a repository holding a copy of products.
+func NewProductRepository(products map[string]*domain.Product) *ProductRepository {
+	repo := &ProductRepository{
+		products: make(map[string]*domain.Product, len(products)),
+	}
+
+	for id, product := range products {
+		repo.products[id] = stored(product)
+	}
+
+	return repo
+}
+
+func (repo *ProductRepository) FindAll() ([]*domain.Product, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	products := make([]*domain.Product, 0, len(repo.products))
+	for _, product := range repo.products {
+		products = append(products, product.Clone())
+	}
+
+	return products, nil
+}
+
+func (repo *ProductRepository) FindByID(productID string) (*domain.Product, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	product, ok := repo.products[productID]
+	if !ok {
+		return nil, domain.ErrProductNotFound
+	}
+
+	return product.Clone(), nil
+}
+
+func (repo *ProductRepository) FindVariants(productID string) ([]*domain.Product, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	var variants []*domain.Product
+	for _, product := range repo.products {
+		if product.ParentID == productID {
+			variants = append(variants, product.Clone())
+		}
+	}
+
+	sort.Slice(variants, func(i, j int) bool {
+		return variants[i].ID < variants[j].ID
+	})
+
+	return variants, nil
+}
+
+func (repo *ProductRepository) Save(product *domain.Product) error {
+	p := stored(product)
+
+	repo.mu.Lock()
+	repo.products[p.ID] = p
+	repo.mu.Unlock()
+
+	return nil
+}
+
+func (repo *ProductRepository) DecreaseStock(quantities map[string]int) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for productID, quantity := range quantities {
+		product, ok := repo.products[productID]
+		if !ok {
+			return fmt.Errorf("%w: %s", domain.ErrProductNotFound, productID)
+		}
+
+		if product.Quantity < quantity {
+			return fmt.Errorf("%w: %s", domain.ErrNotEnoughStock, productID)
+		}
+	}
+
+	for productID, quantity := range quantities {
+		repo.products[productID].Quantity -= quantity
+	}
+
+	return nil
+}
+
+func (repo *ProductRepository) IncreaseStock(quantities map[string]int) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for productID := range quantities {
+		if _, ok := repo.products[productID]; !ok {
+			return fmt.Errorf("%w: %s", domain.ErrProductNotFound, productID)
+		}
+	}
+
+	for productID, quantity := range quantities {
+		repo.products[productID].Quantity += quantity
+	}
+
+	return nil
+}
+
+// stored returns the copy of product to store, without its variants
+// which are stored on their own.
+func stored(product *domain.Product) *domain.Product {
+	p := product.Clone()
+	p.Variants = nil
+
+	return p
+}
+
+// snapshot returns a function restoring the products with the given ids
+// to their current state.
+func (repo *ProductRepository) snapshot(productIDs ...string) (restore func()) {
+	repo.mu.RLock()
+	previous := make(map[string]*domain.Product, len(productIDs))
+	for _, productID := range productIDs {
+		if product, ok := repo.products[productID]; ok {
+			previous[productID] = product.Clone()
+		} else {
+			previous[productID] = nil
+		}
+	}
+	repo.mu.RUnlock()
+
+	return func() {
+		repo.mu.Lock()
+		defer repo.mu.Unlock()
+
+		for productID, product := range previous {
+			if product == nil {
+				delete(repo.products, productID)
+			} else {
+				repo.products[productID] = product
+			}
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+func newTestProducts() map[string]*domain.Product {
+	return map[string]*domain.Product{
+		"p01": {
+			ID:        "p01",
+			SKU:       "120P90",
+			Name:      "Google Home",
+			UnitPrice: domain.MustParseMoney("49.99", "USD"),
+			Quantity:  5,
+		},
+		"p02": {
+			ID:        "p02",
+			SKU:       "43N23P",
+			Name:      "MacBook Pro",
+			UnitPrice: domain.MustParseMoney("5399.99", "USD"),
+			Quantity:  2,
+		},
+	}
+}
+
+func TestProductRepository_FindByID(t *testing.T) {
+	tests := []struct {
+		name      string
+		productID string
+		wantErr   error
+	}{
+		{
+			name:      "should return copy of stored product",
+			productID: "p01",
+		},
+		{
+			name:      "should return error when product is not stored",
+			productID: "p03",
+			wantErr:   domain.ErrProductNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			products := newTestProducts()
+			sut := NewProductRepository(products)
+
+			got, err := sut.FindByID(test.productID)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, products[test.productID], got)
+
+			got.Quantity = 0
+			again, err := sut.FindByID(test.productID)
+			assert.NoError(t, err)
+			assert.Equal(t, products[test.productID].Quantity, again.Quantity)
+		})
+	}
+}
+
+func TestProductRepository_FindAll(t *testing.T) {
+	products := newTestProducts()
+	sut := NewProductRepository(products)
+
+	got, err := sut.FindAll()
+	assert.NoError(t, err)
+	assert.Len(t, got, 2)
+	for _, g := range got {
+		assert.Equal(t, products[g.ID], g)
+	}
+}
+
+func TestProductRepository_Save(t *testing.T) {
+	sut := NewProductRepository(nil)
+
+	product := &domain.Product{ID: "p01", Name: "Google Home", Quantity: 1, Categories: []string{"speakers"}}
+	assert.NoError(t, sut.Save(product))
+
+	got, err := sut.FindByID("p01")
+	assert.NoError(t, err)
+	assert.Equal(t, product, got)
+
+	got.Categories[0] = "laptops"
+	got, err = sut.FindByID("p01")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"speakers"}, got.Categories)
+}
+
+func TestProductRepository_DecreaseStock(t *testing.T) {
+	tests := []struct {
+		name       string
+		quantities map[string]int
+		want       map[string]int
+		wantErr    error
+	}{
+		{
+			name:       "should decrease stock of every product",
+			quantities: map[string]int{"p01": 2, "p02": 2},
+			want:       map[string]int{"p01": 3, "p02": 0},
+		},
+		{
+			name:       "should not decrease any stock when one product is not enough in stock",
+			quantities: map[string]int{"p01": 2, "p02": 3},
+			want:       map[string]int{"p01": 5, "p02": 2},
+			wantErr:    domain.ErrNotEnoughStock,
+		},
+		{
+			name:       "should not decrease any stock when one product is not found",
+			quantities: map[string]int{"p01": 2, "p03": 1},
+			want:       map[string]int{"p01": 5, "p02": 2},
+			wantErr:    domain.ErrProductNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sut := NewProductRepository(newTestProducts())
+
+			err := sut.DecreaseStock(test.quantities)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			for productID, quantity := range test.want {
+				got, err := sut.FindByID(productID)
+				assert.NoError(t, err)
+				assert.Equal(t, quantity, got.Quantity)
+			}
+		})
+	}
+}
+
+func TestProductRepository_IncreaseStock(t *testing.T) {
+	tests := []struct {
+		name       string
+		quantities map[string]int
+		want       map[string]int
+		wantErr    error
+	}{
+		{
+			name:       "should increase stock of every product",
+			quantities: map[string]int{"p01": 2, "p02": 1},
+			want:       map[string]int{"p01": 7, "p02": 3},
+		},
+		{
+			name:       "should not increase any stock when one product is not found",
+			quantities: map[string]int{"p01": 2, "p03": 1},
+			want:       map[string]int{"p01": 5, "p02": 2},
+			wantErr:    domain.ErrProductNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sut := NewProductRepository(newTestProducts())
+
+			err := sut.IncreaseStock(test.quantities)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			for productID, quantity := range test.want {
+				got, err := sut.FindByID(productID)
+				assert.NoError(t, err)
+				assert.Equal(t, quantity, got.Quantity)
+			}
+		})
+	}
+}
+
+func TestProductRepository_FindVariants(t *testing.T) {
+	products := newTestProducts()
+	products["p02-32gb"] = &domain.Product{
+		ID:        "p02-32gb",
+		Name:      "MacBook Pro 32GB",
+		UnitPrice: domain.MustParseMoney("5999.99", "USD"),
+		ParentID:  "p02",
+		Options:   []domain.ProductOption{{Name: "Memory", Value: "32GB"}},
+	}
+	products["p02-16gb"] = &domain.Product{
+		ID:        "p02-16gb",
+		Name:      "MacBook Pro 16GB",
+		UnitPrice: domain.MustParseMoney("5399.99", "USD"),
+		ParentID:  "p02",
+		Options:   []domain.ProductOption{{Name: "Memory", Value: "16GB"}},
+	}
+	sut := NewProductRepository(products)
+
+	got, err := sut.FindVariants("p02")
+	assert.NoError(t, err)
+	assert.Equal(t, []*domain.Product{products["p02-16gb"], products["p02-32gb"]}, got)
+
+	got, err = sut.FindVariants("p01")
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+}
@@ -0,0 +1,143 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+type ReservationRepository struct {
+	mu sync.RWMutex
+	// reservations are indexed by order id then product id.
+	reservations map[string]map[string]domain.Reservation
+}
+
+func NewReservationRepository() *ReservationRepository {
+	return &ReservationRepository{
+		reservations: make(map[string]map[string]domain.Reservation),
+	}
+}
+
+func (repo *ReservationRepository) FindByOrder(orderID string) ([]*domain.Reservation, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	reservations := make([]*domain.Reservation, 0, len(repo.reservations[orderID]))
+	for _, reservation := range repo.reservations[orderID] {
+		r := reservation
+		reservations = append(reservations, &r)
+	}
+
+	sort.Slice(reservations, func(i, j int) bool {
+		return reservations[i].ProductID < reservations[j].ProductID
+	})
+
+	return reservations, nil
+}
+
+func (repo *ReservationRepository) ReservedQuantity(productID string, now time.Time) (int, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	reserved := 0
+	for _, byProduct := range repo.reservations {
+		reservation, ok := byProduct[productID]
+		if ok && reservation.IsActive(now) {
+			reserved += reservation.Quantity
+		}
+	}
+
+	return reserved, nil
+}
+
+func (repo *ReservationRepository) Save(reservation *domain.Reservation) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	byProduct, ok := repo.reservations[reservation.OrderID]
+	if !ok {
+		byProduct = make(map[string]domain.Reservation)
+		repo.reservations[reservation.OrderID] = byProduct
+	}
+	byProduct[reservation.ProductID] = *reservation
+
+	return nil
+}
+
+func (repo *ReservationRepository) Delete(orderID string, productID string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	delete(repo.reservations[orderID], productID)
+	if len(repo.reservations[orderID]) == 0 {
+		delete(repo.reservations, orderID)
+	}
+
+	return nil
+}
+
+func (repo *ReservationRepository) DeleteByOrder(orderID string) error {
+	repo.mu.Lock()
+	delete(repo.reservations, orderID)
+	repo.mu.Unlock()
+
+	return nil
+}
+
+func (repo *ReservationRepository) DeleteExpired(now time.Time) (int, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	deleted := 0
+	for orderID, byProduct := range repo.reservations {
+		for productID, reservation := range byProduct {
+			if !reservation.IsActive(now) {
+				delete(byProduct, productID)
+				deleted++
+			}
+		}
+
+		if len(byProduct) == 0 {
+			delete(repo.reservations, orderID)
+		}
+	}
+
+	return deleted, nil
+}
+
+// snapshot returns a function restoring the reservations of the orders
+// with the given ids, or of every order when no id is given, to their
+// current state.
+func (repo *ReservationRepository) snapshot(orderIDs ...string) (restore func()) {
+	repo.mu.RLock()
+	if len(orderIDs) == 0 {
+		for orderID := range repo.reservations {
+			orderIDs = append(orderIDs, orderID)
+		}
+	}
+
+	previous := make(map[string]map[string]domain.Reservation, len(orderIDs))
+	for _, orderID := range orderIDs {
+		byProduct := make(map[string]domain.Reservation, len(repo.reservations[orderID]))
+		for productID, reservation := range repo.reservations[orderID] {
+			byProduct[productID] = reservation
+		}
+		previous[orderID] = byProduct
+	}
+	repo.mu.RUnlock()
+
+	return func() {
+		repo.mu.Lock()
+		defer repo.mu.Unlock()
+
+		for orderID, byProduct := range previous {
+			if len(byProduct) == 0 {
+				delete(repo.reservations, orderID)
+			} else {
+				repo.reservations[orderID] = byProduct
+			}
+		}
+	}
+}
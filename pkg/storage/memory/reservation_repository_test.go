@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+func TestReservationRepository(t *testing.T) {
+	now := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	sut := NewReservationRepository()
+	for _, reservation := range []*domain.Reservation{
+		{OrderID: "order1", ProductID: "p01", Quantity: 2, ExpiresAt: now.Add(time.Minute)},
+		{OrderID: "order1", ProductID: "p02", Quantity: 1, ExpiresAt: now.Add(time.Minute)},
+		{OrderID: "order2", ProductID: "p01", Quantity: 3, ExpiresAt: now.Add(time.Minute)},
+		{OrderID: "order3", ProductID: "p01", Quantity: 4, ExpiresAt: now},
+	} {
+		assert.NoError(t, sut.Save(reservation))
+	}
+
+	reserved, err := sut.ReservedQuantity("p01", now)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, reserved, "expired reservations must not hold stock")
+
+	assert.NoError(t, sut.Save(&domain.Reservation{OrderID: "order2", ProductID: "p01", Quantity: 1, ExpiresAt: now.Add(time.Minute)}))
+	reserved, err = sut.ReservedQuantity("p01", now)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, reserved, "saving must replace the reservation of the order")
+
+	got, err := sut.FindByOrder("order1")
+	assert.NoError(t, err)
+	assert.Equal(t, []*domain.Reservation{
+		{OrderID: "order1", ProductID: "p01", Quantity: 2, ExpiresAt: now.Add(time.Minute)},
+		{OrderID: "order1", ProductID: "p02", Quantity: 1, ExpiresAt: now.Add(time.Minute)},
+	}, got)
+
+	assert.NoError(t, sut.Delete("order1", "p01"))
+	got, err = sut.FindByOrder("order1")
+	assert.NoError(t, err)
+	assert.Len(t, got, 1)
+
+	deleted, err := sut.DeleteExpired(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	got, err = sut.FindByOrder("order3")
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+
+	assert.NoError(t, sut.DeleteByOrder("order1"))
+	got, err = sut.FindByOrder("order1")
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+}
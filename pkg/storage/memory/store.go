@@ -0,0 +1,165 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+// Store implements domain.Store in process memory.
+type Store struct {
+	products     *ProductRepository
+	orders       *OrderRepository
+	reservations *ReservationRepository
+	customers    *CustomerRepository
+	txMutex      sync.Mutex
+}
+
+// NewStore returns a store holding a copy of products and orders, both may be nil.
+func NewStore(products map[string]*domain.Product, orders map[string]*domain.Order) *Store {
+	return &Store{
+		products:     NewProductRepository(products),
+		orders:       NewOrderRepository(orders),
+		reservations: NewReservationRepository(),
+		customers:    NewCustomerRepository(),
+	}
+}
+
+func (store *Store) Products() domain.ProductRepository {
+	return store.products
+}
+
+func (store *Store) Orders() domain.OrderRepository {
+	return store.orders
+}
+
+func (store *Store) Reservations() domain.ReservationRepository {
+	return store.reservations
+}
+
+func (store *Store) Customers() domain.CustomerRepository {
+	return store.customers
+}
+
+// Transaction runs transactions one at a time and undoes every write made
+// by fn when it fails. Writes made outside of a transaction are not
+// isolated from it.
+func (store *Store) Transaction(fn func(tx domain.Store) error) error {
+	store.txMutex.Lock()
+	defer store.txMutex.Unlock()
+
+	tx := &txStore{store: store}
+	if err := fn(tx); err != nil {
+		tx.rollback()
+		return err
+	}
+
+	return nil
+}
+
+type txStore struct {
+	store *Store
+	undo  []func()
+}
+
+func (tx *txStore) Products() domain.ProductRepository {
+	return txProductRepository{ProductRepository: tx.store.products, tx: tx}
+}
+
+func (tx *txStore) Orders() domain.OrderRepository {
+	return txOrderRepository{OrderRepository: tx.store.orders, tx: tx}
+}
+
+func (tx *txStore) Reservations() domain.ReservationRepository {
+	return txReservationRepository{ReservationRepository: tx.store.reservations, tx: tx}
+}
+
+func (tx *txStore) Customers() domain.CustomerRepository {
+	return txCustomerRepository{CustomerRepository: tx.store.customers, tx: tx}
+}
+
+// Transaction joins the running transaction.
+func (tx *txStore) Transaction(fn func(tx domain.Store) error) error {
+	return fn(tx)
+}
+
+func (tx *txStore) rollback() {
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		tx.undo[i]()
+	}
+}
+
+type txProductRepository struct {
+	*ProductRepository
+	tx *txStore
+}
+
+func (repo txProductRepository) Save(product *domain.Product) error {
+	repo.tx.undo = append(repo.tx.undo, repo.snapshot(product.ID))
+	return repo.ProductRepository.Save(product)
+}
+
+func (repo txProductRepository) DecreaseStock(quantities map[string]int) error {
+	repo.tx.undo = append(repo.tx.undo, repo.snapshot(productIDs(quantities)...))
+	return repo.ProductRepository.DecreaseStock(quantities)
+}
+
+func (repo txProductRepository) IncreaseStock(quantities map[string]int) error {
+	repo.tx.undo = append(repo.tx.undo, repo.snapshot(productIDs(quantities)...))
+	return repo.ProductRepository.IncreaseStock(quantities)
+}
+
+func productIDs(quantities map[string]int) []string {
+	ids := make([]string, 0, len(quantities))
+	for productID := range quantities {
+		ids = append(ids, productID)
+	}
+
+	return ids
+}
+
+type txOrderRepository struct {
+	*OrderRepository
+	tx *txStore
+}
+
+func (repo txOrderRepository) Save(order *domain.Order) error {
+	repo.tx.undo = append(repo.tx.undo, repo.snapshot(order.ID))
+	return repo.OrderRepository.Save(order)
+}
+
+type txReservationRepository struct {
+	*ReservationRepository
+	tx *txStore
+}
+
+func (repo txReservationRepository) Save(reservation *domain.Reservation) error {
+	repo.tx.undo = append(repo.tx.undo, repo.snapshot(reservation.OrderID))
+	return repo.ReservationRepository.Save(reservation)
+}
+
+func (repo txReservationRepository) Delete(orderID string, productID string) error {
+	repo.tx.undo = append(repo.tx.undo, repo.snapshot(orderID))
+	return repo.ReservationRepository.Delete(orderID, productID)
+}
+
+func (repo txReservationRepository) DeleteByOrder(orderID string) error {
+	repo.tx.undo = append(repo.tx.undo, repo.snapshot(orderID))
+	return repo.ReservationRepository.DeleteByOrder(orderID)
+}
+
+func (repo txReservationRepository) DeleteExpired(now time.Time) (int, error) {
+	repo.tx.undo = append(repo.tx.undo, repo.snapshot())
+	return repo.ReservationRepository.DeleteExpired(now)
+}
+
+type txCustomerRepository struct {
+	*CustomerRepository
+	tx *txStore
+}
+
+func (repo txCustomerRepository) Save(customer *domain.Customer) error {
+	repo.tx.undo = append(repo.tx.undo, repo.snapshot(customer.ID))
+	return repo.CustomerRepository.Save(customer)
+}
@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+func TestStore_Transaction(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	tests := []struct {
+		name         string
+		fnErr        error
+		wantQuantity int
+		wantOrder    bool
+	}{
+		{
+			name:         "should keep every write when fn succeeds",
+			wantQuantity: 3,
+			wantOrder:    true,
+		},
+		{
+			name:         "should undo every write when fn fails",
+			fnErr:        errBoom,
+			wantQuantity: 5,
+			wantOrder:    false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sut := NewStore(newTestProducts(), nil)
+
+			err := sut.Transaction(func(tx domain.Store) error {
+				if err := tx.Products().DecreaseStock(map[string]int{"p01": 2}); err != nil {
+					return err
+				}
+
+				if err := tx.Orders().Save(&domain.Order{ID: "order1"}); err != nil {
+					return err
+				}
+
+				if err := tx.Reservations().Save(&domain.Reservation{OrderID: "order1", ProductID: "p01", Quantity: 1}); err != nil {
+					return err
+				}
+
+				return test.fnErr
+			})
+			assert.ErrorIs(t, err, test.fnErr)
+
+			product, err := sut.Products().FindByID("p01")
+			assert.NoError(t, err)
+			assert.Equal(t, test.wantQuantity, product.Quantity)
+
+			_, err = sut.Orders().FindByID("order1")
+			if test.wantOrder {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, domain.ErrCartNotFound)
+			}
+
+			reservations, err := sut.Reservations().FindByOrder("order1")
+			assert.NoError(t, err)
+			if test.wantOrder {
+				assert.Len(t, reservations, 1)
+			} else {
+				assert.Empty(t, reservations)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+type CustomerRepository struct {
+	q queryer
+}
+
+func (repo *CustomerRepository) FindByID(customerID string) (*domain.Customer, error) {
+	return repo.findOne(`SELECT id, email_address, password_hash FROM customers WHERE id = ?`, customerID)
+}
+
+func (repo *CustomerRepository) FindByEmailAddress(emailAddress string) (*domain.Customer, error) {
+	return repo.findOne(`SELECT id, email_address, password_hash FROM customers WHERE email_address = ?`, emailAddress)
+}
+
+func (repo *CustomerRepository) findOne(query string, args ...interface{}) (*domain.Customer, error) {
+	var customer domain.Customer
+	err := repo.q.QueryRow(query, args...).Scan(&customer.ID, &customer.EmailAddress, &customer.PasswordHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrCustomerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &customer, nil
+}
+
+func (repo *CustomerRepository) Save(customer *domain.Customer) error {
+	_, err := repo.q.Exec(`INSERT INTO customers (id, email_address, password_hash) VALUES (?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			email_address = excluded.email_address,
+			password_hash = excluded.password_hash`,
+		customer.ID, customer.EmailAddress, customer.PasswordHash)
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed: customers.email_address") {
+		return domain.ErrCustomerAlreadyExists
+	}
+
+	return err
+}
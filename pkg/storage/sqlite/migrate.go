@@ -0,0 +1,79 @@
+package sqlite
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrate applies, in order, every migration in the migrations directory
+// that wasn't applied yet. Migration files are named <version>_<name>.sql
+// and each of them runs in its own transaction.
+func migrate(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TEXT NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("cannot create schema_migrations: %w", err)
+	}
+
+	names, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		version, err := migrationVersion(name)
+		if err != nil {
+			return err
+		}
+
+		err = runTx(db, func(tx *sql.Tx) error {
+			var applied int
+			err := tx.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE version = ?`, version).Scan(&applied)
+			if err != nil || applied > 0 {
+				return err
+			}
+
+			script, err := migrations.ReadFile(name)
+			if err != nil {
+				return err
+			}
+
+			if _, err := tx.Exec(string(script)); err != nil {
+				return err
+			}
+
+			_, err = tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`,
+				version, time.Now().UTC().Format(time.RFC3339))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("cannot apply migration %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func migrationVersion(name string) (int, error) {
+	base := strings.TrimPrefix(name, "migrations/")
+	prefix := strings.SplitN(base, "_", 2)[0]
+
+	version, err := strconv.Atoi(prefix)
+	if err != nil {
+		return 0, fmt.Errorf("invalid migration name %s: %w", name, err)
+	}
+
+	return version, nil
+}
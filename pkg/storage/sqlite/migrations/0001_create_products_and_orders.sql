@@ -0,0 +1,24 @@
+CREATE TABLE products (
+    id         TEXT PRIMARY KEY,
+    sku        TEXT NOT NULL DEFAULT '',
+    name       TEXT NOT NULL,
+    unit_price INTEGER NOT NULL,
+    currency   TEXT NOT NULL,
+    quantity   INTEGER NOT NULL CHECK (quantity >= 0)
+);
+
+CREATE TABLE orders (
+    id        TEXT PRIMARY KEY,
+    placed_at TEXT
+);
+
+CREATE TABLE order_lines (
+    order_id   TEXT NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    id         TEXT NOT NULL,
+    position   INTEGER NOT NULL,
+    product_id TEXT NOT NULL,
+    quantity   INTEGER NOT NULL,
+    unit_price INTEGER NOT NULL,
+    currency   TEXT NOT NULL,
+    PRIMARY KEY (order_id, id)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE orders ADD COLUMN status TEXT NOT NULL DEFAULT 'Created';
+
+UPDATE orders SET status = 'ArrangingPayment' WHERE placed_at IS NOT NULL;
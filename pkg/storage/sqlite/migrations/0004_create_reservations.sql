@@ -0,0 +1,9 @@
+CREATE TABLE reservations (
+    order_id   TEXT NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    product_id TEXT NOT NULL,
+    quantity   INTEGER NOT NULL,
+    expires_at INTEGER NOT NULL,
+    PRIMARY KEY (order_id, product_id)
+);
+
+CREATE INDEX reservations_product_id ON reservations (product_id, expires_at);
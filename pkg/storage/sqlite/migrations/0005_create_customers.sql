@@ -0,0 +1,11 @@
+CREATE TABLE customers (
+    id            TEXT PRIMARY KEY,
+    email_address TEXT NOT NULL UNIQUE
+);
+
+ALTER TABLE orders ADD COLUMN customer_id TEXT REFERENCES customers (id);
+ALTER TABLE orders ADD COLUMN session_id TEXT;
+ALTER TABLE orders ADD COLUMN created_at TEXT;
+
+CREATE INDEX orders_customer_id ON orders (customer_id, status);
+CREATE INDEX orders_session_id ON orders (session_id, status);
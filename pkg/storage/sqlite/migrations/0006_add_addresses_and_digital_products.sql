@@ -0,0 +1,4 @@
+ALTER TABLE orders ADD COLUMN shipping_address TEXT;
+ALTER TABLE orders ADD COLUMN billing_address TEXT;
+
+ALTER TABLE products ADD COLUMN digital INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+ALTER TABLE orders ADD COLUMN shipping_method_id TEXT;
+
+ALTER TABLE products ADD COLUMN weight INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN length INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN width INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN height INTEGER NOT NULL DEFAULT 0;
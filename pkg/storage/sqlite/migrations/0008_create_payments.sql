@@ -0,0 +1,13 @@
+CREATE TABLE payments (
+    order_id       TEXT NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    id             TEXT NOT NULL,
+    position       INTEGER NOT NULL,
+    method         TEXT NOT NULL,
+    amount         INTEGER NOT NULL,
+    currency       TEXT NOT NULL,
+    state          TEXT NOT NULL,
+    transaction_id TEXT,
+    error_message  TEXT,
+    created_at     TEXT,
+    PRIMARY KEY (order_id, id)
+);
@@ -0,0 +1,13 @@
+CREATE TABLE refunds (
+    order_id       TEXT NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    id             TEXT NOT NULL,
+    position       INTEGER NOT NULL,
+    lines          TEXT NOT NULL,
+    amount         INTEGER NOT NULL,
+    currency       TEXT NOT NULL,
+    reason         TEXT,
+    payment_id     TEXT,
+    transaction_id TEXT,
+    created_at     TEXT,
+    PRIMARY KEY (order_id, id)
+);
@@ -0,0 +1,8 @@
+CREATE TABLE order_coupon_codes (
+    order_id TEXT NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    code     TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (order_id, code)
+);
+
+CREATE INDEX order_coupon_codes_code ON order_coupon_codes (code);
@@ -0,0 +1,8 @@
+ALTER TABLE products ADD COLUMN categories TEXT;
+ALTER TABLE products ADD COLUMN tags TEXT;
+ALTER TABLE products ADD COLUMN collections TEXT;
+
+ALTER TABLE order_lines ADD COLUMN sku TEXT NOT NULL DEFAULT '';
+ALTER TABLE order_lines ADD COLUMN categories TEXT;
+ALTER TABLE order_lines ADD COLUMN tags TEXT;
+ALTER TABLE order_lines ADD COLUMN collections TEXT;
@@ -0,0 +1,6 @@
+ALTER TABLE products ADD COLUMN parent_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE products ADD COLUMN options TEXT;
+
+CREATE INDEX products_parent_id ON products (parent_id);
+
+ALTER TABLE order_lines ADD COLUMN parent_product_id TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE customers ADD COLUMN password_hash TEXT NOT NULL DEFAULT '';
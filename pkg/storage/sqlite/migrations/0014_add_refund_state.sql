@@ -0,0 +1,2 @@
+ALTER TABLE refunds ADD COLUMN state TEXT NOT NULL DEFAULT 'Settled';
+ALTER TABLE refunds ADD COLUMN error_message TEXT;
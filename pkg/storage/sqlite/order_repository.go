@@ -0,0 +1,414 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+type OrderRepository struct {
+	q queryer
+}
+
+const selectOrders = `SELECT id, status, customer_id, session_id, shipping_address, billing_address,
+	shipping_method_id, created_at, placed_at, pricing FROM orders`
+
+func (repo *OrderRepository) FindByID(orderID string) (*domain.Order, error) {
+	return repo.findOne(selectOrders+` WHERE id = ?`, orderID)
+}
+
+func (repo *OrderRepository) FindActive(owner domain.OrderOwner) (*domain.Order, error) {
+	if owner.CustomerID != "" {
+		return repo.findOne(selectOrders+` WHERE customer_id = ? AND status = ?
+			ORDER BY created_at DESC, id DESC LIMIT 1`, owner.CustomerID, domain.OrderStatusCreated)
+	}
+
+	if owner.SessionID == "" {
+		return nil, domain.ErrCartNotFound
+	}
+
+	return repo.findOne(selectOrders+` WHERE session_id = ? AND customer_id IS NULL AND status = ?
+		ORDER BY created_at DESC, id DESC LIMIT 1`, owner.SessionID, domain.OrderStatusCreated)
+}
+
+func (repo *OrderRepository) findOne(query string, args ...interface{}) (*domain.Order, error) {
+	var (
+		order           domain.Order
+		customerID      sql.NullString
+		sessionID       sql.NullString
+		shippingAddress sql.NullString
+		billingAddress  sql.NullString
+		shippingMethod  sql.NullString
+		createdAt       sql.NullString
+		placedAt        sql.NullString
+		pricing         sql.NullString
+	)
+
+	err := repo.q.QueryRow(query, args...).Scan(&order.ID, &order.Status, &customerID, &sessionID,
+		&shippingAddress, &billingAddress, &shippingMethod, &createdAt, &placedAt, &pricing)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrCartNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	order.CustomerID = customerID.String
+	order.SessionID = sessionID.String
+	order.ShippingMethodID = shippingMethod.String
+
+	if order.ShippingAddress, err = parseAddress(shippingAddress); err != nil {
+		return nil, err
+	}
+
+	if order.BillingAddress, err = parseAddress(billingAddress); err != nil {
+		return nil, err
+	}
+
+	if order.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+
+	if order.PlacedAt, err = parseTime(placedAt); err != nil {
+		return nil, err
+	}
+
+	if order.Pricing, err = parsePricing(pricing); err != nil {
+		return nil, err
+	}
+
+	rows, err := repo.q.Query(`SELECT id, product_id, quantity, unit_price, currency, parent_product_id,
+			sku, categories, tags, collections
+		FROM order_lines WHERE order_id = ? ORDER BY position`, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line domain.OrderLine
+		var categories, tags, collections sql.NullString
+		err := rows.Scan(&line.ID, &line.ProductID, &line.Quantity, &line.UnitPrice.Amount, &line.UnitPrice.Currency,
+			&line.ParentProductID, &line.SKU, &categories, &tags, &collections)
+		if err != nil {
+			return nil, err
+		}
+
+		if line.Categories, err = parseStrings(categories); err != nil {
+			return nil, err
+		}
+		if line.Tags, err = parseStrings(tags); err != nil {
+			return nil, err
+		}
+		if line.Collections, err = parseStrings(collections); err != nil {
+			return nil, err
+		}
+		order.Lines = append(order.Lines, &line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if order.CouponCodes, err = repo.findCouponCodes(order.ID); err != nil {
+		return nil, err
+	}
+
+	if order.Payments, err = repo.findPayments(order.ID); err != nil {
+		return nil, err
+	}
+
+	if order.Refunds, err = repo.findRefunds(order.ID); err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+func (repo *OrderRepository) findCouponCodes(orderID string) ([]string, error) {
+	rows, err := repo.q.Query(`SELECT code FROM order_coupon_codes WHERE order_id = ? ORDER BY position`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var codes []string
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, rows.Err()
+}
+
+func (repo *OrderRepository) CountCouponCodeUses(code string, customerID string) (int, error) {
+	query := `SELECT COUNT(*) FROM order_coupon_codes c JOIN orders o ON o.id = c.order_id
+		WHERE c.code = ? AND o.status NOT IN (?, ?)`
+	args := []interface{}{code, domain.OrderStatusCreated, domain.OrderStatusCancelled}
+	if customerID != "" {
+		query += ` AND o.customer_id = ?`
+		args = append(args, customerID)
+	}
+
+	var uses int
+	err := repo.q.QueryRow(query, args...).Scan(&uses)
+	return uses, err
+}
+
+func (repo *OrderRepository) findPayments(orderID string) ([]*domain.Payment, error) {
+	rows, err := repo.q.Query(`SELECT id, method, amount, currency, state, transaction_id, error_message, created_at
+		FROM payments WHERE order_id = ? ORDER BY position`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []*domain.Payment
+	for rows.Next() {
+		var (
+			payment       domain.Payment
+			transactionID sql.NullString
+			errorMessage  sql.NullString
+			createdAt     sql.NullString
+		)
+		err := rows.Scan(&payment.ID, &payment.Method, &payment.Amount.Amount, &payment.Amount.Currency, &payment.State,
+			&transactionID, &errorMessage, &createdAt)
+		if err != nil {
+			return nil, err
+		}
+
+		payment.TransactionID = transactionID.String
+		payment.ErrorMessage = errorMessage.String
+		if payment.CreatedAt, err = parseTime(createdAt); err != nil {
+			return nil, err
+		}
+
+		payments = append(payments, &payment)
+	}
+
+	return payments, rows.Err()
+}
+
+func (repo *OrderRepository) findRefunds(orderID string) ([]*domain.Refund, error) {
+	rows, err := repo.q.Query(`SELECT id, lines, amount, currency, reason, state, payment_id, transaction_id,
+			error_message, created_at
+		FROM refunds WHERE order_id = ? ORDER BY position`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refunds []*domain.Refund
+	for rows.Next() {
+		var (
+			refund        domain.Refund
+			lines         string
+			reason        sql.NullString
+			paymentID     sql.NullString
+			transactionID sql.NullString
+			errorMessage  sql.NullString
+			createdAt     sql.NullString
+		)
+		err := rows.Scan(&refund.ID, &lines, &refund.Amount.Amount, &refund.Amount.Currency, &reason, &refund.State,
+			&paymentID, &transactionID, &errorMessage, &createdAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(lines), &refund.Lines); err != nil {
+			return nil, err
+		}
+
+		refund.Reason = reason.String
+		refund.PaymentID = paymentID.String
+		refund.TransactionID = transactionID.String
+		refund.ErrorMessage = errorMessage.String
+		if refund.CreatedAt, err = parseTime(createdAt); err != nil {
+			return nil, err
+		}
+
+		refunds = append(refunds, &refund)
+	}
+
+	return refunds, rows.Err()
+}
+
+func (repo *OrderRepository) Save(order *domain.Order) error {
+	pricing, err := formatJSON(order.Pricing)
+	if err != nil {
+		return err
+	}
+
+	shippingAddress, err := formatJSON(order.ShippingAddress)
+	if err != nil {
+		return err
+	}
+
+	billingAddress, err := formatJSON(order.BillingAddress)
+	if err != nil {
+		return err
+	}
+
+	return withTx(repo.q, func(q queryer) error {
+		_, err := q.Exec(`INSERT INTO orders (id, status, customer_id, session_id, shipping_address, billing_address,
+				shipping_method_id, created_at, placed_at, pricing)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET
+				status = excluded.status,
+				customer_id = excluded.customer_id,
+				session_id = excluded.session_id,
+				shipping_address = excluded.shipping_address,
+				billing_address = excluded.billing_address,
+				shipping_method_id = excluded.shipping_method_id,
+				created_at = excluded.created_at,
+				placed_at = excluded.placed_at,
+				pricing = excluded.pricing`,
+			order.ID, order.Status, nullString(order.CustomerID), nullString(order.SessionID), shippingAddress, billingAddress,
+			nullString(order.ShippingMethodID), formatTime(order.CreatedAt), formatTime(order.PlacedAt), pricing)
+		if err != nil {
+			return err
+		}
+
+		if _, err := q.Exec(`DELETE FROM order_lines WHERE order_id = ?`, order.ID); err != nil {
+			return err
+		}
+
+		for position, line := range order.Lines {
+			classification, err := formatClassification(line.Categories, line.Tags, line.Collections)
+			if err != nil {
+				return err
+			}
+
+			_, err = q.Exec(`INSERT INTO order_lines (order_id, id, position, product_id, quantity, unit_price, currency,
+					parent_product_id, sku, categories, tags, collections)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				order.ID, line.ID, position, line.ProductID, line.Quantity, line.UnitPrice.Amount, line.UnitPrice.Currency,
+				line.ParentProductID, line.SKU, classification[0], classification[1], classification[2])
+			if err != nil {
+				return err
+			}
+		}
+
+		if _, err := q.Exec(`DELETE FROM order_coupon_codes WHERE order_id = ?`, order.ID); err != nil {
+			return err
+		}
+
+		for position, code := range order.CouponCodes {
+			_, err := q.Exec(`INSERT INTO order_coupon_codes (order_id, code, position) VALUES (?, ?, ?)`,
+				order.ID, code, position)
+			if err != nil {
+				return err
+			}
+		}
+
+		if _, err := q.Exec(`DELETE FROM payments WHERE order_id = ?`, order.ID); err != nil {
+			return err
+		}
+
+		for position, payment := range order.Payments {
+			_, err := q.Exec(`INSERT INTO payments (order_id, id, position, method, amount, currency, state,
+					transaction_id, error_message, created_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				order.ID, payment.ID, position, payment.Method, payment.Amount.Amount, payment.Amount.Currency, payment.State,
+				nullString(payment.TransactionID), nullString(payment.ErrorMessage), formatTime(payment.CreatedAt))
+			if err != nil {
+				return err
+			}
+		}
+
+		if _, err := q.Exec(`DELETE FROM refunds WHERE order_id = ?`, order.ID); err != nil {
+			return err
+		}
+
+		for position, refund := range order.Refunds {
+			lines, err := json.Marshal(refund.Lines)
+			if err != nil {
+				return err
+			}
+
+			_, err = q.Exec(`INSERT INTO refunds (order_id, id, position, lines, amount, currency, reason, state,
+					payment_id, transaction_id, error_message, created_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				order.ID, refund.ID, position, string(lines), refund.Amount.Amount, refund.Amount.Currency, nullString(refund.Reason),
+				refund.State, nullString(refund.PaymentID), nullString(refund.TransactionID), nullString(refund.ErrorMessage),
+				formatTime(refund.CreatedAt))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// timeLayout is RFC 3339 with a fixed number of fractional digits, so
+// stored times sort in chronological order.
+const timeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// formatTime stores zero time as NULL.
+func formatTime(t time.Time) sql.NullString {
+	if t.IsZero() {
+		return sql.NullString{}
+	}
+
+	return sql.NullString{String: t.UTC().Format(timeLayout), Valid: true}
+}
+
+// nullString stores an empty string as NULL.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func parseTime(s sql.NullString) (time.Time, error) {
+	if !s.Valid {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(time.RFC3339Nano, s.String)
+}
+
+// formatJSON stores v as JSON, a nil pointer as NULL.
+func formatJSON(v interface{}) (sql.NullString, error) {
+	if reflect.ValueOf(v).IsNil() {
+		return sql.NullString{}, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+func parsePricing(s sql.NullString) (*domain.Pricing, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+
+	var pricing domain.Pricing
+	if err := json.Unmarshal([]byte(s.String), &pricing); err != nil {
+		return nil, err
+	}
+
+	return &pricing, nil
+}
+
+func parseAddress(s sql.NullString) (*domain.Address, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+
+	var address domain.Address
+	if err := json.Unmarshal([]byte(s.String), &address); err != nil {
+		return nil, err
+	}
+
+	return &address, nil
+}
@@ -0,0 +1,318 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+func TestOrderRepository_FindByID(t *testing.T) {
+	sut := newTestStore(t).Orders()
+
+	_, err := sut.FindByID("order1")
+	assert.ErrorIs(t, err, domain.ErrCartNotFound)
+}
+
+func TestOrderRepository_Save(t *testing.T) {
+	tests := []struct {
+		name  string
+		order *domain.Order
+	}{
+		{
+			name:  "should save cart without lines",
+			order: &domain.Order{ID: "order1", Status: domain.OrderStatusCreated},
+		},
+		{
+			name: "should save lines in order",
+			order: &domain.Order{
+				ID:        "order1",
+				Status:    domain.OrderStatusArrangingPayment,
+				SessionID: "s1",
+				CreatedAt: time.Date(2022, 3, 1, 9, 0, 0, 500, time.UTC),
+				Lines: []*domain.OrderLine{
+					{
+						ID:              "line2",
+						ProductID:       "p02-32gb",
+						Quantity:        1,
+						UnitPrice:       domain.MustParseMoney("5999.99", "USD"),
+						ParentProductID: "p02",
+					},
+					{
+						ID:          "line1",
+						ProductID:   "p01",
+						Quantity:    3,
+						UnitPrice:   domain.MustParseMoney("49.99", "USD"),
+						SKU:         "120P90",
+						Categories:  []string{"smart-home"},
+						Tags:        []string{"sale", "bestseller"},
+						Collections: []string{"spring"},
+					},
+				},
+				PlacedAt: time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "should save pricing",
+			order: &domain.Order{
+				ID:     "order1",
+				Status: domain.OrderStatusArrangingPayment,
+				Lines: []*domain.OrderLine{
+					{
+						ID:        "line1",
+						ProductID: "p01",
+						Quantity:  3,
+						UnitPrice: domain.MustParseMoney("49.99", "USD"),
+					},
+				},
+				ShippingAddress: &domain.Address{
+					FullName:   "Jane Doe",
+					StreetLine: "Jl. Sudirman 1",
+					City:       "Jakarta",
+					Province:   "DKI Jakarta",
+					Country:    "ID",
+				},
+				BillingAddress: &domain.Address{
+					StreetLine: "Jl. Thamrin 2",
+					City:       "Jakarta",
+					Province:   "DKI Jakarta",
+					Country:    "ID",
+				},
+				ShippingMethodID: "standard",
+				PlacedAt:         time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC),
+				Pricing: &domain.Pricing{
+					Lines: []domain.LinePricing{
+						{
+							OrderLineID: "line1",
+							ProductID:   "p01",
+							Quantity:    3,
+							UnitPrice:   domain.MustParseMoney("49.99", "USD"),
+							Subtotal:    domain.MustParseMoney("149.97", "USD"),
+						},
+					},
+					Subtotal: domain.MustParseMoney("149.97", "USD"),
+					Discounts: []domain.AppliedPromotion{
+						{
+							PromotionID: "promo1",
+							Name:        "3 for 2",
+							Amount:      domain.MustParseMoney("-49.99", "USD"),
+							Promotion: &domain.PromotionSnapshot{
+								ConditionType: "product_quantity_discount",
+								Parameters: map[string]interface{}{
+									"productId":          "p01",
+									"requiredQuantity":   float64(3),
+									"discountedQuantity": float64(1),
+								},
+								Priority:   10,
+								StackGroup: "google-home",
+							},
+						},
+					},
+					DiscountTotal:    domain.MustParseMoney("-49.99", "USD"),
+					ShippingMethodID: "standard",
+					Shipping:         domain.MustParseMoney("5", "USD"),
+					Total:            domain.MustParseMoney("104.98", "USD"),
+				},
+			},
+		},
+		{
+			name: "should save coupon codes in order",
+			order: &domain.Order{
+				ID:          "order1",
+				Status:      domain.OrderStatusCreated,
+				CouponCodes: []string{"WELCOME", "SAVE10"},
+			},
+		},
+		{
+			name: "should save payments in order",
+			order: &domain.Order{
+				ID:     "order1",
+				Status: domain.OrderStatusPaid,
+				Payments: []*domain.Payment{
+					{
+						ID:           "pay2",
+						Method:       "card",
+						Amount:       domain.MustParseMoney("149.97", "USD"),
+						State:        domain.PaymentStateDeclined,
+						ErrorMessage: "payment declined: insufficient funds",
+						CreatedAt:    time.Date(2022, 3, 1, 10, 1, 0, 0, time.UTC),
+					},
+					{
+						ID:            "pay1",
+						Method:        "card",
+						Amount:        domain.MustParseMoney("149.97", "USD"),
+						State:         domain.PaymentStateSettled,
+						TransactionID: "tx1",
+						CreatedAt:     time.Date(2022, 3, 1, 10, 2, 0, 0, time.UTC),
+					},
+				},
+			},
+		},
+		{
+			name: "should save refunds in order",
+			order: &domain.Order{
+				ID:     "order1",
+				Status: domain.OrderStatusCancelled,
+				Refunds: []*domain.Refund{
+					{
+						ID:            "refund2",
+						Lines:         []domain.RefundLine{{OrderLineID: "line2", Quantity: 1}},
+						Amount:        domain.MustParseMoney("5369.99", "USD"),
+						Reason:        "damaged",
+						State:         domain.RefundStateSettled,
+						PaymentID:     "pay1",
+						TransactionID: "re1",
+						CreatedAt:     time.Date(2022, 3, 2, 10, 0, 0, 0, time.UTC),
+					},
+					{
+						ID:           "refund1",
+						Lines:        []domain.RefundLine{{OrderLineID: "line1", Quantity: 1}, {OrderLineID: "line3", Quantity: 2}},
+						Amount:       domain.MustParseMoney("30", "USD"),
+						State:        domain.RefundStateFailed,
+						PaymentID:    "pay1",
+						ErrorMessage: "gateway timeout",
+						CreatedAt:    time.Date(2022, 3, 3, 10, 0, 0, 0, time.UTC),
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sut := newTestStore(t).Orders()
+
+			// Save a different version first to make sure it gets replaced.
+			assert.NoError(t, sut.Save(&domain.Order{
+				ID:          "order1",
+				CouponCodes: []string{"OLD"},
+				Lines: []*domain.OrderLine{
+					{ID: "old", ProductID: "p01", Quantity: 1, UnitPrice: domain.MustParseMoney("49.99", "USD")},
+				},
+				Payments: []*domain.Payment{
+					{ID: "old", Method: "card", Amount: domain.MustParseMoney("49.99", "USD"), State: domain.PaymentStateError},
+				},
+			}))
+			assert.NoError(t, sut.Save(test.order))
+
+			got, err := sut.FindByID("order1")
+			assert.NoError(t, err)
+			assert.Equal(t, test.order, got)
+		})
+	}
+}
+
+func TestOrderRepository_FindActive(t *testing.T) {
+	createdAt := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	store := newTestStore(t)
+	for _, customerID := range []string{"c1", "c2"} {
+		assert.NoError(t, store.Customers().Save(&domain.Customer{ID: customerID, EmailAddress: customerID + "@example.com"}))
+	}
+	sut := store.Orders()
+	for _, order := range []*domain.Order{
+		{ID: "order1", Status: domain.OrderStatusCreated, SessionID: "s1", CreatedAt: createdAt},
+		{ID: "order2", Status: domain.OrderStatusCreated, SessionID: "s1", CreatedAt: createdAt.Add(time.Second)},
+		{ID: "order3", Status: domain.OrderStatusArrangingPayment, SessionID: "s1", CreatedAt: createdAt.Add(2 * time.Second)},
+		{ID: "order4", Status: domain.OrderStatusCreated, SessionID: "s2", CustomerID: "c1", CreatedAt: createdAt},
+		{ID: "order5", Status: domain.OrderStatusCancelled, CustomerID: "c1", CreatedAt: createdAt.Add(time.Second)},
+	} {
+		assert.NoError(t, sut.Save(order))
+	}
+
+	tests := []struct {
+		name    string
+		owner   domain.OrderOwner
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "should return latest cart of the session",
+			owner: domain.OrderOwner{SessionID: "s1"},
+			want:  "order2",
+		},
+		{
+			name:  "should return cart of the customer whatever the session",
+			owner: domain.OrderOwner{CustomerID: "c1", SessionID: "s1"},
+			want:  "order4",
+		},
+		{
+			name:    "should not return cart of a customer to the session",
+			owner:   domain.OrderOwner{SessionID: "s2"},
+			wantErr: domain.ErrCartNotFound,
+		},
+		{
+			name:    "should return error when customer has no cart",
+			owner:   domain.OrderOwner{CustomerID: "c2"},
+			wantErr: domain.ErrCartNotFound,
+		},
+		{
+			name:    "should return error when there is no owner",
+			wantErr: domain.ErrCartNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := sut.FindActive(test.owner)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got.ID)
+		})
+	}
+}
+
+func TestOrderRepository_CountCouponCodeUses(t *testing.T) {
+	store := newTestStore(t)
+	for _, customerID := range []string{"c1", "c2"} {
+		assert.NoError(t, store.Customers().Save(&domain.Customer{ID: customerID, EmailAddress: customerID + "@example.com"}))
+	}
+	sut := store.Orders()
+	for _, order := range []*domain.Order{
+		{ID: "order1", Status: domain.OrderStatusCreated, CustomerID: "c1", CouponCodes: []string{"SAVE10"}},
+		{ID: "order2", Status: domain.OrderStatusArrangingPayment, CustomerID: "c1", CouponCodes: []string{"SAVE10"}},
+		{ID: "order3", Status: domain.OrderStatusDelivered, CustomerID: "c2", CouponCodes: []string{"WELCOME", "SAVE10"}},
+		{ID: "order4", Status: domain.OrderStatusCancelled, CustomerID: "c1", CouponCodes: []string{"SAVE10"}},
+		{ID: "order5", Status: domain.OrderStatusPaid, SessionID: "s1", CouponCodes: []string{"SAVE10"}},
+	} {
+		assert.NoError(t, sut.Save(order))
+	}
+
+	tests := []struct {
+		name       string
+		code       string
+		customerID string
+		want       int
+	}{
+		{
+			name: "should count placed orders using the code",
+			code: "SAVE10",
+			want: 3,
+		},
+		{
+			name:       "should count placed orders of the customer using the code",
+			code:       "SAVE10",
+			customerID: "c1",
+			want:       1,
+		},
+		{
+			name: "should return zero for an unused code",
+			code: "UNUSED",
+			want: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := sut.CountCouponCodeUses(test.code, test.customerID)
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+type ProductRepository struct {
+	q queryer
+}
+
+const productColumns = `id, sku, name, unit_price, currency, quantity, digital, weight, length, width, height,
+	categories, tags, collections, parent_id, options`
+
+func (repo *ProductRepository) FindAll() ([]*domain.Product, error) {
+	return repo.findProducts(`SELECT ` + productColumns + ` FROM products ORDER BY id`)
+}
+
+func (repo *ProductRepository) FindVariants(productID string) ([]*domain.Product, error) {
+	return repo.findProducts(`SELECT `+productColumns+` FROM products WHERE parent_id = ? ORDER BY id`, productID)
+}
+
+func (repo *ProductRepository) findProducts(query string, args ...interface{}) ([]*domain.Product, error) {
+	rows, err := repo.q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []*domain.Product
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+
+	return products, rows.Err()
+}
+
+func (repo *ProductRepository) FindByID(productID string) (*domain.Product, error) {
+	row := repo.q.QueryRow(`SELECT `+productColumns+` FROM products WHERE id = ?`, productID)
+
+	product, err := scanProduct(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrProductNotFound
+	}
+
+	return product, err
+}
+
+func (repo *ProductRepository) Save(product *domain.Product) error {
+	classification, err := formatClassification(product.Categories, product.Tags, product.Collections)
+	if err != nil {
+		return err
+	}
+
+	var options sql.NullString
+	if len(product.Options) > 0 {
+		if options, err = formatJSON(product.Options); err != nil {
+			return err
+		}
+	}
+
+	_, err = repo.q.Exec(`INSERT INTO products (`+productColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			sku = excluded.sku,
+			name = excluded.name,
+			unit_price = excluded.unit_price,
+			currency = excluded.currency,
+			quantity = excluded.quantity,
+			digital = excluded.digital,
+			weight = excluded.weight,
+			length = excluded.length,
+			width = excluded.width,
+			height = excluded.height,
+			categories = excluded.categories,
+			tags = excluded.tags,
+			collections = excluded.collections,
+			parent_id = excluded.parent_id,
+			options = excluded.options`,
+		product.ID, product.SKU, product.Name, product.UnitPrice.Amount, product.UnitPrice.Currency, product.Quantity,
+		product.Digital, product.Weight, product.Dimensions.Length, product.Dimensions.Width, product.Dimensions.Height,
+		classification[0], classification[1], classification[2], product.ParentID, options)
+	return err
+}
+
+func (repo *ProductRepository) DecreaseStock(quantities map[string]int) error {
+	return withTx(repo.q, func(q queryer) error {
+		for _, productID := range sortedProductIDs(quantities) {
+			quantity := quantities[productID]
+
+			res, err := q.Exec(`UPDATE products SET quantity = quantity - ? WHERE id = ? AND quantity >= ?`,
+				quantity, productID, quantity)
+			if err != nil {
+				return err
+			}
+
+			affected, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+
+			if affected > 0 {
+				continue
+			}
+
+			if _, err := (&ProductRepository{q: q}).FindByID(productID); err != nil {
+				return fmt.Errorf("%w: %s", err, productID)
+			}
+
+			return fmt.Errorf("%w: %s", domain.ErrNotEnoughStock, productID)
+		}
+
+		return nil
+	})
+}
+
+func (repo *ProductRepository) IncreaseStock(quantities map[string]int) error {
+	return withTx(repo.q, func(q queryer) error {
+		for _, productID := range sortedProductIDs(quantities) {
+			res, err := q.Exec(`UPDATE products SET quantity = quantity + ? WHERE id = ?`, quantities[productID], productID)
+			if err != nil {
+				return err
+			}
+
+			affected, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+
+			if affected == 0 {
+				return fmt.Errorf("%w: %s", domain.ErrProductNotFound, productID)
+			}
+		}
+
+		return nil
+	})
+}
+
+// sortedProductIDs returns the keys of quantities sorted, so rows are
+// always updated in the same order.
+func sortedProductIDs(quantities map[string]int) []string {
+	productIDs := make([]string, 0, len(quantities))
+	for productID := range quantities {
+		productIDs = append(productIDs, productID)
+	}
+	sort.Strings(productIDs)
+
+	return productIDs
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanProduct(s scanner) (*domain.Product, error) {
+	var product domain.Product
+	var categories, tags, collections, options sql.NullString
+	err := s.Scan(
+		&product.ID,
+		&product.SKU,
+		&product.Name,
+		&product.UnitPrice.Amount,
+		&product.UnitPrice.Currency,
+		&product.Quantity,
+		&product.Digital,
+		&product.Weight,
+		&product.Dimensions.Length,
+		&product.Dimensions.Width,
+		&product.Dimensions.Height,
+		&categories,
+		&tags,
+		&collections,
+		&product.ParentID,
+		&options,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if product.Categories, err = parseStrings(categories); err != nil {
+		return nil, err
+	}
+	if product.Tags, err = parseStrings(tags); err != nil {
+		return nil, err
+	}
+	if product.Collections, err = parseStrings(collections); err != nil {
+		return nil, err
+	}
+	if options.Valid {
+		if err := json.Unmarshal([]byte(options.String), &product.Options); err != nil {
+			return nil, err
+		}
+	}
+
+	return &product, nil
+}
+
+// formatClassification stores the categories, tags and collections of a
+// product or an order line as JSON lists.
+func formatClassification(lists ...[]string) ([]sql.NullString, error) {
+	formatted := make([]sql.NullString, 0, len(lists))
+	for _, list := range lists {
+		s, err := formatJSON(list)
+		if err != nil {
+			return nil, err
+		}
+		formatted = append(formatted, s)
+	}
+
+	return formatted, nil
+}
+
+func parseStrings(s sql.NullString) ([]string, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+
+	var values []string
+	if err := json.Unmarshal([]byte(s.String), &values); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
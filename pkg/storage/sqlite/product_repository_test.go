@@ -0,0 +1,215 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+func TestProductRepository_FindByID(t *testing.T) {
+	tests := []struct {
+		name      string
+		productID string
+		want      *domain.Product
+		wantErr   error
+	}{
+		{
+			name:      "should return stored product",
+			productID: "p01",
+			want: &domain.Product{
+				ID:        "p01",
+				SKU:       "120P90",
+				Name:      "Google Home",
+				UnitPrice: domain.MustParseMoney("49.99", "USD"),
+				Quantity:  5,
+			},
+		},
+		{
+			name:      "should return error when product is not stored",
+			productID: "p03",
+			wantErr:   domain.ErrProductNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sut := newTestStore(t).Products()
+
+			got, err := sut.FindByID(test.productID)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.want, got)
+			}
+		})
+	}
+}
+
+func TestProductRepository_FindAll(t *testing.T) {
+	sut := newTestStore(t).Products()
+
+	got, err := sut.FindAll()
+	assert.NoError(t, err)
+	if assert.Len(t, got, 2) {
+		assert.Equal(t, "p01", got[0].ID)
+		assert.Equal(t, "p02", got[1].ID)
+	}
+}
+
+func TestProductRepository_Save(t *testing.T) {
+	sut := newTestStore(t).Products()
+
+	for _, product := range []*domain.Product{
+		{
+			ID:        "p01",
+			SKU:       "120P90",
+			Name:      "Google Home Mini",
+			UnitPrice: domain.MustParseMoney("39.99", "USD"),
+			Quantity:  7,
+			Weight:    180,
+			Dimensions: domain.Dimensions{
+				Length: 98,
+				Width:  98,
+				Height: 42,
+			},
+			Categories:  []string{"smart-home", "speakers"},
+			Tags:        []string{"sale"},
+			Collections: []string{},
+		},
+		{
+			ID:        "p03",
+			Name:      "Go Programming E-Book",
+			UnitPrice: domain.MustParseMoney("15", "USD"),
+			Quantity:  100,
+			Digital:   true,
+		},
+		{
+			ID:        "p02-32gb",
+			SKU:       "43N23P-32",
+			Name:      "MacBook Pro 32GB",
+			UnitPrice: domain.MustParseMoney("5999.99", "USD"),
+			Quantity:  1,
+			ParentID:  "p02",
+			Options:   []domain.ProductOption{{Name: "Memory", Value: "32GB"}, {Name: "Color", Value: "Silver"}},
+		},
+	} {
+		assert.NoError(t, sut.Save(product))
+
+		got, err := sut.FindByID(product.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, product, got)
+	}
+}
+
+func TestProductRepository_FindVariants(t *testing.T) {
+	sut := newTestStore(t).Products()
+
+	for _, id := range []string{"p02-32gb", "p02-16gb"} {
+		assert.NoError(t, sut.Save(&domain.Product{
+			ID:        id,
+			Name:      "MacBook Pro",
+			UnitPrice: domain.MustParseMoney("5399.99", "USD"),
+			ParentID:  "p02",
+		}))
+	}
+
+	got, err := sut.FindVariants("p02")
+	assert.NoError(t, err)
+	if assert.Len(t, got, 2) {
+		assert.Equal(t, "p02-16gb", got[0].ID)
+		assert.Equal(t, "p02-32gb", got[1].ID)
+	}
+
+	got, err = sut.FindVariants("p01")
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestProductRepository_DecreaseStock(t *testing.T) {
+	tests := []struct {
+		name       string
+		quantities map[string]int
+		want       map[string]int
+		wantErr    error
+	}{
+		{
+			name:       "should decrease stock of every product",
+			quantities: map[string]int{"p01": 2, "p02": 2},
+			want:       map[string]int{"p01": 3, "p02": 0},
+		},
+		{
+			name:       "should not decrease any stock when one product is not enough in stock",
+			quantities: map[string]int{"p01": 2, "p02": 3},
+			want:       map[string]int{"p01": 5, "p02": 2},
+			wantErr:    domain.ErrNotEnoughStock,
+		},
+		{
+			name:       "should not decrease any stock when one product is not found",
+			quantities: map[string]int{"p01": 2, "p03": 1},
+			want:       map[string]int{"p01": 5, "p02": 2},
+			wantErr:    domain.ErrProductNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sut := newTestStore(t).Products()
+
+			err := sut.DecreaseStock(test.quantities)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			for productID, quantity := range test.want {
+				got, err := sut.FindByID(productID)
+				assert.NoError(t, err)
+				assert.Equal(t, quantity, got.Quantity)
+			}
+		})
+	}
+}
+
+func TestProductRepository_IncreaseStock(t *testing.T) {
+	tests := []struct {
+		name       string
+		quantities map[string]int
+		want       map[string]int
+		wantErr    error
+	}{
+		{
+			name:       "should increase stock of every product",
+			quantities: map[string]int{"p01": 2, "p02": 1},
+			want:       map[string]int{"p01": 7, "p02": 3},
+		},
+		{
+			name:       "should not increase any stock when one product is not found",
+			quantities: map[string]int{"p01": 2, "p03": 1},
+			want:       map[string]int{"p01": 5, "p02": 2},
+			wantErr:    domain.ErrProductNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sut := newTestStore(t).Products()
+
+			err := sut.IncreaseStock(test.quantities)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			for productID, quantity := range test.want {
+				got, err := sut.FindByID(productID)
+				assert.NoError(t, err)
+				assert.Equal(t, quantity, got.Quantity)
+			}
+		})
+	}
+}
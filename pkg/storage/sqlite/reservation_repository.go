@@ -0,0 +1,75 @@
+package sqlite
+
+import (
+	"time"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+// ReservationRepository stores expiry times as unix nanoseconds so they
+// can be compared in SQL.
+type ReservationRepository struct {
+	q queryer
+}
+
+func (repo *ReservationRepository) FindByOrder(orderID string) ([]*domain.Reservation, error) {
+	rows, err := repo.q.Query(`SELECT order_id, product_id, quantity, expires_at
+		FROM reservations WHERE order_id = ? ORDER BY product_id`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reservations := []*domain.Reservation{}
+	for rows.Next() {
+		var (
+			reservation domain.Reservation
+			expiresAt   int64
+		)
+		if err := rows.Scan(&reservation.OrderID, &reservation.ProductID, &reservation.Quantity, &expiresAt); err != nil {
+			return nil, err
+		}
+		reservation.ExpiresAt = time.Unix(0, expiresAt).UTC()
+		reservations = append(reservations, &reservation)
+	}
+
+	return reservations, rows.Err()
+}
+
+func (repo *ReservationRepository) ReservedQuantity(productID string, now time.Time) (int, error) {
+	var reserved int
+	err := repo.q.QueryRow(`SELECT COALESCE(SUM(quantity), 0) FROM reservations
+		WHERE product_id = ? AND expires_at > ?`, productID, now.UnixNano()).Scan(&reserved)
+
+	return reserved, err
+}
+
+func (repo *ReservationRepository) Save(reservation *domain.Reservation) error {
+	_, err := repo.q.Exec(`INSERT INTO reservations (order_id, product_id, quantity, expires_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (order_id, product_id) DO UPDATE SET
+			quantity = excluded.quantity,
+			expires_at = excluded.expires_at`,
+		reservation.OrderID, reservation.ProductID, reservation.Quantity, reservation.ExpiresAt.UnixNano())
+
+	return err
+}
+
+func (repo *ReservationRepository) Delete(orderID string, productID string) error {
+	_, err := repo.q.Exec(`DELETE FROM reservations WHERE order_id = ? AND product_id = ?`, orderID, productID)
+	return err
+}
+
+func (repo *ReservationRepository) DeleteByOrder(orderID string) error {
+	_, err := repo.q.Exec(`DELETE FROM reservations WHERE order_id = ?`, orderID)
+	return err
+}
+
+func (repo *ReservationRepository) DeleteExpired(now time.Time) (int, error) {
+	result, err := repo.q.Exec(`DELETE FROM reservations WHERE expires_at <= ?`, now.UnixNano())
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+	return int(deleted), err
+}
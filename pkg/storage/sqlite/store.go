@@ -0,0 +1,130 @@
+// Package sqlite implements the domain repositories on top of SQLite.
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+// queryer is implemented by both *sql.DB and *sql.Tx.
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Store implements domain.Store on top of a SQLite database.
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens the SQLite database at path, creating it if needed, and
+// migrates it to the latest schema. Use ":memory:" for a throwaway database.
+func Open(path string) (*Store, error) {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	dsn := path + separator + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite only allows one writer at a time, sharing a single connection
+	// avoids SQLITE_BUSY errors and keeps ":memory:" databases alive.
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+func (store *Store) Close() error {
+	return store.db.Close()
+}
+
+func (store *Store) Products() domain.ProductRepository {
+	return &ProductRepository{q: store.db}
+}
+
+func (store *Store) Orders() domain.OrderRepository {
+	return &OrderRepository{q: store.db}
+}
+
+func (store *Store) Reservations() domain.ReservationRepository {
+	return &ReservationRepository{q: store.db}
+}
+
+func (store *Store) Customers() domain.CustomerRepository {
+	return &CustomerRepository{q: store.db}
+}
+
+func (store *Store) Transaction(fn func(tx domain.Store) error) error {
+	return runTx(store.db, func(tx *sql.Tx) error {
+		return fn(&txStore{tx: tx})
+	})
+}
+
+type txStore struct {
+	tx *sql.Tx
+}
+
+func (store *txStore) Products() domain.ProductRepository {
+	return &ProductRepository{q: store.tx}
+}
+
+func (store *txStore) Orders() domain.OrderRepository {
+	return &OrderRepository{q: store.tx}
+}
+
+func (store *txStore) Reservations() domain.ReservationRepository {
+	return &ReservationRepository{q: store.tx}
+}
+
+func (store *txStore) Customers() domain.CustomerRepository {
+	return &CustomerRepository{q: store.tx}
+}
+
+// Transaction joins the running transaction.
+func (store *txStore) Transaction(fn func(tx domain.Store) error) error {
+	return fn(store)
+}
+
+func runTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// withTx runs fn in a transaction, joining the transaction of q if it
+// is already one.
+func withTx(q queryer, fn func(q queryer) error) error {
+	db, ok := q.(*sql.DB)
+	if !ok {
+		return fn(q)
+	}
+
+	return runTx(db, func(tx *sql.Tx) error {
+		return fn(tx)
+	})
+}
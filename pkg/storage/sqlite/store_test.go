@@ -0,0 +1,123 @@
+package sqlite
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/donnpebe/shoppo/pkg/domain"
+)
+
+func newTestStore(t *testing.T) *Store {
+	store, err := Open(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	for _, product := range []*domain.Product{
+		{
+			ID:        "p01",
+			SKU:       "120P90",
+			Name:      "Google Home",
+			UnitPrice: domain.MustParseMoney("49.99", "USD"),
+			Quantity:  5,
+		},
+		{
+			ID:        "p02",
+			SKU:       "43N23P",
+			Name:      "MacBook Pro",
+			UnitPrice: domain.MustParseMoney("5399.99", "USD"),
+			Quantity:  2,
+		},
+	} {
+		require.NoError(t, store.Products().Save(product))
+	}
+
+	return store
+}
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shoppo.db")
+
+	store, err := Open(path)
+	require.NoError(t, err)
+	require.NoError(t, store.Products().Save(&domain.Product{
+		ID:        "p01",
+		Name:      "Google Home",
+		UnitPrice: domain.MustParseMoney("49.99", "USD"),
+		Quantity:  5,
+	}))
+	require.NoError(t, store.Close())
+
+	// Opening again must not apply the migrations twice and must keep the data.
+	store, err = Open(path)
+	require.NoError(t, err)
+	defer store.Close()
+
+	product, err := store.Products().FindByID("p01")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, product.Quantity)
+
+	files, err := fs.Glob(migrations, "migrations/*.sql")
+	require.NoError(t, err)
+
+	var applied int
+	require.NoError(t, store.db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied))
+	assert.Equal(t, len(files), applied)
+}
+
+func TestStore_Transaction(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	tests := []struct {
+		name         string
+		fnErr        error
+		wantQuantity int
+		wantOrder    bool
+	}{
+		{
+			name:         "should commit every write when fn succeeds",
+			wantQuantity: 3,
+			wantOrder:    true,
+		},
+		{
+			name:         "should roll back every write when fn fails",
+			fnErr:        errBoom,
+			wantQuantity: 5,
+			wantOrder:    false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sut := newTestStore(t)
+
+			err := sut.Transaction(func(tx domain.Store) error {
+				if err := tx.Products().DecreaseStock(map[string]int{"p01": 2}); err != nil {
+					return err
+				}
+
+				if err := tx.Orders().Save(&domain.Order{ID: "order1"}); err != nil {
+					return err
+				}
+
+				return test.fnErr
+			})
+			assert.ErrorIs(t, err, test.fnErr)
+
+			product, err := sut.Products().FindByID("p01")
+			assert.NoError(t, err)
+			assert.Equal(t, test.wantQuantity, product.Quantity)
+
+			_, err = sut.Orders().FindByID("order1")
+			if test.wantOrder {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, domain.ErrCartNotFound)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+// Package shoppo holds the assets that live at the root of the repository.
+package shoppo
+
+import _ "embed" // needed by go:embed
+
+// Schema is the GraphQL schema served by the shoppo API.
+//
+//go:embed shoppo.api.graphql
+var Schema string